@@ -0,0 +1,144 @@
+package sha
+
+import (
+    "encoding/binary"
+    "errors"
+    "hash"
+    "math"
+    "math/big"
+    "runtime"
+    "sync"
+)
+
+/* Balloon hashing (Boneh, Corrigan-Gibbs & Schechter, "Balloon Hashing: A
+ * Memory-Hard Function Providing Provable Protection Against Sequential
+ * Attacks"), a memory-hard password hash built only on a hash function.
+ * Integers are hashed as 8 little-endian bytes */
+
+// The number of blocks mixed into each block per round in the paper
+const BalloonDelta = 3
+
+type balloonHasher struct {
+    d hash.Hash
+    n [8]byte
+}
+
+func (b *balloonHasher) sum(dst []byte, ints []uint64, blocks ...[]byte) []byte {
+    /* Hashes the integers followed by the blocks, writing the digest to
+     * dst */
+    b.d.Reset()
+    for _, x := range ints {
+        binary.LittleEndian.PutUint64(b.n[:], x)
+        b.d.Write(b.n[:])
+    }
+    for _, block := range blocks {
+        b.d.Write(block)
+    }
+    return b.d.Sum(dst[:0])
+}
+
+func Balloon(password []byte, salt []byte, sCost int, tCost int, delta int, h Hash) ([]byte, error) {
+    /* Takes a password, a salt, the space cost in blocks of the hash size,
+     * the time cost in rounds and delta, the number of pseudorandom blocks
+     * mixed into each block per round, and returns the single-threaded
+     * Balloon hash of the password using h */
+    if err := balloonCheck(sCost, tCost, delta, 1, h); err != nil {
+        return nil, err
+    }
+    return balloon(password, salt, sCost, tCost, delta, h), nil
+}
+
+func BalloonM(password []byte, salt []byte, sCost int, tCost int, pCost int, delta int, h Hash) ([]byte, error) {
+    /* The parallel variant Balloon-M: pCost instances, each with its own
+     * sCost blocks, are run concurrently with the salt followed by their
+     * number from 1. The hash of the password, the salt and the xor of
+     * their outputs is returned */
+    if err := balloonCheck(sCost, tCost, delta, pCost, h); err != nil {
+        return nil, err
+    }
+    outputs := make([][]byte, pCost)
+    workers := min(runtime.GOMAXPROCS(0), pCost)
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            for i := w; i < pCost; i += workers {
+                instanceSalt := binary.LittleEndian.AppendUint64(append([]byte(nil), salt...), uint64(i+1))
+                outputs[i] = balloon(password, instanceSalt, sCost, tCost, delta, h)
+            }
+        }(w)
+    }
+    wg.Wait()
+    output := make([]byte, h.Size())
+    for _, o := range outputs {
+        for j := range output {
+            output[j] ^= o[j]
+        }
+    }
+    b := balloonHasher{d: h.New()}
+    return b.sum(nil, nil, password, salt, output), nil
+}
+
+func balloonCheck(sCost int, tCost int, delta int, pCost int, h Hash) error {
+    /* Returns an error for costs Balloon cannot use */
+    if !h.Available() {
+        return errors.New("sha: unknown hash function")
+    }
+    if sCost <= 0 || tCost <= 0 || delta <= 0 || pCost <= 0 {
+        return errors.New("sha: Balloon costs and delta must be positive")
+    }
+    if sCost > math.MaxInt / h.Size() {
+        return errors.New("sha: Balloon space cost is too large")
+    }
+    return nil
+}
+
+func balloon(password []byte, salt []byte, sCost int, tCost int, delta int, h Hash) []byte {
+    /* Algorithm 1 of the paper, where the index block of step 2b is itself
+     * a hash of t, m and i, as in the published test vectors */
+    b := balloonHasher{d: h.New()}
+    size := h.Size()
+    buf := make([]byte, sCost*size)
+    block := func(m int) []byte {
+        return buf[m*size:(m+1)*size]
+    }
+    var cnt uint64
+    // Step 1: expand the input into the buffer
+    b.sum(block(0), []uint64{cnt}, password, salt)
+    cnt++
+    for m := 1; m < sCost; m++ {
+        b.sum(block(m), []uint64{cnt}, block(m-1))
+        cnt++
+    }
+    // Step 2: mix the buffer
+    S := big.NewInt(int64(sCost))
+    other := new(big.Int)
+    idx := make([]byte, size)
+    digest := make([]byte, size)
+    le := make([]byte, size)
+    for t := 0; t < tCost; t++ {
+        for m := 0; m < sCost; m++ {
+            // Step 2a: hash the previous and current blocks
+            b.sum(digest, []uint64{cnt}, block((m + sCost - 1) % sCost), block(m))
+            copy(block(m), digest)
+            cnt++
+            // Step 2b: hash in delta pseudorandomly chosen blocks
+            for i := 0; i < delta; i++ {
+                b.sum(idx, []uint64{uint64(t), uint64(m), uint64(i)})
+                b.sum(digest, []uint64{cnt}, salt, idx)
+                cnt++
+                // The digest is a little-endian integer
+                for j := range digest {
+                    le[size-1-j] = digest[j]
+                }
+                j := int(other.Mod(other.SetBytes(le), S).Int64())
+                b.sum(digest, []uint64{cnt}, block(m), block(j))
+                copy(block(m), digest)
+                cnt++
+            }
+        }
+    }
+    // Step 3: the last block is the output
+    return append([]byte(nil), block(sCost-1)...)
+}
@@ -0,0 +1,165 @@
+package sha
+
+import (
+    "testing"
+    "encoding/binary"
+    "encoding/hex"
+    "math/big"
+)
+
+// Test vectors from the RustCrypto balloon-hash crate, which uses SHA-256
+// and delta = 3
+var balloonTests = []struct {
+    password string
+    salt string
+    sCost int
+    tCost int
+    delta int
+    h Hash
+    expected string
+}{
+    {"hunter42", "examplesalt", 1024, 3, 3, HashSHA256, "716043dff777b44aa7b88dcbab12c078abecfac9d289c5b5195967aa63440dfb"},
+    {"", "salt", 3, 3, 3, HashSHA256, "5f02f8206f9cd212485c6bdf85527b698956701ad0852106f94b94ee94577378"},
+    {"password", "", 3, 3, 3, HashSHA256, "20aa99d7fe3f4df4bd98c655c5480ec98b143107a331fd491deda885c4d6a6cc"},
+    {"\x00", "\x00", 3, 3, 3, HashSHA256, "4fc7e302ffa29ae0eac31166cee7a552d1d71135f4e0da66486fb68a749b73a4"},
+    {"password", "salt", 1, 1, 3, HashSHA256, "eefda4a8a75b461fa389c1dcfaf3e9dfacbc26f81f22e6f280d15cc18c417545"},
+}
+
+var balloonMTests = []struct {
+    password string
+    salt string
+    sCost int
+    tCost int
+    pCost int
+    delta int
+    h Hash
+    expected string
+}{
+    {"hunter42", "examplesalt", 1024, 3, 4, 3, HashSHA256, "1832bd8e5cbeba1cb174a13838095e7e66508e9bf04c40178990adbc8ba9eb6f"},
+    {"", "salt", 3, 3, 2, 3, HashSHA256, "f8767fe04059cef67b4427cda99bf8bcdd983959dbd399a5e63ea04523716c23"},
+    {"password", "", 3, 3, 3, 3, HashSHA256, "bcad257eff3d1090b50276514857e60db5d0ec484129013ef3c88f7d36e438d6"},
+    {"password", "", 3, 3, 1, 3, HashSHA256, "498344ee9d31baf82cc93ebb3874fe0b76e164302c1cefa1b63a90a69afb9b4d"},
+    {"\x00", "\x00", 3, 3, 4, 3, HashSHA256, "8a665611e40710ba1fd78c181549c750f17c12e423c11930ce997f04c7153e0c"},
+    {"\x00", "\x00", 3, 3, 1, 3, HashSHA256, "d9e33c683451b21fb3720afbd78bf12518c1d4401fa39f054b052a145c968bb1"},
+    {"password", "salt", 1, 1, 16, 3, HashSHA256, "a67b383bb88a282aef595d98697f90820adf64582a4b3627c76b7da3d8bae915"},
+    {"password", "salt", 1, 1, 1, 3, HashSHA256, "97a11df9382a788c781929831d409d3599e0b67ab452ef834718114efdcd1c6d"},
+}
+
+func TestBalloon(t *testing.T) {
+    for _, test := range balloonTests {
+        output, err := Balloon([]byte(test.password), []byte(test.salt), test.sCost, test.tCost, test.delta, test.h)
+        if err != nil || hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %q %q s=%d t=%d delta=%d %s\nResult:   %x %v\nExpected: %s\n", test.password, test.salt, test.sCost, test.tCost, test.delta, test.h, output, err, test.expected)
+        }
+    }
+}
+
+func TestBalloonM(t *testing.T) {
+    for _, test := range balloonMTests {
+        output, err := BalloonM([]byte(test.password), []byte(test.salt), test.sCost, test.tCost, test.pCost, test.delta, test.h)
+        if err != nil || hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %q %q s=%d t=%d p=%d delta=%d %s\nResult:   %x %v\nExpected: %s\n", test.password, test.salt, test.sCost, test.tCost, test.pCost, test.delta, test.h, output, err, test.expected)
+        }
+    }
+}
+
+func balloonReference(password []byte, salt []byte, sCost int, tCost int, delta int, h Hash) []byte {
+    /* A direct transcription of Algorithm 1 of the paper, keeping every
+     * block in its own slice and reading the index digests as little-endian
+     * integers byte by byte */
+    H := func(ints []uint64, blocks ...[]byte) []byte {
+        d := h.New()
+        for _, x := range ints {
+            d.Write(binary.LittleEndian.AppendUint64(nil, x))
+        }
+        for _, block := range blocks {
+            d.Write(block)
+        }
+        return d.Sum(nil)
+    }
+    var cnt uint64
+    buf := [][]byte{H([]uint64{cnt}, password, salt)}
+    cnt++
+    for m := 1; m < sCost; m++ {
+        buf = append(buf, H([]uint64{cnt}, buf[m-1]))
+        cnt++
+    }
+    for t := 0; t < tCost; t++ {
+        for m := 0; m < sCost; m++ {
+            buf[m] = H([]uint64{cnt}, buf[(m+sCost-1)%sCost], buf[m])
+            cnt++
+            for i := 0; i < delta; i++ {
+                digest := H([]uint64{cnt}, salt, H([]uint64{uint64(t), uint64(m), uint64(i)}))
+                cnt++
+                other := new(big.Int)
+                for j := len(digest) - 1; j >= 0; j-- {
+                    other.Lsh(other, 8).Or(other, big.NewInt(int64(digest[j])))
+                }
+                other.Mod(other, big.NewInt(int64(sCost)))
+                buf[m] = H([]uint64{cnt}, buf[m], buf[other.Int64()])
+                cnt++
+            }
+        }
+    }
+    return buf[sCost-1]
+}
+
+func TestBalloonReference(t *testing.T) {
+    // The RustCrypto vectors only use SHA-256 with delta = 3, so other
+    // hashes and deltas are checked against balloonReference, which
+    // reproduces those vectors
+    tests := []struct {
+        sCost int
+        tCost int
+        delta int
+        h Hash
+    }{
+        {3, 3, 3, HashSHA256},
+        {1024, 3, 3, HashSHA256},
+        {64, 2, 3, HashSHA512},
+        {17, 2, 3, HashSHA384},
+        {16, 2, 1, HashSHA256},
+        {16, 2, 5, HashSHA256},
+        {33, 3, 4, HashSHA512},
+    }
+    for _, test := range tests {
+        output, err := Balloon([]byte("hunter42"), []byte("examplesalt"), test.sCost, test.tCost, test.delta, test.h)
+        expected := balloonReference([]byte("hunter42"), []byte("examplesalt"), test.sCost, test.tCost, test.delta, test.h)
+        if err != nil || hex.EncodeToString(output) != hex.EncodeToString(expected) {
+            t.Errorf("\nTest: s=%d t=%d delta=%d %s\nResult:   %x %v\nExpected: %x\n", test.sCost, test.tCost, test.delta, test.h, output, err, expected)
+        }
+    }
+    for _, test := range balloonTests {
+        expected := balloonReference([]byte(test.password), []byte(test.salt), test.sCost, test.tCost, test.delta, test.h)
+        if hex.EncodeToString(expected) != test.expected {
+            t.Errorf("\nTest: reference %q %q\nResult:   %x\nExpected: %s\n", test.password, test.salt, expected, test.expected)
+        }
+    }
+}
+
+func TestBalloonParameters(t *testing.T) {
+    tests := []struct {
+        sCost int
+        tCost int
+        pCost int
+        delta int
+        h Hash
+    }{
+        {0, 1, 1, 3, HashSHA256},
+        {1, 0, 1, 3, HashSHA256},
+        {1, 1, 0, 3, HashSHA256},
+        {1, 1, 1, 0, HashSHA256},
+        {1, 1, 1, 3, Hash(-1)},
+        {1 << 62, 1, 1, 3, HashSHA512},
+    }
+    for _, test := range tests {
+        if _, err := BalloonM([]byte("password"), []byte("salt"), test.sCost, test.tCost, test.pCost, test.delta, test.h); err == nil {
+            t.Errorf("\nTest: %+v\nExpected an error\n", test)
+        }
+        if test.pCost == 1 {
+            if _, err := Balloon([]byte("password"), []byte("salt"), test.sCost, test.tCost, test.delta, test.h); err == nil {
+                t.Errorf("\nTest: %+v\nExpected an error from Balloon\n", test)
+            }
+        }
+    }
+}
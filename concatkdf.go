@@ -0,0 +1,89 @@
+package sha
+
+import (
+    "encoding/binary"
+    "errors"
+    "hash"
+)
+
+/* One-step key derivation from a shared secret: the SP 800-56C
+ * concatenation KDF, the ANSI X9.63 KDF and the JOSE Concat KDF */
+
+func concatKDF(prf func() hash.Hash, counterFirst bool, Z []byte, info []byte, L int) ([]byte, error) {
+    /* Concatenates the outputs K(i) = H(counter || Z || info), or
+     * H(Z || counter || info) if counterFirst is false, for a 32-bit
+     * counter starting at 1, until there are L bytes */
+    if L <= 0 {
+        return nil, errors.New("sha: key length must be positive")
+    }
+    h := prf()
+    hLen := h.Size()
+    reps := (L + hLen - 1) / hLen
+    if uint64(reps) > 0xffffffff {
+        return nil, errors.New("sha: requested key is too long for a 32-bit counter")
+    }
+    output := make([]byte, 0, reps*hLen)
+    var counter [4]byte
+    for i := 1; i <= reps; i++ {
+        binary.BigEndian.PutUint32(counter[:], uint32(i))
+        h.Reset()
+        if counterFirst {
+            h.Write(counter[:])
+            h.Write(Z)
+        } else {
+            h.Write(Z)
+            h.Write(counter[:])
+        }
+        h.Write(info)
+        output = h.Sum(output)
+    }
+    return output[:L], nil
+}
+
+func OneStepKDF(h Hash, Z []byte, otherInfo []byte, L int) ([]byte, error) {
+    /* Takes a hash function, a shared secret Z, the FixedInfo (OtherInfo)
+     * and a key length in bytes, and returns the key derived by the
+     * SP 800-56C one-step KDF with H(x) = hash(x) */
+    return concatKDF(h.New, true, Z, otherInfo, L)
+}
+
+func OneStepKDFHMAC(h Hash, salt []byte, Z []byte, otherInfo []byte, L int) ([]byte, error) {
+    /* Takes a hash function, a salt, a shared secret Z, the FixedInfo
+     * (OtherInfo) and a key length in bytes, and returns the key derived by
+     * the SP 800-56C one-step KDF with H(x) = HMAC(salt, x). An empty salt
+     * is replaced by the default salt, a block of zeros */
+    if len(salt) == 0 {
+        salt = make([]byte, h.BlockSize())
+    }
+    return concatKDF(func() hash.Hash { return NewHMAC(h, salt) }, true, Z, otherInfo, L)
+}
+
+func X963KDF(h Hash, Z []byte, sharedInfo []byte, L int) ([]byte, error) {
+    /* Takes a hash function, a shared secret Z, the SharedInfo and a key
+     * length in bytes, and returns the key derived by the ANSI X9.63 KDF,
+     * which puts the counter after Z */
+    return concatKDF(h.New, false, Z, sharedInfo, L)
+}
+
+func JOSEOtherInfo(algorithmID string, partyUInfo []byte, partyVInfo []byte, keyDataLen int) []byte {
+    /* Returns the OtherInfo for the JOSE Concat KDF (RFC 7518 section
+     * 4.6.2): the algorithm ID, PartyUInfo and PartyVInfo each prefixed by
+     * a 32-bit length, followed by keyDataLen in bits as a 32-bit integer */
+    var otherInfo []byte
+    for _, field := range [][]byte{[]byte(algorithmID), partyUInfo, partyVInfo} {
+        otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(field)))
+        otherInfo = append(otherInfo, field...)
+    }
+    return binary.BigEndian.AppendUint32(otherInfo, uint32(keyDataLen))
+}
+
+func JOSEConcatKDF(Z []byte, algorithmID string, partyUInfo []byte, partyVInfo []byte, keyDataLen int) ([]byte, error) {
+    /* Takes the ECDH-ES shared secret Z, the "enc" or "alg" value, the
+     * decoded "apu" and "apv" values and the key length in bits, and
+     * returns the key derived by the JOSE Concat KDF with SHA-256 */
+    if keyDataLen <= 0 || keyDataLen % 8 != 0 {
+        return nil, errors.New("sha: JOSE key length must be a positive multiple of 8 bits")
+    }
+    otherInfo := JOSEOtherInfo(algorithmID, partyUInfo, partyVInfo, keyDataLen)
+    return OneStepKDF(HashSHA256, Z, otherInfo, keyDataLen/8)
+}
@@ -0,0 +1,159 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/base64"
+    "encoding/hex"
+)
+
+// Longer outputs and the other hashes, with expected values from the SSKDF
+// and X963KDF implementations in OpenSSL 3.0 rather than NIST
+var concatKDFTests = []struct {
+    h Hash
+    Z string
+    info string
+    salt string
+    hash string  // SP 800-56C with H = hash
+    hmac string  // SP 800-56C with H = HMAC(salt, .)
+    x963 string  // ANSI X9.63
+}{
+    {HashSHA1, "f7d98f0279a7854dc48afdee6bb53b185f9e1156a441fe923505c292350ded94", "8e2362ae07ed2762e63f2f189d328f37712fb662", "20e4abfb10c73686ac2e302c228c7fbc",
+        "bc2da6d453cb0c83da9d4cade2f029d0ef46f0230fdfb4e9100963c2792c8cb1de7043fa9de3cca3e1127a6f16b772a48eb59616ec4c878d121dd0604fab5336733818352e74911a9337cf0f7c68abc8",
+        "316d9bdfd297261a504fe1fc0b85088273645538c056cf8134461f49a8e53a5a58eeb689e0ad9ea29c795b341431c90cff82df2b4bf9a01750e19a404e5a9966a332d013b89e532b1bd7a62d3ce275d7",
+        "32f1c27f7a649847b9be560e324ef77c514dda5f03ae00d2de24fa972efb91aac4392f74b9a73e4195d4a5e56d46ae5792eee6176297242d56ead05a1224fb976df7887165bd9909d1dca949f746c8cd"},
+    {HashSHA256, "a35c01a8ac08955e00df91ac00868d199c7f62ad5c79cb5f3183768c7faf0bae", "e6362e57cab2772402304d2495407602e921079a", "d44adc8229c582170cec94dab1d6499a",
+        "3118322052fd2824a154309709ee2cec19ce77b42f84f824094358986740d2f5786a41689ac651b1248b144b1fc53bf11f06d0db06f0a57567d7c4fbd40a69bb3c03e97b545fc2e1ff12fa4f55cdab93",
+        "8bc76e8d94f5721877375f5dd829f8c6ea93c71c9ee7f4bf542cbbd6fa83360d4cbc53080afb7014afe6f57479a20ed073210dd2b75cbe89aab48cacddd69c0e79aad923ab828ffc359222c1670c2541",
+        "3cb79f5d160f97fbfc1d040789cc5007816e38c52aa179856c5e800aed0c65242f126efc6cc3a42e0934da7d10cd472f2df36dbd58066bfb54707e676bddefc827716be5cf78a836284ec845ef0dc860"},
+    {HashSHA384, "c9653eb70e837ba3be894d9c370ea7b1ab6922ee2aaa9f8bca922485a3b7a805", "0a09aeb07c421b82220b41ceb4f426c494eddef3", "91ceb1e31dfcf567f2b8dbf23b35971c",
+        "c1bdc75c3cad056a489681d578afe4681980d284876660df4ace5b17c40757c64bdf765775959677c493f84be1569e391f4f164b8432d1d9a5cc303ab0a953aa073ea0ba478358cd3d6c48bce7cf5401",
+        "0e461346e448ae81a80e9b50d5de2b1a4b103333a134e08aeeeab528ef3ff0b6b9d484fc7472f82298debeb777d33034a758ec33fbf6969d4e52d4aaef5013fc3a86b0855aece476976877cb4886bbc6",
+        "3040a145cc72b0dce06b70b954abdb81c81ac996165dc3f4d66933b4c6b9027a61d881d3e1f7ec98fe420a212025004da125117b3d007702194336c222713a6fb037449ca22b780a397521d0bba582c6"},
+    {HashSHA512, "c050228921f6e6bc8f1ceb3b6bbff066b71b31c73cefef491adbf79aa414f0f3", "311e0ba6ef6d89d048e69ac624d8b290a1f4a88a", "60e167cc3eb50fca827354c160e11f59",
+        "f54d738881cbc09f501e1713e18a1e2ebc490a985986db00dd7d2e0ae34c2fa7e7ec91e6bba4efd4bedcafe1ff1b22f50053b5ac79748c3756f29754b3d897c582411490ecf114d61cf9ee62d8df4509",
+        "159e27d80ec47d233fe9fb2f76bb861973761149d752680a19729a7e7f430dac5d642f18d160b0075a77f0d20b51bac25a963a15cbec6899ff9d171b6884abdb18425307771e69491d4f010cef7d5ad4",
+        "3398dc4788215b69e5e47f515020cc4dd2f6197fee7451085c898a9fd35e886ff2d1150bc18fe3ab874fc6143f8b6b558cc74fa692d29ff781fa0cdc2fc051dd458bbb31c3226ff1b13f26591dde3b12"},
+}
+
+// Records from the NIST CAVP SP 800-56A KAS vectors, whose key derivation
+// is the one-step KDF of SP 800-56C, with H = hash or H = HMAC with the
+// default salt. OtherInfo starts with the IUT and CAVS IDs
+var oneStepKDFCAVPTests = []struct {
+    h Hash
+    hmac bool
+    Z string
+    OI string
+    DKM string
+}{
+    {HashSHA224, false, "43f23b2c760d686fc99cc008b63aea92f866e224265af60d2d8ae540", "a1b2c3d4e5bb7f1b40d14ebd70443393990b574341565369645b1582daab9cc6c30d61fdcf1cdfc7e9a304651e0fdb", "ad65fa2d12541c3a21f3cd223efb"},
+    {HashSHA256, false, "52169af5c485dcc2321eb8d26d5efa21fb9b93c98e38412ee2484cf14f0d0d23", "a1b2c3d4e53728157e634612c12d6d5223e204aeea4341565369647bd184bcd246f72971f292badaa2fe4124612cba", "1c3bc9e7c4547c5191c0d478cccaed55"},
+    {HashSHA512, true, "013951627c1dea63ea2d7702dd24e963eef5faac6b4af7e4b831cde499dff1ce45f6179f741c728aa733583b024092088f0af7fce1d045edbc5790931e8d5ca79c73", "a1b2c3d4e55e600be5f367e0e8a465f4bf2704db00c9325c9fbd216d12b49160b2ae5157650f43415653696421e68e", "64ce901db10d558661f10b6836a122a7605323ce2f39bf27eaaac8b34cf89f2f"},
+}
+
+// Records from the NIST CAVP ANS X9.63-2001 KDF vectors (CAVS 12.0)
+var x963KDFCAVPTests = []struct {
+    h Hash
+    Z string
+    SharedInfo string
+    keyData string
+}{
+    {HashSHA1, "1c7d7b5f0597b03d06a018466ed1a93e30ed4b04dc64ccdd", "", "bf71dffd8f4d99223936beb46fee8ccc"},
+    {HashSHA1, "5ed096510e3fcf782ceea98e9737993e2b21370f6cda2ab1", "", "ec3e224446bfd7b3be1df404104af953"},
+    {HashSHA256, "96c05619d56c328ab95fe84b18264b08725b85e33fd34f08", "", "443024c3dae66b95e6f5670601558f71"},
+    {HashSHA256, "22518b10e70f2a3f243810ae3254139efbee04aa57c7af7d", "75eef81aa3041e33b80971203d2c0c52", "c498af77161cc59f2962b9a713e2b215152d139766ce34a776df11866a69bf2e52a13d9c7c6fc878c50c5ea0bc7b00e0da2447cfd874f6cf92f30d0097111485500c90c3af8b487872d04685d14c8d1dc8d7fa08beb0ce0ababc11f0bd496269142d43525a78e5bc79a17f59676a5706dc54d54d4d1f0bd7e386128ec26afc21"},
+    {HashSHA512, "00aa5bb79b33e389fa58ceadc047197f14e73712f452caa9fc4c9adb369348b81507392f1a86ddfdb7c4ff8231c4bd0f44e44a1b55b1404747a9e2e753f55ef05a2d", "e3b5b4c1b0d5cf1d2b3a2f9937895d31", "4463f869f3cc18769b52264b0112b5858f7ad32a5a2d96d8cffabf7fa733633d6e4dd2a599acceb3ea54a6217ce0b50eef4f6b40a5c30250a5a8eeee208002267089dbf351f3f5022aa9638bf1ee419dea9c4ff745a25ac27bda33ca08bd56dd1a59b4106cf2dbbc0ab2aa8e2efa7b17902d34276951ceccab87f9661c3e8816"},
+}
+
+func TestOneStepKDFCAVP(t *testing.T) {
+    for _, test := range oneStepKDFCAVPTests {
+        var key []byte
+        var err error
+        if test.hmac {
+            key, err = OneStepKDFHMAC(test.h, nil, mustHex(test.Z), mustHex(test.OI), len(test.DKM)/2)
+        } else {
+            key, err = OneStepKDF(test.h, mustHex(test.Z), mustHex(test.OI), len(test.DKM)/2)
+        }
+        if err != nil || hex.EncodeToString(key) != test.DKM {
+            t.Errorf("\nTest: %s HMAC=%v\nResult:   %x %v\nExpected: %s\n", test.h, test.hmac, key, err, test.DKM)
+        }
+    }
+}
+
+func TestX963KDFCAVP(t *testing.T) {
+    for _, test := range x963KDFCAVPTests {
+        key, err := X963KDF(test.h, mustHex(test.Z), mustHex(test.SharedInfo), len(test.keyData)/2)
+        if err != nil || hex.EncodeToString(key) != test.keyData {
+            t.Errorf("\nTest: %s\nResult:   %x %v\nExpected: %s\n", test.h, key, err, test.keyData)
+        }
+    }
+}
+
+func TestOneStepKDF(t *testing.T) {
+    for _, test := range concatKDFTests {
+        key, err := OneStepKDF(test.h, mustHex(test.Z), mustHex(test.info), 80)
+        if err != nil || hex.EncodeToString(key) != test.hash {
+            t.Errorf("\nTest: %s\nResult:   %x %v\nExpected: %s\n", test.h, key, err, test.hash)
+        }
+    }
+}
+
+func TestOneStepKDFHMAC(t *testing.T) {
+    for _, test := range concatKDFTests {
+        key, err := OneStepKDFHMAC(test.h, mustHex(test.salt), mustHex(test.Z), mustHex(test.info), 80)
+        if err != nil || hex.EncodeToString(key) != test.hmac {
+            t.Errorf("\nTest: %s\nResult:   %x %v\nExpected: %s\n", test.h, key, err, test.hmac)
+        }
+    }
+    // Input: no salt, so the default salt of zeros is used
+    key, _ := OneStepKDFHMAC(HashSHA256, nil, mustHex("c360934e19f773206efdd59e5c754e30889dd8f905b6f46e"), nil, 40)
+    expected := "580842243364d23d3fbf583d9a323fd4b4786227acd9418623a9a3d0928468752ec08edbb9b8b305"
+    if hex.EncodeToString(key) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", key, expected)
+    }
+}
+
+func TestX963KDF(t *testing.T) {
+    for _, test := range concatKDFTests {
+        key, err := X963KDF(test.h, mustHex(test.Z), mustHex(test.info), 80)
+        if err != nil || hex.EncodeToString(key) != test.x963 {
+            t.Errorf("\nTest: %s\nResult:   %x %v\nExpected: %s\n", test.h, key, err, test.x963)
+        }
+    }
+}
+
+func TestConcatKDFLength(t *testing.T) {
+    // Key lengths below 1 are refused by every variant
+    for _, L := range []int{0, -1} {
+        if _, err := OneStepKDF(HashSHA256, []byte("Z"), nil, L); err == nil {
+            t.Errorf("\nTest: OneStepKDF L=%d\nExpected an error\n", L)
+        }
+        if _, err := OneStepKDFHMAC(HashSHA256, nil, []byte("Z"), nil, L); err == nil {
+            t.Errorf("\nTest: OneStepKDFHMAC L=%d\nExpected an error\n", L)
+        }
+        if _, err := X963KDF(HashSHA256, []byte("Z"), nil, L); err == nil {
+            t.Errorf("\nTest: X963KDF L=%d\nExpected an error\n", L)
+        }
+    }
+    for _, keyDataLen := range []int{0, -8, 100} {
+        if _, err := JOSEConcatKDF([]byte("Z"), "A128GCM", nil, nil, keyDataLen); err == nil {
+            t.Errorf("\nTest: JOSEConcatKDF keyDataLen=%d\nExpected an error\n", keyDataLen)
+        }
+    }
+}
+
+func TestJOSEConcatKDF(t *testing.T) {
+    // Example from RFC 7518 appendix C
+    Z := []byte{158, 86, 217, 29, 129, 113, 53, 211, 114, 131, 66, 131, 191, 132, 38, 156, 251, 49, 110, 163, 218, 128, 106, 72, 246, 218, 167, 121, 140, 254, 144, 196}
+    otherInfo := JOSEOtherInfo("A128GCM", []byte("Alice"), []byte("Bob"), 128)
+    expectedInfo := []byte{0, 0, 0, 7, 65, 49, 50, 56, 71, 67, 77, 0, 0, 0, 5, 65, 108, 105, 99, 101, 0, 0, 0, 3, 66, 111, 98, 0, 0, 0, 128}
+    if !bytes.Equal(otherInfo, expectedInfo) {
+        t.Errorf("\nResult:   %v\nExpected: %v\n", otherInfo, expectedInfo)
+    }
+    // Expected: VqqN6vgjbSBcIijNcacQGg
+    key, err := JOSEConcatKDF(Z, "A128GCM", []byte("Alice"), []byte("Bob"), 128)
+    result := base64.RawURLEncoding.EncodeToString(key)
+    if err != nil || result != "VqqN6vgjbSBcIijNcacQGg" {
+        t.Errorf("\nResult:   %s %v\nExpected: VqqN6vgjbSBcIijNcacQGg\n", result, err)
+    }
+}
@@ -0,0 +1,220 @@
+package sha
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "errors"
+    "strconv"
+    "strings"
+)
+
+/* Password hashing in the style of crypt(3), as used in /etc/shadow:
+ * SHA-crypt ($5$ & $6$) from Ulrich Drepper's "Unix crypt using SHA-256 and
+ * SHA-512", and yescrypt ($y$) in yescrypt.go */
+
+// The base64 alphabet used by crypt(3), which differs from RFC 4648
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Returned by Crypt for a setting it cannot parse
+var ErrCryptFormat = errors.New("sha: unrecognised password hash format")
+
+// Limits on SHA-crypt settings. Rounds outside the range are clamped to it,
+// and longer salts are truncated
+const (
+    SHACryptRoundsDefault = 5000
+    SHACryptRoundsMin = 1000
+    SHACryptRoundsMax = 999999999
+    SHACryptSaltMax = 16
+)
+
+// The order in which SHA-crypt encodes the digest bytes, in groups of three
+// with the first byte most significant
+var shaCryptOrder = map[Hash][]int{
+    HashSHA256: {0, 10, 20, 21, 1, 11, 12, 22, 2, 3, 13, 23, 24, 4, 14, 15, 25, 5, 6, 16, 26, 27, 7, 17, 18, 28, 8, 9, 19, 29, 31, 30},
+    HashSHA512: {0, 21, 42, 22, 43, 1, 44, 2, 23, 3, 24, 45, 25, 46, 4, 47, 5, 26, 6, 27, 48, 28, 49, 7, 50, 8, 29, 9, 30, 51, 31, 52, 10,
+        53, 11, 32, 12, 33, 54, 34, 55, 13, 56, 14, 35, 15, 36, 57, 37, 58, 16, 59, 17, 38, 18, 39, 60, 40, 61, 19, 62, 20, 41, 63},
+}
+
+func cryptBase64(b []byte, order []int) string {
+    /* Encodes the bytes of b taken in the given order, least significant 6
+     * bits first. A final group of n < 3 bytes gives n+1 characters */
+    var output strings.Builder
+    for i := 0; i < len(order); i += 3 {
+        group := order[i:min(i+3, len(order))]
+        var w uint
+        for _, j := range group {
+            w = w << 8 | uint(b[j])
+        }
+        for n := 0; n <= len(group); n++ {
+            output.WriteByte(cryptAlphabet[w & 0x3f])
+            w >>= 6
+        }
+    }
+    return output.String()
+}
+
+func Crypt(password []byte, setting string) (string, error) {
+    /* Hashes a password with the method and parameters in setting, which
+     * is either a setting such as "$6$rounds=10000$salt" or a complete hash
+     * made with one. Supports SHA-256-crypt ($5$), SHA-512-crypt ($6$)
+     * and yescrypt ($y$) */
+    switch {
+    case strings.HasPrefix(setting, "$5$"):
+        return shaCrypt(HashSHA256, password, setting[3:])
+    case strings.HasPrefix(setting, "$6$"):
+        return shaCrypt(HashSHA512, password, setting[3:])
+    case strings.HasPrefix(setting, "$y$"):
+        return yescryptCrypt(password, setting)
+    }
+    return "", ErrCryptFormat
+}
+
+func CryptVerify(password []byte, hash string) bool {
+    /* Checks in constant time whether password matches a hash made by
+     * Crypt */
+    output, err := Crypt(password, hash)
+    return err == nil && subtle.ConstantTimeCompare([]byte(output), []byte(hash)) == 1
+}
+
+func SHACryptSetting(h Hash, rounds int) (string, error) {
+    /* Returns a setting for Crypt with a random 16-character salt, using
+     * SHA-256-crypt for HashSHA256 and SHA-512-crypt for HashSHA512. A
+     * rounds value of 0 gives the default without a rounds= field */
+    var setting string
+    switch h {
+    case HashSHA256:
+        setting = "$5$"
+    case HashSHA512:
+        setting = "$6$"
+    default:
+        return "", errors.New("sha: SHA-crypt hash function must be SHA-256 or SHA-512")
+    }
+    if rounds != 0 {
+        setting += "rounds=" + strconv.Itoa(rounds) + "$"
+    }
+    // 16 characters of 6 bits from 12 random bytes
+    salt := make([]byte, 12)
+    if _, err := rand.Read(salt); err != nil {
+        return "", err
+    }
+    order := make([]int, len(salt))
+    for i := range order {
+        order[i] = i
+    }
+    return setting + cryptBase64(salt, order), nil
+}
+
+/* SHA-crypt */
+
+func shaCrypt(h Hash, password []byte, setting string) (string, error) {
+    /* Parses "rounds=N$salt$..." after the $5$ or $6$ prefix, and returns
+     * the complete hash */
+    prefix := "$5$"
+    if h == HashSHA512 {
+        prefix = "$6$"
+    }
+    rounds := SHACryptRoundsDefault
+    customRounds := false
+    if strings.HasPrefix(setting, "rounds=") {
+        end := strings.IndexByte(setting, '$')
+        if end < 0 {
+            return "", ErrCryptFormat
+        }
+        digits := setting[len("rounds="):end]
+        if digits == "" || strings.Trim(digits, "0123456789") != "" {
+            return "", ErrCryptFormat
+        }
+        n, err := strconv.ParseUint(digits, 10, 64)
+        if err != nil || n > SHACryptRoundsMax {
+            n = SHACryptRoundsMax
+        }
+        rounds = max(int(n), SHACryptRoundsMin)
+        customRounds = true
+        setting = setting[end+1:]
+    }
+    // The salt ends at the next '$', and only its first 16 bytes are used
+    salt := setting
+    if end := strings.IndexByte(salt, '$'); end >= 0 {
+        salt = salt[:end]
+    }
+    if len(salt) > SHACryptSaltMax {
+        salt = salt[:SHACryptSaltMax]
+    }
+    digest := shaCryptDigest(h, password, []byte(salt), rounds)
+    output := prefix
+    if customRounds {
+        output += "rounds=" + strconv.Itoa(rounds) + "$"
+    }
+    return output + salt + "$" + cryptBase64(digest, shaCryptOrder[h]), nil
+}
+
+func repeatDigest(d []byte, n int) []byte {
+    /* Returns n bytes of copies of d, the last one truncated */
+    output := make([]byte, n)
+    for i := range output {
+        output[i] = d[i % len(d)]
+    }
+    return output
+}
+
+func shaCryptDigest(h Hash, password []byte, salt []byte, rounds int) []byte {
+    /* The SHA-crypt digest, following steps 1-21 of Drepper's
+     * specification */
+    d := h.New()
+    // Digest B = H(password || salt || password)
+    d.Write(password)
+    d.Write(salt)
+    d.Write(password)
+    B := d.Sum(nil)
+    // Digest A = H(password || salt || B repeated to the password length ||
+    // for each bit of the length, B if it is 1 or the password if it is 0)
+    d.Reset()
+    d.Write(password)
+    d.Write(salt)
+    d.Write(repeatDigest(B, len(password)))
+    for n := len(password); n > 0; n >>= 1 {
+        if n & 1 != 0 {
+            d.Write(B)
+        } else {
+            d.Write(password)
+        }
+    }
+    A := d.Sum(nil)
+    // The byte sequences P and S replace the password and salt in the
+    // rounds: DP = H(password repeated len(password) times), and DS =
+    // H(salt repeated 16 + A[0] times)
+    d.Reset()
+    for i := 0; i < len(password); i++ {
+        d.Write(password)
+    }
+    P := repeatDigest(d.Sum(nil), len(password))
+    d.Reset()
+    for i := 0; i < 16 + int(A[0]); i++ {
+        d.Write(salt)
+    }
+    S := repeatDigest(d.Sum(nil), len(salt))
+    // Each round hashes the previous digest C with P and S in an order
+    // depending on the round number
+    C := A
+    for i := 0; i < rounds; i++ {
+        d.Reset()
+        if i & 1 != 0 {
+            d.Write(P)
+        } else {
+            d.Write(C)
+        }
+        if i % 3 != 0 {
+            d.Write(S)
+        }
+        if i % 7 != 0 {
+            d.Write(P)
+        }
+        if i & 1 != 0 {
+            d.Write(C)
+        } else {
+            d.Write(P)
+        }
+        C = d.Sum(C[:0])
+    }
+    return C
+}
@@ -0,0 +1,77 @@
+package sha
+
+import (
+    "testing"
+    "strings"
+)
+
+// Test vectors from Drepper's "Unix crypt using SHA-256 and SHA-512". The
+// roundstoolow vectors check that rounds below 1000 are clamped
+var cryptTests = []struct {
+    setting string
+    password string
+    expected string
+}{
+    {"$5$saltstring", "Hello world!", "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5"},
+    {"$5$rounds=10000$saltstringsaltstring", "Hello world!", "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA"},
+    {"$5$rounds=5000$toolongsaltstring", "This is just a test", "$5$rounds=5000$toolongsaltstrin$Un/5jzAHMgOGZ5.mWJpuVolil07guHPvOW8mGRcvxa5"},
+    {"$5$rounds=1400$anotherlongsaltstring", "a very much longer text to encrypt.  This one even stretches over morethan one line.", "$5$rounds=1400$anotherlongsalts$Rx.j8H.h8HjEDGomFU8bDkXm3XIUnzyxf12oP84Bnq1"},
+    {"$5$rounds=77777$short", "we have a short salt string but not a short password", "$5$rounds=77777$short$JiO1O3ZpDAxGJeaDIuqCoEFysAe1mZNJRs3pw0KQRd/"},
+    {"$5$rounds=123456$asaltof16chars..", "a short string", "$5$rounds=123456$asaltof16chars..$gP3VQ/6X7UUEW3HkBn2w1/Ptq2jxPyzV/cZKmF/wJvD"},
+    {"$5$rounds=10$roundstoolow", "the minimum number is still observed", "$5$rounds=1000$roundstoolow$yfvwcWrQ8l/K0DAWyuPMDNHpIVlTQebY9l/gL972bIC"},
+    {"$6$saltstring", "Hello world!", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+    {"$6$rounds=10000$saltstringsaltstring", "Hello world!", "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v."},
+    {"$6$rounds=5000$toolongsaltstring", "This is just a test", "$6$rounds=5000$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0"},
+    {"$6$rounds=1400$anotherlongsaltstring", "a very much longer text to encrypt.  This one even stretches over morethan one line.", "$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1"},
+    {"$6$rounds=77777$short", "we have a short salt string but not a short password", "$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0"},
+    {"$6$rounds=123456$asaltof16chars..", "a short string", "$6$rounds=123456$asaltof16chars..$BtCwjqMJGx5hrJhZywWvt0RLE8uZ4oPwcelCjmw2kSYu.Ec6ycULevoBK25fs2xXgMNrCzIMVcgEJAstJeonj1"},
+    {"$6$rounds=10$roundstoolow", "the minimum number is still observed", "$6$rounds=1000$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX."},
+    // Empty password and salt, and a UTF-8 password, from libxcrypt
+    {"$5$rounds=1000$", "", "$5$rounds=1000$$jAQ1NZgJkatmWyAsK0NxDcSXTB41aRCrTggwkNbBkXA"},
+    {"$6$a$", "pässwörd", "$6$a$OKrcUSoIZoiG4funxwR5M0Cdjn4r6bVFeOsvtx043G7n7r8nLbeKuJB5/YRFX2PMrcP/rrx/9NoGI.2s53a92."},
+}
+
+func TestCrypt(t *testing.T) {
+    for _, test := range cryptTests {
+        output, err := Crypt([]byte(test.password), test.setting)
+        if err != nil || output != test.expected {
+            t.Errorf("\nTest: %s\nResult:   %s %v\nExpected: %s\n", test.setting, output, err, test.expected)
+        }
+    }
+    // Input: settings which cannot be parsed
+    for _, setting := range []string{"", "$1$salt", "$5$rounds=$salt", "$5$rounds=-1$salt", "$6$rounds=1000"} {
+        if _, err := Crypt([]byte("password"), setting); err != ErrCryptFormat {
+            t.Errorf("\nTest: %q\nResult:   %v\nExpected: %v\n", setting, err, ErrCryptFormat)
+        }
+    }
+}
+
+func TestVerify(t *testing.T) {
+    for _, test := range cryptTests {
+        if !CryptVerify([]byte(test.password), test.expected) {
+            t.Errorf("\nTest: %s\nPassword not verified\n", test.expected)
+        }
+        if CryptVerify([]byte(test.password + "x"), test.expected) {
+            t.Errorf("\nTest: %s\nWrong password verified\n", test.expected)
+        }
+    }
+}
+
+func TestSHACryptSetting(t *testing.T) {
+    for _, h := range []Hash{HashSHA256, HashSHA512} {
+        setting, err := SHACryptSetting(h, 2000)
+        if err != nil {
+            t.Fatal(err)
+        }
+        hash, err := Crypt([]byte("password"), setting)
+        if err != nil || !strings.HasPrefix(hash, setting + "$") || len(setting) != len("$5$rounds=2000$") + 16 {
+            t.Errorf("\nTest: %s\nResult:   %s %v\n", h, hash, err)
+        }
+        if !CryptVerify([]byte("password"), hash) {
+            t.Errorf("\nTest: %s\nPassword not verified\n", h)
+        }
+    }
+    if _, err := SHACryptSetting(HashSHA1, 0); err == nil {
+        t.Errorf("\nExpected an error for SHA-1\n")
+    }
+}
@@ -0,0 +1,406 @@
+package sha
+
+import (
+    "encoding/asn1"
+    "encoding/binary"
+    "hash"
+    "strconv"
+)
+
+/* Streaming versions of the hash functions, which implement hash.Hash so
+ * that input can be written in pieces */
+
+// Identifies one of the hash functions in this package
+type Hash int
+
+const (
+    HashSHA1 Hash = iota + 1
+    HashSHA224
+    HashSHA256
+    HashSHA384
+    HashSHA512
+    HashSHA512_224
+    HashSHA512_256
+    HashSHA3_224
+    HashSHA3_256
+    HashSHA3_384
+    HashSHA3_512
+)
+
+// Name, output size and block size in bytes, and object identifier of each
+// hash function
+var hashes = map[Hash]struct {
+    name string
+    size int
+    blockSize int
+    oid asn1.ObjectIdentifier
+}{
+    HashSHA1:       {"SHA-1", 20, 64, asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}},
+    HashSHA224:     {"SHA-224", 28, 64, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 4}},
+    HashSHA256:     {"SHA-256", 32, 64, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}},
+    HashSHA384:     {"SHA-384", 48, 128, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}},
+    HashSHA512:     {"SHA-512", 64, 128, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}},
+    HashSHA512_224: {"SHA-512/224", 28, 128, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 5}},
+    HashSHA512_256: {"SHA-512/256", 32, 128, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 6}},
+    HashSHA3_224:   {"SHA3-224", 28, 144, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 7}},
+    HashSHA3_256:   {"SHA3-256", 32, 136, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 8}},
+    HashSHA3_384:   {"SHA3-384", 48, 104, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 9}},
+    HashSHA3_512:   {"SHA3-512", 64, 72, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 10}},
+}
+
+func (h Hash) Available() bool {
+    /* Returns whether h is one of the hash functions in this package */
+    _, ok := hashes[h]
+    return ok
+}
+
+func (h Hash) String() string {
+    /* Returns the name of the hash function, e.g. "SHA-256" */
+    if !h.Available() {
+        return "unknown hash " + strconv.Itoa(int(h))
+    }
+    return hashes[h].name
+}
+
+func (h Hash) Size() int {
+    /* Returns the output size of the hash function in bytes */
+    return hashes[h].size
+}
+
+func (h Hash) BlockSize() int {
+    /* Returns the block size of the hash function in bytes */
+    return hashes[h].blockSize
+}
+
+func (h Hash) OID() asn1.ObjectIdentifier {
+    /* Returns the object identifier of the hash function, as used in
+     * DigestInfo and other algorithm identifiers */
+    return hashes[h].oid
+}
+
+func HashFromOID(oid asn1.ObjectIdentifier) (Hash, bool) {
+    /* Returns the hash function with the given object identifier, and
+     * whether one was found */
+    for h, info := range hashes {
+        if info.oid.Equal(oid) {
+            return h, true
+        }
+    }
+    return 0, false
+}
+
+func (h Hash) New() hash.Hash {
+    /* Returns a new streaming hash for the hash function */
+    switch h {
+    case HashSHA1:
+        return NewSHA1()
+    case HashSHA224:
+        return NewSHA224()
+    case HashSHA256:
+        return NewSHA256()
+    case HashSHA384:
+        return NewSHA384()
+    case HashSHA512:
+        return NewSHA512()
+    case HashSHA512_224:
+        return NewSHA512_t(224)
+    case HashSHA512_256:
+        return NewSHA512_t(256)
+    case HashSHA3_224:
+        return newSHA3(448, 28)
+    case HashSHA3_256:
+        return newSHA3(512, 32)
+    case HashSHA3_384:
+        return newSHA3(768, 48)
+    case HashSHA3_512:
+        return newSHA3(1024, 64)
+    }
+    panic("sha: requested hash function is not available")
+}
+
+func (h Hash) Sum(input []byte) []byte {
+    /* Takes an input and returns its hash */
+    d := h.New()
+    d.Write(input)
+    return d.Sum(nil)
+}
+
+// Implemented by the streaming hashes, so that a midstate (e.g. after
+// hashing an HMAC key) can be saved and restored without reallocating
+type midstate interface {
+    hash.Hash
+    clone() midstate
+    restore(from midstate)
+}
+
+/* Hashes with 32-bit words (SHA-1, SHA-224 & SHA-256) */
+
+type digest32 struct {
+    H [8]uint32       // Intermediate hash value (SHA-1 only uses 5 words)
+    H0 [8]uint32      // Initial hash value
+    size int          // Output size in bytes
+    block [64]byte    // Partial message block
+    n int             // Number of bytes in block
+    length uint64     // Length of the message so far in bytes
+}
+
+func NewSHA1() hash.Hash {
+    /* Returns a new streaming SHA1 hash */
+    d := &digest32{H0: [8]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476, 0xc3d2e1f0}, size: 20}
+    d.Reset()
+    return d
+}
+
+func NewSHA224() hash.Hash {
+    /* Returns a new streaming SHA224 hash */
+    d := &digest32{H0: [8]uint32{0xc1059ed8, 0x367cd507, 0x3070dd17, 0xf70e5939, 0xffc00b31, 0x68581511, 0x64f98fa7, 0xbefa4fa4}, size: 28}
+    d.Reset()
+    return d
+}
+
+func NewSHA256() hash.Hash {
+    /* Returns a new streaming SHA256 hash */
+    d := &digest32{H0: [8]uint32{0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19}, size: 32}
+    d.Reset()
+    return d
+}
+
+func (d *digest32) compress(block []byte) {
+    /* Parses a 512-bit block into words and updates the hash value */
+    var M [16]uint32
+    for j := 0; j < 16; j++ {
+        M[j] = binary.BigEndian.Uint32(block[j*4:])
+    }
+    if d.size == 20 {
+        var H [5]uint32
+        copy(H[:], d.H[:5])
+        SHA1Compress(&H, M)
+        copy(d.H[:5], H[:])
+    } else {
+        SHA2Compress32(&d.H, M)
+    }
+}
+
+func (d *digest32) Write(p []byte) (int, error) {
+    /* Adds p to the message being hashed */
+    written := len(p)
+    d.length += uint64(len(p))
+    // Fill any partial block first
+    if d.n > 0 {
+        c := copy(d.block[d.n:], p)
+        d.n += c
+        p = p[c:]
+        if d.n < 64 {
+            return written, nil
+        }
+        d.compress(d.block[:])
+        d.n = 0
+    }
+    // Hash whole blocks directly from the input
+    for len(p) >= 64 {
+        d.compress(p[:64])
+        p = p[64:]
+    }
+    d.n = copy(d.block[:], p)
+    return written, nil
+}
+
+func (d *digest32) Sum(b []byte) []byte {
+    /* Appends the hash of the message so far to b, without changing the
+     * state of the hash */
+    e := *d
+    // Pad with a '1' bit, zeros to 448 bits mod 512, and the length in bits
+    var padding [72]byte
+    padding[0] = 0x80
+    k := (55 - int(e.length % 64) + 64) % 64
+    binary.BigEndian.PutUint64(padding[1+k:], e.length*8)
+    e.Write(padding[:1+k+8])
+    var output [32]byte
+    for i := 0; i < 8; i++ {
+        binary.BigEndian.PutUint32(output[i*4:], e.H[i])
+    }
+    return append(b, output[:d.size]...)
+}
+
+func (d *digest32) Reset() {
+    /* Resets the hash to the initial hash value */
+    d.H = d.H0
+    d.n = 0
+    d.length = 0
+}
+
+func (d *digest32) Size() int {
+    return d.size
+}
+
+func (d *digest32) BlockSize() int {
+    return 64
+}
+
+func (d *digest32) clone() midstate {
+    e := *d
+    return &e
+}
+
+func (d *digest32) restore(from midstate) {
+    *d = *from.(*digest32)
+}
+
+/* Hashes with 64-bit words (SHA-384, SHA-512 & SHA-512/t) */
+
+type digest64 struct {
+    H [8]uint64       // Intermediate hash value
+    H0 [8]uint64      // Initial hash value
+    size int          // Output size in bytes
+    block [128]byte   // Partial message block
+    n int             // Number of bytes in block
+    length uint64     // Length of the message so far in bytes
+}
+
+func NewSHA384() hash.Hash {
+    /* Returns a new streaming SHA384 hash */
+    d := &digest64{H0: [8]uint64{0xcbbb9d5dc1059ed8, 0x629a292a367cd507, 0x9159015a3070dd17, 0x152fecd8f70e5939, 0x67332667ffc00b31, 0x8eb44a8768581511, 0xdb0c2e0d64f98fa7, 0x47b5481dbefa4fa4}, size: 48}
+    d.Reset()
+    return d
+}
+
+func NewSHA512() hash.Hash {
+    /* Returns a new streaming SHA512 hash */
+    d := &digest64{H0: [8]uint64{0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1, 0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179}, size: 64}
+    d.Reset()
+    return d
+}
+
+func NewSHA512_t(t int) hash.Hash {
+    /* Returns a new streaming SHA512/t hash, where t is a multiple of 8
+     * less than 512, other than 384 */
+    if t <= 0 || t >= 512 || t == 384 || t % 8 != 0 {
+        panic("sha: invalid SHA512/t output length")
+    }
+    d := &digest64{H0: SHA512_t_IV(t), size: t/8}
+    d.Reset()
+    return d
+}
+
+func (d *digest64) compress(block []byte) {
+    /* Parses a 1024-bit block into words and updates the hash value */
+    var M [16]uint64
+    for j := 0; j < 16; j++ {
+        M[j] = binary.BigEndian.Uint64(block[j*8:])
+    }
+    SHA2Compress64(&d.H, M)
+}
+
+func (d *digest64) Write(p []byte) (int, error) {
+    /* Adds p to the message being hashed */
+    written := len(p)
+    d.length += uint64(len(p))
+    // Fill any partial block first
+    if d.n > 0 {
+        c := copy(d.block[d.n:], p)
+        d.n += c
+        p = p[c:]
+        if d.n < 128 {
+            return written, nil
+        }
+        d.compress(d.block[:])
+        d.n = 0
+    }
+    // Hash whole blocks directly from the input
+    for len(p) >= 128 {
+        d.compress(p[:128])
+        p = p[128:]
+    }
+    d.n = copy(d.block[:], p)
+    return written, nil
+}
+
+func (d *digest64) Sum(b []byte) []byte {
+    /* Appends the hash of the message so far to b, without changing the
+     * state of the hash */
+    e := *d
+    // Pad with a '1' bit, zeros to 896 bits mod 1024, and the length in
+    // bits as a 128-bit integer
+    var padding [144]byte
+    padding[0] = 0x80
+    k := (111 - int(e.length % 128) + 128) % 128
+    binary.BigEndian.PutUint64(padding[1+k:], e.length >> 61)
+    binary.BigEndian.PutUint64(padding[9+k:], e.length*8)
+    e.Write(padding[:1+k+16])
+    var output [64]byte
+    for i := 0; i < 8; i++ {
+        binary.BigEndian.PutUint64(output[i*8:], e.H[i])
+    }
+    return append(b, output[:d.size]...)
+}
+
+func (d *digest64) Reset() {
+    /* Resets the hash to the initial hash value */
+    d.H = d.H0
+    d.n = 0
+    d.length = 0
+}
+
+func (d *digest64) Size() int {
+    return d.size
+}
+
+func (d *digest64) BlockSize() int {
+    return 128
+}
+
+func (d *digest64) clone() midstate {
+    e := *d
+    return &e
+}
+
+func (d *digest64) restore(from midstate) {
+    *d = *from.(*digest64)
+}
+
+/* SHA-3 hashes */
+
+type digestSHA3 struct {
+    s Sponge          // Keccak[c] sponge
+    size int          // Output size in bytes
+}
+
+func newSHA3(c int, size int) *digestSHA3 {
+    s, _ := NewSponge(SpongeConfig{Capacity: c, Suffix: 0x02, SuffixLen: 2})
+    return &digestSHA3{s: *s, size: size}
+}
+
+func (d *digestSHA3) Write(p []byte) (int, error) {
+    /* Adds p to the message being hashed */
+    return d.s.Write(p)
+}
+
+func (d *digestSHA3) Sum(b []byte) []byte {
+    /* Appends the hash of the message so far to b, without changing the
+     * state of the hash */
+    s := d.s
+    output := make([]byte, d.size)
+    s.Read(output)
+    return append(b, output...)
+}
+
+func (d *digestSHA3) Reset() {
+    d.s.Reset()
+}
+
+func (d *digestSHA3) Size() int {
+    return d.size
+}
+
+func (d *digestSHA3) BlockSize() int {
+    return d.s.Rate / 8
+}
+
+func (d *digestSHA3) clone() midstate {
+    e := *d
+    return &e
+}
+
+func (d *digestSHA3) restore(from midstate) {
+    *d = *from.(*digestSHA3)
+}
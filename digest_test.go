@@ -0,0 +1,81 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+)
+
+func TestHashNew(t *testing.T) {
+    // Input: 1000 bytes, written in uneven pieces
+    input := make([]byte, 1000)
+    for i := 0; i < len(input); i++ {
+        input[i] = byte(i * 7)
+    }
+    h224, h256, h384, h512 := SHA224(input), SHA256(input), SHA384(input), SHA512(input)
+    h1, h512_224, h512_256 := SHA1(input), SHA512_224(input), SHA512_256(input)
+    h3_224, h3_256, h3_384, h3_512 := SHA3_224(input), SHA3_256(input), SHA3_384(input), SHA3_512(input)
+    // Expected: the same output as the one-shot functions
+    tests := []struct {
+        h Hash
+        expected []byte
+    }{
+        {HashSHA1, h1[:]},
+        {HashSHA224, h224[:]},
+        {HashSHA256, h256[:]},
+        {HashSHA384, h384[:]},
+        {HashSHA512, h512[:]},
+        {HashSHA512_224, h512_224[:]},
+        {HashSHA512_256, h512_256[:]},
+        {HashSHA3_224, h3_224[:]},
+        {HashSHA3_256, h3_256[:]},
+        {HashSHA3_384, h3_384[:]},
+        {HashSHA3_512, h3_512[:]},
+    }
+    for _, test := range tests {
+        d := test.h.New()
+        for i := 0; i < len(input); i += 93 {
+            end := i + 93
+            if end > len(input) {
+                end = len(input)
+            }
+            d.Write(input[i:end])
+            // Sum should not change the state of the hash
+            d.Sum(nil)
+        }
+        result := d.Sum(nil)
+        if !bytes.Equal(result, test.expected) {
+            t.Errorf("\nTest: %s\nResult:   %x\nExpected: %x\n", test.h, result, test.expected)
+        }
+        if d.Size() != test.h.Size() || d.BlockSize() != test.h.BlockSize() {
+            t.Errorf("\nTest: %s\nResult:   %d, %d\nExpected: %d, %d\n", test.h, d.Size(), d.BlockSize(), test.h.Size(), test.h.BlockSize())
+        }
+        // Reset should return to an empty message
+        d.Reset()
+        if !bytes.Equal(d.Sum(nil), test.h.Sum([]byte{})) {
+            t.Errorf("\nTest: %s after Reset\nResult:   %x\nExpected: %x\n", test.h, d.Sum(nil), test.h.Sum([]byte{}))
+        }
+    }
+}
+
+func TestHashPaddingBoundaries(t *testing.T) {
+    // Lengths either side of where the padding needs an extra block
+    for _, n := range []int{55, 56, 63, 64, 111, 112, 127, 128} {
+        input := bytes.Repeat([]byte{0x61}, n)
+        h256, h512 := SHA256(input), SHA512(input)
+        if !bytes.Equal(HashSHA256.Sum(input), h256[:]) {
+            t.Errorf("\nTest: SHA-256 of %d bytes\nResult:   %x\nExpected: %x\n", n, HashSHA256.Sum(input), h256)
+        }
+        if !bytes.Equal(HashSHA512.Sum(input), h512[:]) {
+            t.Errorf("\nTest: SHA-512 of %d bytes\nResult:   %x\nExpected: %x\n", n, HashSHA512.Sum(input), h512)
+        }
+    }
+}
+
+func TestHashString(t *testing.T) {
+    if HashSHA512_256.String() != "SHA-512/256" {
+        t.Errorf("\nResult:   %s\nExpected: SHA-512/256\n", HashSHA512_256)
+    }
+    if Hash(0).Available() {
+        t.Errorf("\nHash(0) should not be available\n")
+    }
+}
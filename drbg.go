@@ -0,0 +1,311 @@
+package sha
+
+import (
+    "encoding/binary"
+    "errors"
+    "io"
+)
+
+/* Deterministic random bit generators from NIST SP 800-90A (Hash_DRBG &
+ * HMAC_DRBG) */
+
+// Returned by Generate when the reseed interval has passed and there is no
+// entropy source to reseed from
+var ErrReseedRequired = errors.New("sha: DRBG must be reseeded")
+
+// Largest request allowed in one call to Generate, 2^19 bits
+const DRBGMaxRequest = 1 << 16
+
+// Default maximum number of requests between reseeds, 2^48
+const DRBGReseedInterval = 1 << 48
+
+// A deterministic random bit generator, which implements io.Reader. If
+// Entropy is set it is used to reseed automatically once ReseedInterval
+// requests have been made, and before every request if PredictionResistance
+// is set
+type DRBG struct {
+    Entropy io.Reader           // Source of entropy input for reseeding
+    PredictionResistance bool   // Whether to reseed before every request
+    ReseedInterval uint64       // Requests allowed between reseeds
+    mech drbgMechanism          // Hash_DRBG or HMAC_DRBG state
+    strength int                // Security strength in bytes
+    reseedCounter uint64        // Requests since the last (re)seed, plus 1
+}
+
+// The reseed and generate functions of a DRBG mechanism, without the
+// checks and reseeding done by DRBG
+type drbgMechanism interface {
+    reseed(entropy []byte, additionalInput []byte)
+    generate(out []byte, additionalInput []byte, reseedCounter uint64)
+}
+
+func drbgStrength(h Hash) int {
+    /* Returns the security strength in bytes of a DRBG using the hash
+     * function h, or 0 if h is not SHA-1 or SHA-2 */
+    switch h {
+    case HashSHA1:
+        return 16
+    case HashSHA224, HashSHA512_224:
+        return 24
+    case HashSHA256, HashSHA512_256, HashSHA384, HashSHA512:
+        return 32
+    }
+    return 0
+}
+
+func newDRBG(h Hash, entropy []byte, mech func(h Hash) drbgMechanism, seedMaterial ...[]byte) (*DRBG, error) {
+    /* Checks the hash function and entropy input, then instantiates the
+     * mechanism from the concatenated seed material */
+    strength := drbgStrength(h)
+    if strength == 0 {
+        return nil, errors.New("sha: DRBG hash function must be SHA-1 or SHA-2")
+    }
+    if len(entropy) < strength {
+        return nil, errors.New("sha: DRBG entropy input is shorter than the security strength")
+    }
+    m := mech(h)
+    var material []byte
+    material = append(material, entropy...)
+    for _, s := range seedMaterial {
+        material = append(material, s...)
+    }
+    m.reseed(material, nil)
+    return &DRBG{mech: m, strength: strength, reseedCounter: 1, ReseedInterval: DRBGReseedInterval}, nil
+}
+
+func NewHashDRBG(h Hash, entropy []byte, nonce []byte, personalization []byte) (*DRBG, error) {
+    /* Instantiates a Hash_DRBG using the hash function h (SHA-1 or SHA-2),
+     * from the entropy input, a nonce and an optional personalization
+     * string */
+    return newDRBG(h, entropy, newHashDRBG, nonce, personalization)
+}
+
+func NewHMACDRBG(h Hash, entropy []byte, nonce []byte, personalization []byte) (*DRBG, error) {
+    /* Instantiates an HMAC_DRBG using HMAC with the hash function h (SHA-1
+     * or SHA-2), from the entropy input, a nonce and an optional
+     * personalization string */
+    return newDRBG(h, entropy, newHMACDRBG, nonce, personalization)
+}
+
+func (d *DRBG) Reseed(entropy []byte, additionalInput []byte) error {
+    /* Reseeds the DRBG with new entropy input and optional additional
+     * input */
+    if len(entropy) < d.strength {
+        return errors.New("sha: DRBG entropy input is shorter than the security strength")
+    }
+    d.mech.reseed(entropy, additionalInput)
+    d.reseedCounter = 1
+    return nil
+}
+
+func (d *DRBG) reseedFromSource(additionalInput []byte) error {
+    /* Reseeds the DRBG with entropy input read from d.Entropy */
+    if d.Entropy == nil {
+        return ErrReseedRequired
+    }
+    entropy := make([]byte, d.strength)
+    if _, err := io.ReadFull(d.Entropy, entropy); err != nil {
+        return err
+    }
+    return d.Reseed(entropy, additionalInput)
+}
+
+func (d *DRBG) Generate(out []byte, additionalInput []byte) error {
+    /* Fills out with pseudorandom bytes, using optional additional input.
+     * At most DRBGMaxRequest bytes can be requested at once */
+    if len(out) > DRBGMaxRequest {
+        return errors.New("sha: DRBG request is too long")
+    }
+    // With prediction resistance, or once the reseed interval has passed,
+    // reseed first and use the additional input for the reseed instead
+    if d.PredictionResistance || d.reseedCounter > d.ReseedInterval {
+        if err := d.reseedFromSource(additionalInput); err != nil {
+            return err
+        }
+        additionalInput = nil
+    }
+    d.mech.generate(out, additionalInput, d.reseedCounter)
+    d.reseedCounter++
+    return nil
+}
+
+func (d *DRBG) Read(p []byte) (int, error) {
+    /* Fills p with pseudorandom bytes, making as many requests as needed */
+    for i := 0; i < len(p); i += DRBGMaxRequest {
+        end := i + DRBGMaxRequest
+        if end > len(p) {
+            end = len(p)
+        }
+        if err := d.Generate(p[i:end], nil); err != nil {
+            return i, err
+        }
+    }
+    return len(p), nil
+}
+
+/* Hash_DRBG (SP 800-90A section 10.1.1) */
+
+type hashDRBG struct {
+    h Hash
+    V []byte          // Value of seedlen bits, updated on each request
+    C []byte          // Constant of seedlen bits, set on each (re)seed
+    seeded bool       // Whether the state has been instantiated
+}
+
+func newHashDRBG(h Hash) drbgMechanism {
+    return &hashDRBG{h: h}
+}
+
+func drbgSeedLen(h Hash) int {
+    /* Returns seedlen for Hash_DRBG in bytes: 440 bits for hashes with
+     * 512-bit blocks, and 888 bits for SHA-384 & SHA-512 */
+    if h.BlockSize() == 128 && h.Size() > 32 {
+        return 111
+    }
+    return 55
+}
+
+func HashDF(h Hash, input []byte, L int) []byte {
+    /* The Hash_df derivation function, which hashes input into L bytes as
+     * Hash(counter || no_of_bits_to_return || input) for an 8-bit counter */
+    d := h.New()
+    var prefix [5]byte
+    binary.BigEndian.PutUint32(prefix[1:], uint32(L*8))
+    output := make([]byte, 0, L+h.Size())
+    for counter := 1; len(output) < L; counter++ {
+        prefix[0] = byte(counter)
+        d.Reset()
+        d.Write(prefix[:])
+        d.Write(input)
+        output = d.Sum(output)
+    }
+    return output[:L]
+}
+
+func addBytes(a []byte, b []byte) {
+    /* Sets a to a + b mod 2^(8*len(a)), treating both as big-endian
+     * integers, where b is no longer than a */
+    var carry uint
+    for i, j := len(a)-1, len(b)-1; i >= 0; i, j = i-1, j-1 {
+        sum := uint(a[i]) + carry
+        if j >= 0 {
+            sum += uint(b[j])
+        }
+        a[i] = byte(sum)
+        carry = sum >> 8
+    }
+}
+
+func (s *hashDRBG) reseed(entropy []byte, additionalInput []byte) {
+    /* Instantiates the state from the seed material the first time, and
+     * otherwise reseeds with V = Hash_df(0x01 || V || entropy || input) */
+    seedLen := drbgSeedLen(s.h)
+    var material []byte
+    if s.seeded {
+        material = append([]byte{0x01}, s.V...)
+    }
+    material = append(material, entropy...)
+    material = append(material, additionalInput...)
+    s.V = HashDF(s.h, material, seedLen)
+    s.C = HashDF(s.h, append([]byte{0x00}, s.V...), seedLen)
+    s.seeded = true
+}
+
+func (s *hashDRBG) hash(prefix byte, data ...[]byte) []byte {
+    /* Returns Hash(prefix || data...) */
+    d := s.h.New()
+    d.Write([]byte{prefix})
+    for _, b := range data {
+        d.Write(b)
+    }
+    return d.Sum(nil)
+}
+
+func (s *hashDRBG) generate(out []byte, additionalInput []byte, reseedCounter uint64) {
+    /* Fills out using Hashgen, then updates V */
+    if len(additionalInput) > 0 {
+        addBytes(s.V, s.hash(0x02, s.V, additionalInput))
+    }
+    // Hashgen: hash V, V + 1, V + 2, ...
+    data := append([]byte(nil), s.V...)
+    d := s.h.New()
+    var block []byte
+    for i := 0; i < len(out); i += len(block) {
+        d.Reset()
+        d.Write(data)
+        block = d.Sum(block[:0])
+        copy(out[i:], block)
+        addBytes(data, []byte{1})
+    }
+    // V = V + Hash(0x03 || V) + C + reseed_counter
+    H := s.hash(0x03, s.V)
+    var counter [8]byte
+    binary.BigEndian.PutUint64(counter[:], reseedCounter)
+    addBytes(s.V, H)
+    addBytes(s.V, s.C)
+    addBytes(s.V, counter[:])
+}
+
+/* HMAC_DRBG (SP 800-90A section 10.1.2) */
+
+type hmacDRBG struct {
+    h Hash
+    K []byte          // HMAC key
+    V []byte          // Value of outlen bits, updated on each request
+    prf *HMAC         // HMAC keyed with K
+}
+
+func newHMACDRBG(h Hash) drbgMechanism {
+    /* Returns an HMAC_DRBG with K = 0x00 00...00 and V = 0x01 01...01,
+     * ready for instantiation */
+    s := &hmacDRBG{h: h, K: make([]byte, h.Size()), V: make([]byte, h.Size())}
+    for i := range s.V {
+        s.V[i] = 0x01
+    }
+    s.prf = NewHMAC(h, s.K)
+    return s
+}
+
+func (s *hmacDRBG) update(provided ...[]byte) {
+    /* The HMAC_DRBG_Update function, which mixes the provided data into K
+     * and V */
+    var length int
+    for _, p := range provided {
+        length += len(p)
+    }
+    for _, sep := range []byte{0x00, 0x01} {
+        // K = HMAC(K, V || sep || provided_data), then V = HMAC(K, V)
+        s.prf.Reset()
+        s.prf.Write(s.V)
+        s.prf.Write([]byte{sep})
+        for _, p := range provided {
+            s.prf.Write(p)
+        }
+        s.K = s.prf.Sum(s.K[:0])
+        s.prf = NewHMAC(s.h, s.K)
+        s.prf.Write(s.V)
+        s.V = s.prf.Sum(s.V[:0])
+        // The second round is skipped with no provided data
+        if length == 0 {
+            return
+        }
+    }
+}
+
+func (s *hmacDRBG) reseed(entropy []byte, additionalInput []byte) {
+    s.update(entropy, additionalInput)
+}
+
+func (s *hmacDRBG) generate(out []byte, additionalInput []byte, reseedCounter uint64) {
+    /* Fills out with V = HMAC(K, V), ..., then updates K and V */
+    if len(additionalInput) > 0 {
+        s.update(additionalInput)
+    }
+    for i := 0; i < len(out); i += len(s.V) {
+        s.prf.Reset()
+        s.prf.Write(s.V)
+        s.V = s.prf.Sum(s.V[:0])
+        copy(out[i:], s.V)
+    }
+    s.update(additionalInput)
+}
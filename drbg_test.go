@@ -0,0 +1,96 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/hex"
+)
+
+// Hash functions used in the [SHA-...] sections of the CAVP DRBG files
+var cavpDRBGHash = map[string]Hash{"SHA-1": HashSHA1, "SHA-224": HashSHA224, "SHA-256": HashSHA256, "SHA-384": HashSHA384, "SHA-512": HashSHA512, "SHA-512/224": HashSHA512_224, "SHA-512/256": HashSHA512_256}
+
+func testDRBG(t *testing.T, file string, newDRBG func(h Hash, entropy []byte, nonce []byte, personalization []byte) (*DRBG, error)) {
+    /* Runs the CAVP DRBG test: instantiate, then (without prediction
+     * resistance) reseed, then generate twice and check the second
+     * output */
+    var tested int
+    readRSP(t, file, func(section map[string]string, record map[string]string) {
+        h := cavpDRBGHash[section[""]]
+        d, err := newDRBG(h, mustHex(record["EntropyInput"]), mustHex(record["Nonce"]), mustHex(record["PersonalizationString"]))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if section["PredictionResistance"] == "True" {
+            d.PredictionResistance = true
+            d.Entropy = bytes.NewReader(mustHex(record["EntropyInputPR"] + record["EntropyInputPR2"]))
+        } else if reseed, ok := record["EntropyInputReseed"]; ok {
+            d.Reseed(mustHex(reseed), mustHex(record["AdditionalInputReseed"]))
+        }
+        output := make([]byte, len(record["ReturnedBits"])/2)
+        if err := d.Generate(output, mustHex(record["AdditionalInput"])); err != nil {
+            t.Fatal(err)
+        }
+        if err := d.Generate(output, mustHex(record["AdditionalInput2"])); err != nil {
+            t.Fatal(err)
+        }
+        if hex.EncodeToString(output) != record["ReturnedBits"] {
+            t.Errorf("\nTest: %s %s PR=%s COUNT=%s\nResult:   %x\nExpected: %s\n", file, h, section["PredictionResistance"], record["COUNT"], output, record["ReturnedBits"])
+        }
+        tested++
+    })
+    if tested == 0 {
+        t.Errorf("No tests found in %s", file)
+    }
+}
+
+func TestHashDRBG(t *testing.T) {
+    // The CAVP excerpt, then OpenSSL's output for the paths it lacks
+    testDRBG(t, "Hash_DRBG.rsp", NewHashDRBG)
+    testDRBG(t, "Hash_DRBG_generated.rsp", NewHashDRBG)
+}
+
+func TestHMACDRBG(t *testing.T) {
+    testDRBG(t, "HMAC_DRBG.rsp", NewHMACDRBG)
+    testDRBG(t, "HMAC_DRBG_generated.rsp", NewHMACDRBG)
+}
+
+func TestDRBGReseed(t *testing.T) {
+    entropy := byteRange(0, 32)
+    d, _ := NewHMACDRBG(HashSHA256, entropy, nil, nil)
+    d.ReseedInterval = 2
+    output := make([]byte, 16)
+    for i := 0; i < 2; i++ {
+        if err := d.Generate(output, nil); err != nil {
+            t.Errorf("\nResult:   %v\nExpected: <nil>\n", err)
+        }
+    }
+    // The third request needs a reseed, which fails with no entropy source
+    if err := d.Generate(output, nil); err != ErrReseedRequired {
+        t.Errorf("\nResult:   %v\nExpected: %v\n", err, ErrReseedRequired)
+    }
+    d.Entropy = bytes.NewReader(entropy)
+    if err := d.Generate(output, nil); err != nil {
+        t.Errorf("\nResult:   %v\nExpected: <nil>\n", err)
+    }
+    // Too little entropy is rejected
+    if _, err := NewHashDRBG(HashSHA256, entropy[:16], nil, nil); err == nil {
+        t.Errorf("Expected an error for 128 bits of entropy with SHA-256")
+    }
+}
+
+func TestDRBGRead(t *testing.T) {
+    // Read splits long requests into DRBGMaxRequest bytes at a time
+    a, _ := NewHashDRBG(HashSHA512, byteRange(0, 32), byteRange(32, 48), nil)
+    b, _ := NewHashDRBG(HashSHA512, byteRange(0, 32), byteRange(32, 48), nil)
+    result := make([]byte, DRBGMaxRequest + 100)
+    a.Read(result)
+    expected := make([]byte, DRBGMaxRequest + 100)
+    b.Generate(expected[:DRBGMaxRequest], nil)
+    b.Generate(expected[DRBGMaxRequest:], nil)
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result[DRBGMaxRequest:], expected[DRBGMaxRequest:])
+    }
+    if err := a.Generate(result, nil); err == nil {
+        t.Errorf("Expected an error for a request of more than DRBGMaxRequest bytes")
+    }
+}
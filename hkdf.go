@@ -0,0 +1,84 @@
+package sha
+
+import (
+    "errors"
+    "io"
+)
+
+/* HKDF extract-and-expand key derivation (RFC 5869) */
+
+// Returned when more than 255 blocks of HKDF output are requested
+var ErrHKDFLimit = errors.New("sha: HKDF output is limited to 255 times the hash size")
+
+func HKDFExtract(h Hash, salt []byte, IKM []byte) []byte {
+    /* Takes a hash function, an optional salt and the input keying
+     * material, and returns the pseudorandom key PRK = HMAC(salt, IKM).
+     * A missing salt is replaced by a string of HashLen zeros */
+    if len(salt) == 0 {
+        salt = make([]byte, h.Size())
+    }
+    return HMACSum(h, salt, IKM)
+}
+
+// Reads the output keying material of HKDF-Expand, one block at a time
+type HKDFReader struct {
+    m *HMAC           // HMAC keyed with PRK
+    info []byte       // Context and application specific information
+    T []byte          // Last block of output, T(i)
+    i int             // Number of blocks so far
+    n int             // Number of bytes of T(i) already read
+}
+
+func NewHKDFReader(h Hash, PRK []byte, info []byte) *HKDFReader {
+    /* Takes a hash function, a pseudorandom key and optional info, and
+     * returns a reader for the output of HKDF-Expand */
+    r := &HKDFReader{m: NewHMAC(h, PRK)}
+    r.info = append(r.info, info...)
+    return r
+}
+
+func (r *HKDFReader) Read(p []byte) (int, error) {
+    /* Fills p with the next bytes of output. Returns ErrHKDFLimit once
+     * 255 blocks have been read */
+    read := 0
+    for read < len(p) {
+        if r.n == len(r.T) {
+            if r.i == 255 {
+                return read, ErrHKDFLimit
+            }
+            // T(i) = HMAC(PRK, T(i-1) || info || i)
+            r.i++
+            r.m.Reset()
+            r.m.Write(r.T)
+            r.m.Write(r.info)
+            r.m.Write([]byte{byte(r.i)})
+            r.T = r.m.Sum(r.T[:0])
+            r.n = 0
+        }
+        c := copy(p[read:], r.T[r.n:])
+        r.n += c
+        read += c
+    }
+    return read, nil
+}
+
+func HKDFExpand(h Hash, PRK []byte, info []byte, L int) ([]byte, error) {
+    /* Takes a hash function, a pseudorandom key, optional info and a
+     * length L in bytes, and returns L bytes of output keying material.
+     * L can be at most 255 * HashLen */
+    if L > 255 * h.Size() {
+        return nil, ErrHKDFLimit
+    }
+    OKM := make([]byte, L)
+    if _, err := io.ReadFull(NewHKDFReader(h, PRK, info), OKM); err != nil {
+        return nil, err
+    }
+    return OKM, nil
+}
+
+func HKDFKey(h Hash, IKM []byte, salt []byte, info []byte, L int) ([]byte, error) {
+    /* Takes a hash function, the input keying material, an optional salt
+     * and info, and a length L in bytes, and returns L bytes of output
+     * keying material from HKDF-Extract followed by HKDF-Expand */
+    return HKDFExpand(h, HKDFExtract(h, salt, IKM), info, L)
+}
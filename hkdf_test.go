@@ -0,0 +1,122 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/hex"
+    "io"
+)
+
+func byteRange(start int, end int) []byte {
+    /* Returns the bytes start, start+1, ..., end-1 */
+    output := make([]byte, 0, end-start)
+    for i := start; i < end; i++ {
+        output = append(output, byte(i))
+    }
+    return output
+}
+
+// Test cases from RFC 5869 appendix A
+var hkdfTests = []struct {
+    h Hash
+    IKM []byte
+    salt []byte
+    info []byte
+    L int
+    PRK string
+    OKM string
+}{
+    {HashSHA256, bytes.Repeat([]byte{0x0b}, 22), byteRange(0x00, 0x0d), byteRange(0xf0, 0xfa), 42,
+        "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+        "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"},
+    {HashSHA256, byteRange(0x00, 0x50), byteRange(0x60, 0xb0), byteRange(0xb0, 0x100), 82,
+        "06a6b88c5853361a06104c9ceb35b45cef760014904671014a193f40c15fc244",
+        "b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71cc30c58179ec3e87c14c01d5c1f3434f1d87"},
+    {HashSHA256, bytes.Repeat([]byte{0x0b}, 22), []byte{}, []byte{}, 42,
+        "19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+        "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"},
+    {HashSHA1, bytes.Repeat([]byte{0x0b}, 11), byteRange(0x00, 0x0d), byteRange(0xf0, 0xfa), 42,
+        "9b6c18c432a7bf8f0e71c8eb88f4b30baa2ba243",
+        "085a01ea1b10f36933068b56efa5ad81a4f14b822f5b091568a9cdd4f155fda2c22e422478d305f3f896"},
+    {HashSHA1, byteRange(0x00, 0x50), byteRange(0x60, 0xb0), byteRange(0xb0, 0x100), 82,
+        "8adae09a2a307059478d309b26c4115a224cfaf6",
+        "0bd770a74d1160f7c9f12cd5912a06ebff6adcae899d92191fe4305673ba2ffe8fa3f1a4e5ad79f3f334b3b202b2173c486ea37ce3d397ed034c7f9dfeb15c5e927336d0441f4c4300e2cff0d0900b52d3b4"},
+    {HashSHA1, bytes.Repeat([]byte{0x0b}, 22), []byte{}, []byte{}, 42,
+        "da8c8a73c7fa77288ec6f5e7c297786aa0d32d01",
+        "0ac1af7002b3d761d1e55298da9d0506b9ae52057220a306e07b6b87e8df21d0ea00033de03984d34918"},
+    {HashSHA1, bytes.Repeat([]byte{0x0c}, 22), nil, []byte{}, 42,
+        "2adccada18779e7c2077ad2eb19d3f3e731385dd",
+        "2c91117204d745f3500d636a62f64f0ab3bae548aa53d423b0d1f27ebba6f5e5673a081d70cce7acfc48"},
+}
+
+func TestHKDFExtract(t *testing.T) {
+    for i, test := range hkdfTests {
+        result := hex.EncodeToString(HKDFExtract(test.h, test.salt, test.IKM))
+        if result != test.PRK {
+            t.Errorf("\nTest: case %d\nResult:   %s\nExpected: %s\n", i+1, result, test.PRK)
+        }
+    }
+}
+
+func TestHKDFExpand(t *testing.T) {
+    for i, test := range hkdfTests {
+        PRK, _ := hex.DecodeString(test.PRK)
+        OKM, err := HKDFExpand(test.h, PRK, test.info, test.L)
+        if err != nil {
+            t.Errorf("\nTest: case %d\nError: %s\n", i+1, err)
+            continue
+        }
+        result := hex.EncodeToString(OKM)
+        if result != test.OKM {
+            t.Errorf("\nTest: case %d\nResult:   %s\nExpected: %s\n", i+1, result, test.OKM)
+        }
+    }
+}
+
+func TestHKDFKey(t *testing.T) {
+    for i, test := range hkdfTests {
+        OKM, err := HKDFKey(test.h, test.IKM, test.salt, test.info, test.L)
+        if err != nil {
+            t.Errorf("\nTest: case %d\nError: %s\n", i+1, err)
+            continue
+        }
+        result := hex.EncodeToString(OKM)
+        if result != test.OKM {
+            t.Errorf("\nTest: case %d\nResult:   %s\nExpected: %s\n", i+1, result, test.OKM)
+        }
+    }
+}
+
+func TestHKDFReader(t *testing.T) {
+    // Reading in small pieces should give the same output
+    test := hkdfTests[1]
+    PRK, _ := hex.DecodeString(test.PRK)
+    r := NewHKDFReader(test.h, PRK, test.info)
+    result := make([]byte, test.L)
+    for i := 0; i < test.L; i += 5 {
+        end := i + 5
+        if end > test.L {
+            end = test.L
+        }
+        r.Read(result[i:end])
+    }
+    if hex.EncodeToString(result) != test.OKM {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", result, test.OKM)
+    }
+}
+
+func TestHKDFLimit(t *testing.T) {
+    // 255 * HashLen bytes is allowed, but one more byte is not
+    PRK := make([]byte, 32)
+    if _, err := HKDFExpand(HashSHA256, PRK, nil, 255*32); err != nil {
+        t.Errorf("\nError: %s\n", err)
+    }
+    if _, err := HKDFExpand(HashSHA256, PRK, nil, 255*32+1); err != ErrHKDFLimit {
+        t.Errorf("\nResult:   %v\nExpected: %v\n", err, ErrHKDFLimit)
+    }
+    r := NewHKDFReader(HashSHA256, PRK, nil)
+    n, err := io.ReadFull(r, make([]byte, 255*32+1))
+    if n != 255*32 || err != ErrHKDFLimit {
+        t.Errorf("\nResult:   %d, %v\nExpected: %d, %v\n", n, err, 255*32, ErrHKDFLimit)
+    }
+}
@@ -0,0 +1,122 @@
+package sha
+
+import (
+    "crypto/subtle"
+    "hash"
+)
+
+/* HMAC (RFC 2104 & FIPS 198-1) */
+
+// An HMAC, which implements hash.Hash. The hash states after the inner and
+// outer padded keys are computed once by NewHMAC, so Reset is cheap and
+// one HMAC can authenticate many messages with the same key
+type HMAC struct {
+    h Hash            // Underlying hash function
+    inner midstate    // Hash of K0 xor ipad
+    outer midstate    // Hash of K0 xor opad
+    current midstate  // Inner hash of the message so far
+    tmp midstate      // Outer hash, reused by Sum
+    sum [64]byte      // Inner hash, reused by Sum
+}
+
+func NewHMAC(h Hash, key []byte) *HMAC {
+    /* Returns an HMAC using the hash function h and the given key */
+    m := &HMAC{h: h}
+    m.inner = h.New().(midstate)
+    m.outer = h.New().(midstate)
+    B := m.inner.BlockSize()
+    // Keys longer than the block size are hashed, then all keys are
+    // padded with zeros to the block size to give K0
+    K0 := make([]byte, B)
+    if len(key) > B {
+        copy(K0, h.Sum(key))
+    } else {
+        copy(K0, key)
+    }
+    // Absorb K0 xor ipad into the inner hash and K0 xor opad into the outer
+    pad := make([]byte, B)
+    for i := 0; i < B; i++ {
+        pad[i] = K0[i] ^ 0x36
+    }
+    m.inner.Write(pad)
+    for i := 0; i < B; i++ {
+        pad[i] = K0[i] ^ 0x5c
+    }
+    m.outer.Write(pad)
+    m.current = m.inner.clone()
+    m.tmp = m.outer.clone()
+    return m
+}
+
+func (m *HMAC) Write(p []byte) (int, error) {
+    /* Adds p to the message being authenticated */
+    return m.current.Write(p)
+}
+
+func (m *HMAC) Sum(b []byte) []byte {
+    /* Appends the HMAC of the message so far to b, without changing the
+     * state of the HMAC */
+    // H((K0 xor opad) || H((K0 xor ipad) || text))
+    innerHash := m.current.Sum(m.sum[:0])
+    m.tmp.restore(m.outer)
+    m.tmp.Write(innerHash)
+    return m.tmp.Sum(b)
+}
+
+func (m *HMAC) Verify(tag []byte) bool {
+    /* Checks in constant time whether tag matches the HMAC of the message
+     * so far. The tag may be truncated to its leftmost bytes, but not to
+     * fewer than half the hash output or 80 bits, following RFC 2104
+     * section 5 */
+    if len(tag) > m.Size() || len(tag) < m.Size()/2 || len(tag) < 10 {
+        return false
+    }
+    var buf [64]byte
+    expected := m.Sum(buf[:0])
+    return subtle.ConstantTimeCompare(expected[:len(tag)], tag) == 1
+}
+
+func (m *HMAC) Reset() {
+    /* Resets the HMAC to its state just after the key was added */
+    m.current.restore(m.inner)
+}
+
+func (m *HMAC) Size() int {
+    return m.current.Size()
+}
+
+func (m *HMAC) BlockSize() int {
+    return m.current.BlockSize()
+}
+
+func (m *HMAC) clone() *HMAC {
+    /* Returns a copy of the HMAC which shares its key midstates, so it can
+     * be used by another goroutine without rehashing the key */
+    n := *m
+    n.current = m.current.clone()
+    n.tmp = m.tmp.clone()
+    return &n
+}
+
+func (m *HMAC) Hash() Hash {
+    /* Returns the hash function used by the HMAC */
+    return m.h
+}
+
+func HMACSum(h Hash, key []byte, message []byte) []byte {
+    /* Takes a hash function, a key and a message, and returns the HMAC */
+    m := NewHMAC(h, key)
+    m.Write(message)
+    return m.Sum(nil)
+}
+
+func VerifyHMAC(h Hash, key []byte, message []byte, tag []byte) bool {
+    /* Checks in constant time whether tag, which may be truncated, is the
+     * HMAC of message with the given key */
+    m := NewHMAC(h, key)
+    m.Write(message)
+    return m.Verify(tag)
+}
+
+// Checks that HMAC implements hash.Hash
+var _ hash.Hash = (*HMAC)(nil)
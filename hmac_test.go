@@ -0,0 +1,156 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/hex"
+)
+
+func TestHMACSHA2(t *testing.T) {
+    // Test cases 1-4, 6 & 7 from RFC 4231
+    tests := []struct {
+        key []byte
+        data []byte
+        expected [4]string  // HMAC-SHA-224, 256, 384 & 512
+    }{
+        {bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), [4]string{
+            "896fb1128abbdf196832107cd49df33f47b4b1169912ba4f53684b22",
+            "b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7",
+            "afd03944d84895626b0825f4ab46907f15f9dadbe4101ec682aa034c7cebc59cfaea9ea9076ede7f4af152e8b2fa9cb6",
+            "87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cdedaa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854"}},
+        {[]byte("Jefe"), []byte("what do ya want for nothing?"), [4]string{
+            "a30e01098bc6dbbf45690f3a7e9e6d0f8bbea2a39e6148008fd05e44",
+            "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+            "af45d2e376484031617f78d2b58a6b1b9c7ef464f5a01b47e42ec3736322445e8e2240ca5e69e2c78b3239ecfab21649",
+            "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"}},
+        {bytes.Repeat([]byte{0xaa}, 20), bytes.Repeat([]byte{0xdd}, 50), [4]string{
+            "7fb3cb3588c6c1f6ffa9694d7d6ad2649365b0c1f65d69d1ec8333ea",
+            "773ea91e36800e46854db8ebd09181a72959098b3ef8c122d9635514ced565fe",
+            "88062608d3e6ad8a0aa2ace014c8a86f0aa635d947ac9febe83ef4e55966144b2a5ab39dc13814b94e3ab6e101a34f27",
+            "fa73b0089d56a284efb0f0756c890be9b1b5dbdd8ee81a3655f83e33b2279d39bf3e848279a722c806b485a47e67c807b946a337bee8942674278859e13292fb"}},
+        {[]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19}, bytes.Repeat([]byte{0xcd}, 50), [4]string{
+            "6c11506874013cac6a2abc1bb382627cec6a90d86efc012de7afec5a",
+            "82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b",
+            "3e8a69b7783c25851933ab6290af6ca77a9981480850009cc5577c6e1f573b4e6801dd23c4a7d679ccf8a386c674cffb",
+            "b0ba465637458c6990e5a8c5f61d4af7e576d97ff94b872de76f8050361ee3dba91ca5c11aa25eb4d679275cc5788063a5f19741120c4f2de2adebeb10a298dd"}},
+        {bytes.Repeat([]byte{0xaa}, 131), []byte("Test Using Larger Than Block-Size Key - Hash Key First"), [4]string{
+            "95e9a0db962095adaebe9b2d6f0dbce2d499f112f2d2b7273fa6870e",
+            "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54",
+            "4ece084485813e9088d2c63a041bc5b44f9ef1012a2b588f3cd11f05033ac4c60c2ef6ab4030fe8296248df163f44952",
+            "80b24263c7c1a3ebb71493c1dd7be8b49b46d1f41b4aeec1121b013783f8f3526b56d037e05f2598bd0fd2215d6a1e5295e64f73f63f0aec8b915a985d786598"}},
+        {bytes.Repeat([]byte{0xaa}, 131), []byte("This is a test using a larger than block-size key and a larger than block-size data. The key needs to be hashed before being used by the HMAC algorithm."), [4]string{
+            "3a854166ac5d9f023f54d517d0b39dbd946770db9c2b95c9f6f565d1",
+            "9b09ffa71b942fcb27635fbcd5b0e944bfdc63644f0713938a7f51535c3a35e2",
+            "6617178e941f020d351e2f254e8fd32c602420feb0b8fb9adccebb82461e99c5a678cc31e799176d3860e6110c46523e",
+            "e37b6a775dc87dbaa4dfa9f96e5e3ffddebd71f8867289865df5a32d20cdc944b6022cac3c4982b10d5eeb55c3e4de15134676fb6de0446065c97440fa8c6a58"}},
+    }
+    hashes := [4]Hash{HashSHA224, HashSHA256, HashSHA384, HashSHA512}
+    for _, test := range tests {
+        for i, h := range hashes {
+            result := hex.EncodeToString(HMACSum(h, test.key, test.data))
+            if result != test.expected[i] {
+                t.Errorf("\nTest: HMAC-%s(%q)\nResult:   %s\nExpected: %s\n", h, test.data, result, test.expected[i])
+            }
+        }
+    }
+}
+
+func TestHMACTruncated(t *testing.T) {
+    // Test case 5 from RFC 4231, with output truncated to 128 bits
+    key := bytes.Repeat([]byte{0x0c}, 20)
+    data := []byte("Test With Truncation")
+    tests := []struct {
+        h Hash
+        expected string
+    }{
+        {HashSHA224, "0e2aea68a90c8d37c988bcdb9fca6fa8"},
+        {HashSHA256, "a3b6167473100ee06e0c796c2955552b"},
+        {HashSHA384, "3abf34c3503b2a23a46efc619baef897"},
+        {HashSHA512, "415fad6271580a531d4179bc891d87a6"},
+    }
+    for _, test := range tests {
+        tag, _ := hex.DecodeString(test.expected)
+        result := HMACSum(test.h, key, data)[:16]
+        if !bytes.Equal(result, tag) {
+            t.Errorf("\nTest: HMAC-%s\nResult:   %x\nExpected: %x\n", test.h, result, tag)
+        }
+        // Tags shorter than half the hash output are refused, so only the
+        // SHA-224 and SHA-256 tags should be verified
+        if VerifyHMAC(test.h, key, data, tag) != (test.h.Size() <= 32) {
+            t.Errorf("\nTest: HMAC-%s\nTruncated tag of %d bytes was wrongly verified or refused\n", test.h, len(tag))
+        }
+    }
+}
+
+func TestHMACSHA1(t *testing.T) {
+    // Test cases from RFC 2202
+    tests := []struct {
+        key []byte
+        data []byte
+        expected string
+    }{
+        {bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), "b617318655057264e28bc0b6fb378c8ef146be00"},
+        {[]byte("Jefe"), []byte("what do ya want for nothing?"), "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79"},
+        {bytes.Repeat([]byte{0xaa}, 20), bytes.Repeat([]byte{0xdd}, 50), "125d7342b9ac11cd91a39af48aa17b4f63f175d3"},
+        {[]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19}, bytes.Repeat([]byte{0xcd}, 50), "4c9007f4026250c6bc8414f9bf50c86c2d7235da"},
+        {bytes.Repeat([]byte{0x0c}, 20), []byte("Test With Truncation"), "4c1a03424b55e07fe7f27be1d58bb9324a9a5a04"},
+        {bytes.Repeat([]byte{0xaa}, 80), []byte("Test Using Larger Than Block-Size Key - Hash Key First"), "aa4ae5e15272d00e95705637ce8a3b55ed402112"},
+        {bytes.Repeat([]byte{0xaa}, 80), []byte("Test Using Larger Than Block-Size Key and Larger Than One Block-Size Data"), "e8e99d0f45237d786d6bbaa7965c7808bbff1a91"},
+    }
+    for _, test := range tests {
+        result := hex.EncodeToString(HMACSum(HashSHA1, test.key, test.data))
+        if result != test.expected {
+            t.Errorf("\nTest: HMAC-SHA-1(%q)\nResult:   %s\nExpected: %s\n", test.data, result, test.expected)
+        }
+    }
+}
+
+func TestHMACOtherHashes(t *testing.T) {
+    // Input: key "key", message "The quick brown fox jumps over the lazy dog"
+    key := []byte("key")
+    data := []byte("The quick brown fox jumps over the lazy dog")
+    // Expected: values from Python's hmac module
+    tests := []struct {
+        h Hash
+        expected string
+    }{
+        {HashSHA512_224, "a1afb4f708cb63570639195121785ada3dc615989cc3c73f38e306a3"},
+        {HashSHA512_256, "7fb65e03577da9151a1016e9c2e514d4d48842857f13927f348588173dca6d89"},
+        {HashSHA3_256, "8c6e0683409427f8931711b10ca92a506eb1fafa48fadd66d76126f47ac2c333"},
+    }
+    for _, test := range tests {
+        result := hex.EncodeToString(HMACSum(test.h, key, data))
+        if result != test.expected {
+            t.Errorf("\nTest: HMAC-%s\nResult:   %s\nExpected: %s\n", test.h, result, test.expected)
+        }
+    }
+}
+
+func TestHMACReuse(t *testing.T) {
+    // One HMAC should authenticate several messages after Reset
+    m := NewHMAC(HashSHA256, []byte("Jefe"))
+    m.Write([]byte("something else"))
+    m.Reset()
+    m.Write([]byte("what do ya want "))
+    m.Write([]byte("for nothing?"))
+    expected, _ := hex.DecodeString("5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843")
+    result := m.Sum(nil)
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+    if !m.Verify(expected) {
+        t.Errorf("\nCorrect tag was not verified\n")
+    }
+    // Altered, over-truncated and over-long tags should be rejected
+    expected[0] ^= 1
+    if m.Verify(expected) {
+        t.Errorf("\nAltered tag was verified\n")
+    }
+    expected[0] ^= 1
+    if !m.Verify(expected[:16]) {
+        t.Errorf("\nTag truncated to half the hash output was not verified\n")
+    }
+    if m.Verify(expected[:15]) || m.Verify(append(expected, 0)) {
+        t.Errorf("\nTag of the wrong length was verified\n")
+    }
+}
@@ -0,0 +1,569 @@
+package sha
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+/* HTTP Digest access authentication (RFC 7616) with the SHA-256 and
+ * SHA-512-256 algorithms and their -sess variants, as a client
+ * http.RoundTripper and a server middleware. Only the "auth" quality of
+ * protection is supported: MD5 and "auth-int" are not */
+
+// How long a server nonce is accepted by default, after which clients are
+// sent a fresh one marked stale
+const DigestNonceLifetime = 5 * time.Minute
+
+// The algorithm names of RFC 7616, in the client's order of preference
+var digestAlgorithms = []struct {
+    name string
+    h Hash
+}{
+    {"SHA-512-256", HashSHA512_256},
+    {"SHA-256", HashSHA256},
+}
+
+// Returned by DigestTransport when the server's rspauth is wrong, so it
+// does not know the password
+var ErrDigestRspauth = errors.New("sha: digest rspauth does not match")
+
+// Returned internally when a response was right but its nonce has expired
+var errDigestStale = errors.New("sha: stale digest nonce")
+
+var errDigestAuth = errors.New("sha: digest authentication failed")
+
+// A client http.RoundTripper which answers Digest challenges. After the
+// first challenge from a host, later requests are authorized up front with
+// the same nonce and an increasing nonce count
+type DigestTransport struct {
+    Username string
+    Password string
+    Transport http.RoundTripper  // http.DefaultTransport if nil
+    mu sync.Mutex
+    sessions map[string]*digestSession
+}
+
+// A server's challenge, as held by a client
+type digestSession struct {
+    h Hash
+    sess bool
+    algorithm string
+    realm string
+    nonce string
+    opaque string
+    userhash bool
+    nc uint32
+}
+
+// Server middleware which requires Digest authentication
+type DigestAuth struct {
+    Realm string
+    Hash Hash                // HashSHA256 or HashSHA512_256
+    Session bool             // Use the -sess variant of the algorithm
+    UserHash bool            // Ask clients to send H(username:realm)
+    // Returns DigestHA1 of the user's password, or an error if there is no
+    // such user. With UserHash, it is given the hex H(username:realm)
+    // rather than the username
+    Lookup func(username string) (string, error)
+    NonceLifetime time.Duration  // DigestNonceLifetime if zero
+    mu sync.Mutex
+    key []byte                   // The HMAC key of nonces
+    nonces map[string]*digestNonce
+    pruned time.Time             // When expired nonces were last dropped
+}
+
+// A nonce which a client has answered, with the nonce counts it has
+// accepted
+type digestNonce struct {
+    expires time.Time
+    used map[uint32]bool
+}
+
+// The context key of the authenticated username
+type digestUserKey struct{}
+
+func digestAlgorithm(h Hash, sess bool) string {
+    /* Returns the name of an algorithm, or "" if it is not supported */
+    for _, a := range digestAlgorithms {
+        if a.h == h {
+            if sess {
+                return a.name + "-sess"
+            }
+            return a.name
+        }
+    }
+    return ""
+}
+
+func digestHash(h Hash, s string) string {
+    /* H(s), as lower case hex */
+    return hex.EncodeToString(h.Sum([]byte(s)))
+}
+
+func DigestHA1(h Hash, username string, realm string, password string) string {
+    /* Returns H(username:realm:password), which a server can store instead
+     * of the password */
+    return digestHash(h, username + ":" + realm + ":" + password)
+}
+
+func digestResponse(h Hash, sess bool, ha1 string, nonce string, nc string, cnonce string, method string, uri string) string {
+    /* Returns the response of section 3.4.1 for the "auth" quality of
+     * protection, or the rspauth of section 3.5 if method is empty */
+    if sess {
+        ha1 = digestHash(h, ha1 + ":" + nonce + ":" + cnonce)
+    }
+    ha2 := digestHash(h, method + ":" + uri)
+    return digestHash(h, ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+}
+
+/* Header parsing */
+
+// A challenge or credentials: an authentication scheme and its parameters
+type digestChallenge struct {
+    scheme string
+    params map[string]string
+}
+
+func parseAuthHeader(values []string) []digestChallenge {
+    /* Parses WWW-Authenticate or Authorization header values, each of which
+     * may hold several comma-separated challenges. Parameter names are
+     * made lower case */
+    var challenges []digestChallenge
+    for _, s := range values {
+        for {
+            s = strings.TrimLeft(s, " \t,")
+            if s == "" {
+                break
+            }
+            i := strings.IndexAny(s, " \t,=")
+            if i < 0 {
+                i = len(s)
+            }
+            token := s[:i]
+            rest := strings.TrimLeft(s[i:], " \t")
+            if !strings.HasPrefix(rest, "=") {
+                challenges = append(challenges, digestChallenge{scheme: token, params: map[string]string{}})
+                s = rest
+                continue
+            }
+            var value string
+            value, s = parseAuthValue(strings.TrimLeft(rest[1:], " \t"))
+            // Parameters before any scheme are ignored
+            if len(challenges) > 0 {
+                challenges[len(challenges)-1].params[strings.ToLower(token)] = value
+            }
+        }
+    }
+    return challenges
+}
+
+func parseAuthValue(s string) (string, string) {
+    /* Reads a token or quoted string, returning it and the rest of s */
+    if !strings.HasPrefix(s, "\"") {
+        i := strings.IndexAny(s, " \t,")
+        if i < 0 {
+            return s, ""
+        }
+        return s[:i], s[i:]
+    }
+    var value strings.Builder
+    for i := 1; i < len(s); i++ {
+        switch s[i] {
+        case '"':
+            return value.String(), s[i+1:]
+        case '\\':
+            if i + 1 < len(s) {
+                i++
+            }
+        }
+        value.WriteByte(s[i])
+    }
+    return value.String(), ""
+}
+
+func digestQuote(s string) string {
+    /* Returns s as a quoted string */
+    return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(s) + "\""
+}
+
+func digestExtValue(s string) (string, bool) {
+    /* Returns s percent-encoded as an RFC 8187 ext-value, and whether it
+     * needs to be, having characters which cannot go in a quoted string */
+    needed := false
+    var value strings.Builder
+    value.WriteString("UTF-8''")
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case c >= 0x80 || c < 0x20 || c == 0x7f:
+            needed = true
+            fmt.Fprintf(&value, "%%%02X", c)
+        case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0:
+            value.WriteByte(c)
+        default:
+            fmt.Fprintf(&value, "%%%02X", c)
+        }
+    }
+    return value.String(), needed
+}
+
+func digestRandom() (string, error) {
+    /* Returns a random nonce or cnonce */
+    b := make([]byte, 18)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+/* Client */
+
+func newDigestSession(values []string) *digestSession {
+    /* Returns the most preferred Digest challenge the client can answer,
+     * or nil if there is none */
+    var best *digestSession
+    rank := len(digestAlgorithms)
+    for _, c := range parseAuthHeader(values) {
+        if !strings.EqualFold(c.scheme, "Digest") || c.params["nonce"] == "" {
+            continue
+        }
+        auth := false
+        for _, qop := range strings.Split(c.params["qop"], ",") {
+            auth = auth || strings.EqualFold(strings.TrimSpace(qop), "auth")
+        }
+        name, sess := strings.CutSuffix(strings.ToUpper(c.params["algorithm"]), "-SESS")
+        for i, a := range digestAlgorithms {
+            if auth && name == a.name && i < rank {
+                rank = i
+                best = &digestSession{
+                    h: a.h,
+                    sess: sess,
+                    algorithm: c.params["algorithm"],
+                    realm: c.params["realm"],
+                    nonce: c.params["nonce"],
+                    opaque: c.params["opaque"],
+                    userhash: strings.EqualFold(c.params["userhash"], "true"),
+                }
+            }
+        }
+    }
+    return best
+}
+
+func (t *DigestTransport) authorization(s *digestSession, method string, uri string) (string, string, error) {
+    /* Returns the Authorization header for a request, with the session's
+     * next nonce count, and the rspauth the server should answer with */
+    cnonce, err := digestRandom()
+    if err != nil {
+        return "", "", err
+    }
+    t.mu.Lock()
+    s.nc++
+    nc := fmt.Sprintf("%08x", s.nc)
+    t.mu.Unlock()
+    ha1 := DigestHA1(s.h, t.Username, s.realm, t.Password)
+    username := "username=" + digestQuote(t.Username)
+    if s.userhash {
+        username = "username=" + digestQuote(digestHash(s.h, t.Username + ":" + s.realm))
+    } else if ext, needed := digestExtValue(t.Username); needed {
+        username = "username*=" + ext
+    }
+    header := "Digest " + username +
+        ", realm=" + digestQuote(s.realm) +
+        ", uri=" + digestQuote(uri) +
+        ", algorithm=" + s.algorithm +
+        ", nonce=" + digestQuote(s.nonce) +
+        ", nc=" + nc +
+        ", cnonce=" + digestQuote(cnonce) +
+        ", qop=auth" +
+        ", response=" + digestQuote(digestResponse(s.h, s.sess, ha1, s.nonce, nc, cnonce, method, uri))
+    if s.opaque != "" {
+        header += ", opaque=" + digestQuote(s.opaque)
+    }
+    if s.userhash {
+        header += ", userhash=true"
+    }
+    return header, digestResponse(s.h, s.sess, ha1, s.nonce, nc, cnonce, "", uri), nil
+}
+
+func (t *DigestTransport) authorize(req *http.Request, s *digestSession, body io.ReadCloser) (*http.Request, string, error) {
+    /* Returns a copy of the request with an Authorization header, and the
+     * expected rspauth */
+    header, rspauth, err := t.authorization(s, req.Method, req.URL.RequestURI())
+    if err != nil {
+        return nil, "", err
+    }
+    r := req.Clone(req.Context())
+    r.Body = body
+    r.Header.Set("Authorization", header)
+    return r, rspauth, nil
+}
+
+func checkRspauth(resp *http.Response, rspauth string) (*http.Response, error) {
+    /* Checks the rspauth of the Authentication-Info header, which proves
+     * that the server knows the password. The header is optional, so a
+     * response without one is passed on unchecked */
+    values := resp.Header.Values("Authentication-Info")
+    if len(values) == 0 {
+        return resp, nil
+    }
+    // The header has parameters but no scheme
+    info := parseAuthHeader([]string{"Digest " + strings.Join(values, ", ")})
+    if len(info) != 1 || subtle.ConstantTimeCompare([]byte(info[0].params["rspauth"]), []byte(rspauth)) != 1 {
+        resp.Body.Close()
+        return nil, ErrDigestRspauth
+    }
+    return resp, nil
+}
+
+func (t *DigestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    /* Sends a request, answering a Digest challenge by sending it again.
+     * A request with a body is only sent again if it has GetBody set, as
+     * http.NewRequest does for in-memory bodies. If the server sends
+     * Authentication-Info, a wrong rspauth gives ErrDigestRspauth */
+    transport := t.Transport
+    if transport == nil {
+        transport = http.DefaultTransport
+    }
+    t.mu.Lock()
+    s := t.sessions[req.URL.Host]
+    t.mu.Unlock()
+    first := req
+    var rspauth string
+    if s != nil {
+        var err error
+        if first, rspauth, err = t.authorize(req, s, req.Body); err != nil {
+            return nil, err
+        }
+    }
+    resp, err := transport.RoundTrip(first)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode != http.StatusUnauthorized {
+        if s != nil {
+            return checkRspauth(resp, rspauth)
+        }
+        return resp, nil
+    }
+    s = newDigestSession(resp.Header.Values("WWW-Authenticate"))
+    if s == nil {
+        return resp, nil
+    }
+    var body io.ReadCloser
+    if req.Body != nil && req.Body != http.NoBody {
+        if req.GetBody == nil {
+            return resp, nil
+        }
+        if body, err = req.GetBody(); err != nil {
+            return resp, nil
+        }
+    }
+    // Drain the response so that the connection can be reused
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 1 << 16))
+    resp.Body.Close()
+    t.mu.Lock()
+    if t.sessions == nil {
+        t.sessions = map[string]*digestSession{}
+    }
+    t.sessions[req.URL.Host] = s
+    t.mu.Unlock()
+    retry, rspauth, err := t.authorize(req, s, body)
+    if err != nil {
+        return nil, err
+    }
+    resp, err = transport.RoundTrip(retry)
+    if err != nil || resp.StatusCode == http.StatusUnauthorized {
+        return resp, err
+    }
+    return checkRspauth(resp, rspauth)
+}
+
+/* Server */
+
+func DigestUsername(r *http.Request) string {
+    /* Returns the username authenticated by DigestAuth.Handler */
+    username, _ := r.Context().Value(digestUserKey{}).(string)
+    return username
+}
+
+func (a *DigestAuth) Handler(next http.Handler) http.Handler {
+    /* Returns a handler which passes requests with valid credentials to
+     * next, with an Authentication-Info header, and answers others with
+     * 401 Unauthorized and a challenge */
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        username, info, err := a.verify(r)
+        if err != nil {
+            challenge, nonceErr := a.challenge(err == errDigestStale)
+            if nonceErr != nil {
+                http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+                return
+            }
+            w.Header().Add("WWW-Authenticate", challenge)
+            http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+            return
+        }
+        w.Header().Set("Authentication-Info", info)
+        next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), digestUserKey{}, username)))
+    })
+}
+
+func (a *DigestAuth) lifetime() time.Duration {
+    /* Returns how long a nonce is accepted */
+    if a.NonceLifetime == 0 {
+        return DigestNonceLifetime
+    }
+    return a.NonceLifetime
+}
+
+func (a *DigestAuth) newNonce(now time.Time) (string, error) {
+    /* Returns a nonce issued at time now: the time and random bytes, with
+     * an HMAC of them under the server's key, so that nothing is stored
+     * until a client answers it. Called with a.mu held */
+    if a.key == nil {
+        key := make([]byte, 32)
+        if _, err := rand.Read(key); err != nil {
+            return "", err
+        }
+        a.key = key
+    }
+    b := make([]byte, 16, 32)
+    binary.BigEndian.PutUint64(b, uint64(now.UnixNano()))
+    if _, err := rand.Read(b[8:]); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(append(b, HMACSum(HashSHA256, a.key, b)[:16]...)), nil
+}
+
+func (a *DigestAuth) nonceIssued(nonce string) (time.Time, bool) {
+    /* Returns the time a nonce was issued, if this server made it. Called
+     * with a.mu held */
+    b, err := hex.DecodeString(nonce)
+    if err != nil || len(b) != 32 || a.key == nil {
+        return time.Time{}, false
+    }
+    if subtle.ConstantTimeCompare(b[16:], HMACSum(HashSHA256, a.key, b[:16])[:16]) != 1 {
+        return time.Time{}, false
+    }
+    return time.Unix(0, int64(binary.BigEndian.Uint64(b))), true
+}
+
+func (a *DigestAuth) challenge(stale bool) (string, error) {
+    /* Issues a nonce and returns the WWW-Authenticate challenge for it */
+    a.mu.Lock()
+    nonce, err := a.newNonce(time.Now())
+    a.mu.Unlock()
+    if err != nil {
+        return "", err
+    }
+    challenge := "Digest realm=" + digestQuote(a.Realm) +
+        ", qop=\"auth\"" +
+        ", algorithm=" + digestAlgorithm(a.Hash, a.Session) +
+        ", nonce=" + digestQuote(nonce) +
+        ", charset=UTF-8"
+    if a.UserHash {
+        challenge += ", userhash=true"
+    }
+    if stale {
+        challenge += ", stale=true"
+    }
+    return challenge, nil
+}
+
+func (a *DigestAuth) verify(r *http.Request) (string, string, error) {
+    /* Checks the credentials of a request, returning the username and the
+     * Authentication-Info header. The response is checked before the
+     * nonce, so that only clients knowing the password are told that their
+     * nonce is stale */
+    algorithm := digestAlgorithm(a.Hash, a.Session)
+    if algorithm == "" {
+        return "", "", errors.New("sha: unsupported digest algorithm")
+    }
+    credentials := parseAuthHeader(r.Header.Values("Authorization"))
+    if len(credentials) != 1 || !strings.EqualFold(credentials[0].scheme, "Digest") {
+        return "", "", errDigestAuth
+    }
+    p := credentials[0].params
+    uri := r.RequestURI
+    if uri == "" {
+        uri = r.URL.RequestURI()
+    }
+    if !strings.EqualFold(p["algorithm"], algorithm) || p["qop"] != "auth" || p["realm"] != a.Realm || p["uri"] != uri || p["cnonce"] == "" || len(p["nc"]) != 8 {
+        return "", "", errDigestAuth
+    }
+    nc, err := strconv.ParseUint(p["nc"], 16, 32)
+    if err != nil {
+        return "", "", errDigestAuth
+    }
+    if strings.EqualFold(p["userhash"], "true") != a.UserHash {
+        return "", "", errDigestAuth
+    }
+    username, hasUsername := p["username"]
+    if ext, ok := p["username*"]; ok {
+        charset, value, found := strings.Cut(ext, "''")
+        if hasUsername || a.UserHash || !found || !strings.EqualFold(charset, "UTF-8") {
+            return "", "", errDigestAuth
+        }
+        if username, err = url.PathUnescape(value); err != nil {
+            return "", "", errDigestAuth
+        }
+    }
+    ha1, err := a.Lookup(username)
+    if err != nil {
+        return "", "", errDigestAuth
+    }
+    expected := digestResponse(a.Hash, a.Session, ha1, p["nonce"], p["nc"], p["cnonce"], r.Method, uri)
+    if subtle.ConstantTimeCompare([]byte(expected), []byte(p["response"])) != 1 {
+        return "", "", errDigestAuth
+    }
+    // Each nonce count of a nonce is accepted once. A nonce is stored
+    // when first answered, and expired ones are dropped once per lifetime
+    now := time.Now()
+    lifetime := a.lifetime()
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    issued := a.nonces[p["nonce"]]
+    if issued == nil {
+        when, ok := a.nonceIssued(p["nonce"])
+        if !ok {
+            return "", "", errDigestStale
+        }
+        if a.nonces == nil {
+            a.nonces = map[string]*digestNonce{}
+        }
+        if now.Sub(a.pruned) > lifetime {
+            for n, old := range a.nonces {
+                if now.After(old.expires) {
+                    delete(a.nonces, n)
+                }
+            }
+            a.pruned = now
+        }
+        issued = &digestNonce{expires: when.Add(lifetime), used: map[uint32]bool{}}
+        a.nonces[p["nonce"]] = issued
+    }
+    if now.After(issued.expires) {
+        return "", "", errDigestStale
+    }
+    if issued.used[uint32(nc)] {
+        return "", "", errDigestAuth
+    }
+    issued.used[uint32(nc)] = true
+    info := "qop=auth" +
+        ", rspauth=" + digestQuote(digestResponse(a.Hash, a.Session, ha1, p["nonce"], p["nc"], p["cnonce"], "", uri)) +
+        ", cnonce=" + digestQuote(p["cnonce"]) +
+        ", nc=" + p["nc"]
+    return username, info, nil
+}
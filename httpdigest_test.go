@@ -0,0 +1,243 @@
+package sha
+
+import (
+    "testing"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// The requests of RFC 7616 sections 3.9.1 and 3.9.2. The second's response
+// in the RFC does not match SHA-512/256 (see its errata), so its userhash
+// and response were computed for the same inputs with Python's hashlib
+var httpDigestTests = []struct {
+    h Hash
+    userhash bool
+    username string
+    realm string
+    password string
+    uri string
+    authorization string
+}{
+    {HashSHA256, false, "Mufasa", "http-auth@example.org", "Circle of Life", "/dir/index.html",
+        `Digest username="Mufasa", realm="http-auth@example.org", uri="/dir/index.html", algorithm=SHA-256, ` +
+        `nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", nc=00000001, cnonce="f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", qop=auth, ` +
+        `response="753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`},
+    {HashSHA512_256, true, "Jäsøn Doe", "api@example.org", "Secret, or not?", "/doc/index.html",
+        `Digest username="793263caabb707a56211940d90411ea4a575adeccb7e360aeb624ed06ece9b0b", realm="api@example.org", uri="/doc/index.html", algorithm=SHA-512-256, ` +
+        `nonce="5TsQWLVdgBdmrQ0XsxbDODV+57QdFR34I9HAbC/RVvkK", nc=00000001, cnonce="NTg6RKcb9boFIAS3KrFK9BGeh+iDa/sm6jUMp2wds69v", qop=auth, ` +
+        `response="93308f41873a77f41ea3d87886878276f1a92271362e72275c3d3a38cf9f5fd6", opaque="HRPCssKJSGjCrkzDg8OhwpzCiGPChXYjwrI2QmXDnsOS", userhash=true`},
+}
+
+func digestLookup(h Hash, realm string, userhash bool, users map[string]string) func(string) (string, error) {
+    return func(username string) (string, error) {
+        for user, password := range users {
+            if user == username || userhash && digestHash(h, user + ":" + realm) == username {
+                return DigestHA1(h, user, realm, password), nil
+            }
+        }
+        return "", errors.New("unknown user")
+    }
+}
+
+func TestDigestAuthRFC(t *testing.T) {
+    for _, test := range httpDigestTests {
+        a := &DigestAuth{Realm: test.realm, Hash: test.h, UserHash: test.userhash, Lookup: digestLookup(test.h, test.realm, test.userhash, map[string]string{test.username: test.password})}
+        nonce := parseAuthHeader([]string{test.authorization})[0].params["nonce"]
+        a.nonces = map[string]*digestNonce{nonce: {expires: time.Now().Add(time.Minute), used: map[uint32]bool{}}}
+        handler := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+        // The request is accepted once, as the nonce count is then used
+        for i, expected := range []int{http.StatusOK, http.StatusUnauthorized} {
+            r := httptest.NewRequest("GET", test.uri, nil)
+            r.Header.Set("Authorization", test.authorization)
+            w := httptest.NewRecorder()
+            handler.ServeHTTP(w, r)
+            if w.Code != expected || i == 0 && !strings.Contains(w.Header().Get("Authentication-Info"), "rspauth=") {
+                t.Errorf("\nTest: %s %d\nResult:   %d %v\nExpected: %d\n", test.h, i, w.Code, w.Header(), expected)
+            }
+        }
+    }
+    // Section 3.9.2 sends the username in this form when not hashing it
+    if ext, needed := digestExtValue("Jäsøn Doe"); !needed || ext != "UTF-8''J%C3%A4s%C3%B8n%20Doe" {
+        t.Errorf("\nResult:   %s\nExpected: UTF-8''J%%C3%%A4s%%C3%%B8n%%20Doe\n", ext)
+    }
+}
+
+func newDigestServer(a *DigestAuth, requests *int32) *httptest.Server {
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(requests, 1)
+        a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            body, _ := io.ReadAll(r.Body)
+            io.WriteString(w, DigestUsername(r) + ":" + string(body))
+        })).ServeHTTP(w, r)
+    }))
+}
+
+func TestDigestTransport(t *testing.T) {
+    users := map[string]string{"Mufasa": "Circle of Life", "Jäsøn Doe": "Secret, or not?"}
+    for _, h := range []Hash{HashSHA256, HashSHA512_256} {
+        for _, options := range [][2]bool{{false, false}, {true, false}, {false, true}, {true, true}} {
+            a := &DigestAuth{Realm: "test@example.org", Hash: h, Session: options[0], UserHash: options[1], Lookup: digestLookup(h, "test@example.org", options[1], users)}
+            var requests int32
+            server := newDigestServer(a, &requests)
+            for username, password := range users {
+                client := &http.Client{Transport: &DigestTransport{Username: username, Password: password}}
+                // The first request is challenged and the second authorized
+                // up front, and a body is sent again after a challenge
+                expected := username + ":"
+                if options[1] {
+                    expected = digestHash(h, username + ":test@example.org") + ":"
+                }
+                requests = 0
+                for i, body := range []string{"first", "second"} {
+                    resp, err := client.Post(server.URL + "/path?query=1", "text/plain", strings.NewReader(body))
+                    if err != nil {
+                        t.Fatal(err)
+                    }
+                    output, _ := io.ReadAll(resp.Body)
+                    resp.Body.Close()
+                    if resp.StatusCode != http.StatusOK || string(output) != expected + body || int(requests) != 2 + i {
+                        t.Errorf("\nTest: %s %v %q %d\nResult:   %d %q %d requests\nExpected: %q\n", h, options, username, i, resp.StatusCode, output, requests, expected + body)
+                    }
+                }
+            }
+            // Input: a wrong password
+            client := &http.Client{Transport: &DigestTransport{Username: "Mufasa", Password: "circle of life"}}
+            resp, err := client.Get(server.URL)
+            if err != nil || resp.StatusCode != http.StatusUnauthorized {
+                t.Errorf("\nTest: %s %v wrong password\nResult:   %v %v\n", h, options, resp, err)
+            }
+            server.Close()
+        }
+    }
+}
+
+func TestDigestStale(t *testing.T) {
+    a := &DigestAuth{Realm: "test@example.org", Hash: HashSHA256, Lookup: digestLookup(HashSHA256, "test@example.org", false, map[string]string{"Mufasa": "Circle of Life"})}
+    var requests int32
+    server := newDigestServer(a, &requests)
+    defer server.Close()
+    var authorization string
+    transport := &DigestTransport{Username: "Mufasa", Password: "Circle of Life"}
+    client := &http.Client{Transport: transport}
+    resp, err := client.Get(server.URL)
+    if err != nil || resp.StatusCode != http.StatusOK {
+        t.Fatalf("\nResult:   %v %v\n", resp, err)
+    }
+    resp.Body.Close()
+    // Input: a replayed request
+    for _, s := range transport.sessions {
+        authorization, _, _ = transport.authorization(s, "GET", "/")
+    }
+    for i, expected := range []int{http.StatusOK, http.StatusUnauthorized} {
+        r, _ := http.NewRequest("GET", server.URL, nil)
+        r.Header.Set("Authorization", authorization)
+        resp, err := http.DefaultClient.Do(r)
+        if err != nil || resp.StatusCode != expected || i == 1 && strings.Contains(resp.Header.Get("WWW-Authenticate"), "stale") {
+            t.Errorf("\nTest: replay %d\nResult:   %v %v\nExpected: %d\n", i, resp, err, expected)
+        }
+    }
+    // Input: an expired nonce, for which the client is sent a stale
+    // challenge and retries
+    a.mu.Lock()
+    for _, n := range a.nonces {
+        n.expires = time.Now().Add(-time.Second)
+    }
+    a.mu.Unlock()
+    requests = 0
+    resp, err = client.Get(server.URL)
+    if err != nil || resp.StatusCode != http.StatusOK || requests != 2 {
+        t.Errorf("\nTest: stale nonce\nResult:   %v %v %d requests\n", resp, err, requests)
+    }
+}
+
+func TestDigestNonces(t *testing.T) {
+    a := &DigestAuth{Realm: "test@example.org", Hash: HashSHA256, Lookup: digestLookup(HashSHA256, "test@example.org", false, map[string]string{"Mufasa": "Circle of Life"})}
+    var requests int32
+    server := newDigestServer(a, &requests)
+    defer server.Close()
+    // Challenges store nothing, and an answered nonce is stored once
+    for i := 0; i < 100; i++ {
+        resp, err := http.Get(server.URL)
+        if err != nil || resp.StatusCode != http.StatusUnauthorized {
+            t.Fatalf("\nResult:   %v %v\n", resp, err)
+        }
+    }
+    transport := &DigestTransport{Username: "Mufasa", Password: "Circle of Life"}
+    client := &http.Client{Transport: transport}
+    for i := 0; i < 2; i++ {
+        resp, err := client.Get(server.URL)
+        if err != nil || resp.StatusCode != http.StatusOK {
+            t.Fatalf("\nResult:   %v %v\n", resp, err)
+        }
+        resp.Body.Close()
+    }
+    if len(a.nonces) != 1 {
+        t.Errorf("\nResult:   %d nonces stored\nExpected: 1\n", len(a.nonces))
+    }
+    // Input: a nonce not made by the server, answered with the password
+    for _, s := range transport.sessions {
+        forged := *s
+        forged.nonce = strings.Repeat("0", 64)
+        authorization, _, _ := transport.authorization(&forged, "GET", "/")
+        r, _ := http.NewRequest("GET", server.URL, nil)
+        r.Header.Set("Authorization", authorization)
+        resp, err := http.DefaultClient.Do(r)
+        if err != nil || resp.StatusCode != http.StatusUnauthorized || len(a.nonces) != 1 {
+            t.Errorf("\nTest: forged nonce\nResult:   %v %v %d nonces\n", resp, err, len(a.nonces))
+        }
+    }
+}
+
+func TestDigestRspauth(t *testing.T) {
+    a := &DigestAuth{Realm: "test@example.org", Hash: HashSHA256, Lookup: digestLookup(HashSHA256, "test@example.org", false, map[string]string{"Mufasa": "Circle of Life"})}
+    wrong := false
+    server := httptest.NewServer(a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if wrong {
+            w.Header().Set("Authentication-Info", `qop=auth, rspauth="0000"`)
+        }
+    })))
+    defer server.Close()
+    client := &http.Client{Transport: &DigestTransport{Username: "Mufasa", Password: "Circle of Life"}}
+    // The server's rspauth is checked after a challenge and up front
+    for _, wrong = range []bool{false, false, true} {
+        resp, err := client.Get(server.URL)
+        if wrong && !errors.Is(err, ErrDigestRspauth) || !wrong && (err != nil || resp.StatusCode != http.StatusOK) {
+            t.Errorf("\nTest: wrong rspauth %v\nResult:   %v %v\n", wrong, resp, err)
+        }
+        if err == nil {
+            resp.Body.Close()
+        }
+    }
+}
+
+func TestDigestChallenges(t *testing.T) {
+    tests := []struct {
+        header []string
+        algorithm string
+    }{
+        // The strongest supported algorithm is chosen, whatever the order
+        {[]string{`Basic realm="x", Digest realm="x", qop="auth", algorithm=MD5, nonce="a"`, `Digest realm="x", qop="auth-int, auth", algorithm=SHA-256-sess, nonce="b", Digest realm="x", qop="auth", algorithm=SHA-512-256, nonce="c"`}, "SHA-512-256"},
+        {[]string{`Digest realm="x", qop="auth", algorithm=sha-256, nonce="d", stale=true`}, "sha-256"},
+        // Input: MD5 by default, no qop, only auth-int, and no nonce
+        {[]string{`Digest realm="x", qop="auth", nonce="e"`}, ""},
+        {[]string{`Digest realm="x", algorithm=SHA-256, nonce="f"`}, ""},
+        {[]string{`Digest realm="x", qop="auth-int", algorithm=SHA-256, nonce="g"`}, ""},
+        {[]string{`Digest realm="x", qop="auth", algorithm=SHA-256`}, ""},
+    }
+    for _, test := range tests {
+        s := newDigestSession(test.header)
+        if s == nil && test.algorithm != "" || s != nil && s.algorithm != test.algorithm {
+            t.Errorf("\nTest: %q\nResult:   %+v\nExpected: %s\n", test.header, s, test.algorithm)
+        }
+    }
+    // Quoted strings with escapes, and parameters without spaces
+    c := parseAuthHeader([]string{`Digest realm="a \"b\", c\\",nonce=xyz,opaque=""`})
+    if len(c) != 1 || c[0].params["realm"] != `a "b", c\` || c[0].params["nonce"] != "xyz" || c[0].params["opaque"] != "" || digestQuote(c[0].params["realm"]) != `"a \"b\", c\\"` {
+        t.Errorf("\nResult:   %+v\n", c)
+    }
+}
@@ -0,0 +1,315 @@
+package sha
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "math"
+    "strings"
+    "time"
+)
+
+/* JSON Web Signatures (RFC 7515) in the compact serialization with the
+ * HMAC algorithms HS256, HS384 and HS512 of RFC 7518, and JSON Web Tokens
+ * (RFC 7519) signed with them. Each key is bound to one algorithm, which
+ * a token's header must name: "none" and every other algorithm are
+ * refused, so a token cannot choose how it is checked */
+
+// The clock skew a JWTValidator allows by default
+const JWTMaxSkew = time.Minute
+
+// Returned for a token which cannot be parsed
+var ErrJWSFormat = errors.New("sha: malformed JWS")
+
+// Returned for a token whose algorithm is not its key's, or whose key is
+// unknown
+var ErrJWSAlgorithm = errors.New("sha: JWS algorithm or key not accepted")
+
+// Returned for a token whose signature is wrong
+var ErrJWSSignature = errors.New("sha: JWS signature does not match")
+
+// Returned for a token at or past its expiry time
+var ErrJWTExpired = errors.New("sha: JWT has expired")
+
+// Returned for a token before its not-before time
+var ErrJWTNotYetValid = errors.New("sha: JWT is not valid yet")
+
+// Returned for a token with the wrong issuer or audience, or issued in the
+// future
+var ErrJWTClaims = errors.New("sha: JWT claims not accepted")
+
+// The HMAC algorithms of RFC 7518 section 3.2
+var jwsAlgorithms = map[string]Hash{
+    "HS256": HashSHA256,
+    "HS384": HashSHA384,
+    "HS512": HashSHA512,
+}
+
+// The base64url encoding of JWS, without padding
+var jwsBase64 = base64.RawURLEncoding.Strict()
+
+// A JWS protected header
+type JWSHeader struct {
+    Alg string        `json:"alg"`
+    Kid string        `json:"kid,omitempty"`
+    Typ string        `json:"typ,omitempty"`
+    Crit []string     `json:"crit,omitempty"`  // Extensions which must be understood
+}
+
+// A key for one of the HMAC algorithms, with an optional key ID
+type JWSKey struct {
+    ID string
+    Algorithm string  // "HS256", "HS384" or "HS512"
+    Secret []byte     // At least as long as the hash
+}
+
+// A set of keys, looked up by key ID
+type JWSKeySet []JWSKey
+
+// The audience of a JWT: one string or an array of them
+type JWTAudience []string
+
+// The registered claims of RFC 7519 section 4.1. Times are seconds since
+// the Unix epoch, with any fraction dropped, and zero if absent. Decoding
+// records which times were present, so that a time of zero is still checked
+type JWTClaims struct {
+    Issuer string         `json:"iss,omitempty"`
+    Subject string        `json:"sub,omitempty"`
+    Audience JWTAudience  `json:"aud,omitempty"`
+    ExpiresAt int64       `json:"exp,omitempty"`
+    NotBefore int64       `json:"nbf,omitempty"`
+    IssuedAt int64        `json:"iat,omitempty"`
+    ID string             `json:"jti,omitempty"`
+    hasExpiresAt bool     // Whether the decoded claims had "exp"
+    hasNotBefore bool     // Whether the decoded claims had "nbf"
+    hasIssuedAt bool      // Whether the decoded claims had "iat"
+}
+
+// Checks JWTs and their claims
+type JWTValidator struct {
+    // Returns the key of a key ID, which is empty if the token has none.
+    // A JWSKeySet's Lookup may be used
+    Lookup func(kid string) (JWSKey, error)
+    Issuer string           // The required issuer, if not empty
+    Audience string         // This recipient, which tokens with an audience must name
+    RequireExpiry bool      // Refuse tokens without an expiry time
+    MaxSkew time.Duration   // JWTMaxSkew if zero
+}
+
+func (s JWSKeySet) Lookup(kid string) (JWSKey, error) {
+    /* Returns the key with the given ID */
+    for _, key := range s {
+        if key.ID == kid {
+            return key, nil
+        }
+    }
+    return JWSKey{}, ErrJWSAlgorithm
+}
+
+func (a JWTAudience) MarshalJSON() ([]byte, error) {
+    /* Encodes a single audience as a string, as is usual */
+    if len(a) == 1 {
+        return json.Marshal(a[0])
+    }
+    return json.Marshal([]string(a))
+}
+
+func (a *JWTAudience) UnmarshalJSON(data []byte) error {
+    /* Decodes a string or an array of strings */
+    if string(data) == "null" {
+        *a = nil
+        return nil
+    }
+    var single string
+    if err := json.Unmarshal(data, &single); err == nil {
+        *a = JWTAudience{single}
+        return nil
+    }
+    return json.Unmarshal(data, (*[]string)(a))
+}
+
+func (c *JWTClaims) UnmarshalJSON(data []byte) error {
+    /* Decodes the claims. NumericDates may have a fraction of a second,
+     * which is truncated */
+    type claims JWTClaims
+    times := struct {
+        *claims
+        ExpiresAt *float64  `json:"exp"`
+        NotBefore *float64  `json:"nbf"`
+        IssuedAt *float64   `json:"iat"`
+    }{claims: (*claims)(c)}
+    if err := json.Unmarshal(data, &times); err != nil {
+        return err
+    }
+    for _, t := range []struct {
+        value *float64
+        field *int64
+        present *bool
+    }{
+        {times.ExpiresAt, &c.ExpiresAt, &c.hasExpiresAt},
+        {times.NotBefore, &c.NotBefore, &c.hasNotBefore},
+        {times.IssuedAt, &c.IssuedAt, &c.hasIssuedAt},
+    } {
+        *t.field, *t.present = 0, t.value != nil
+        if t.value == nil {
+            continue
+        }
+        if math.Abs(*t.value) >= 1 << 62 {
+            return errors.New("sha: JWT time out of range")
+        }
+        *t.field = int64(math.Trunc(*t.value))
+    }
+    return nil
+}
+
+func (key JWSKey) hash() (Hash, error) {
+    /* Returns the hash of the key's algorithm, checking its length */
+    h, ok := jwsAlgorithms[key.Algorithm]
+    if !ok {
+        return 0, ErrJWSAlgorithm
+    }
+    if len(key.Secret) < h.Size() {
+        return 0, errors.New("sha: JWS keys must be at least as long as the hash")
+    }
+    return h, nil
+}
+
+/* Signing */
+
+func SignJWS(key JWSKey, payload []byte) (string, error) {
+    /* Returns the compact JWS of the payload, with a header of the key's
+     * algorithm and ID */
+    return signJWS(key, JWSHeader{Alg: key.Algorithm, Kid: key.ID}, payload)
+}
+
+func SignJWT(key JWSKey, claims any) (string, error) {
+    /* Returns a JWT of the claims, which may be a JWTClaims or any value
+     * encoding to a JSON object */
+    payload, err := json.Marshal(claims)
+    if err != nil {
+        return "", err
+    }
+    if !bytes.HasPrefix(payload, []byte("{")) {
+        return "", errors.New("sha: JWT claims must be a JSON object")
+    }
+    return signJWS(key, JWSHeader{Alg: key.Algorithm, Kid: key.ID, Typ: "JWT"}, payload)
+}
+
+func signJWS(key JWSKey, header JWSHeader, payload []byte) (string, error) {
+    /* Signs the encoded header and payload */
+    h, err := key.hash()
+    if err != nil {
+        return "", err
+    }
+    encoded, err := json.Marshal(header)
+    if err != nil {
+        return "", err
+    }
+    input := jwsBase64.EncodeToString(encoded) + "." + jwsBase64.EncodeToString(payload)
+    return input + "." + jwsBase64.EncodeToString(HMACSum(h, key.Secret, []byte(input))), nil
+}
+
+/* Verifying */
+
+func ParseJWS(token string) (*JWSHeader, []byte, error) {
+    /* Decodes the header and payload of a compact JWS without checking its
+     * signature, e.g. to read the key ID */
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, nil, ErrJWSFormat
+    }
+    encoded, err := jwsBase64.DecodeString(parts[0])
+    if err != nil {
+        return nil, nil, ErrJWSFormat
+    }
+    header := new(JWSHeader)
+    if json.Unmarshal(encoded, header) != nil || header.Alg == "" {
+        return nil, nil, ErrJWSFormat
+    }
+    payload, err := jwsBase64.DecodeString(parts[1])
+    if err != nil {
+        return nil, nil, ErrJWSFormat
+    }
+    return header, payload, nil
+}
+
+func VerifyJWS(token string, lookup func(kid string) (JWSKey, error)) (*JWSHeader, []byte, error) {
+    /* Checks a compact JWS with the key that lookup returns for its key ID,
+     * returning its header and payload. The header's algorithm must be the
+     * key's, and critical extensions are refused as none are understood */
+    header, payload, err := ParseJWS(token)
+    if err != nil {
+        return nil, nil, err
+    }
+    if header.Crit != nil {
+        return nil, nil, ErrJWSFormat
+    }
+    key, err := lookup(header.Kid)
+    if err != nil || key.Algorithm != header.Alg {
+        return nil, nil, ErrJWSAlgorithm
+    }
+    h, err := key.hash()
+    if err != nil {
+        return nil, nil, err
+    }
+    dot := strings.LastIndexByte(token, '.')
+    signature, err := jwsBase64.DecodeString(token[dot+1:])
+    if err != nil {
+        return nil, nil, ErrJWSFormat
+    }
+    if !VerifyHMAC(h, key.Secret, []byte(token[:dot]), signature) {
+        return nil, nil, ErrJWSSignature
+    }
+    return header, payload, nil
+}
+
+func (v *JWTValidator) Verify(token string, now time.Time, claims any) (*JWTClaims, error) {
+    /* Checks a JWT's signature and registered claims at time now, within
+     * the allowed skew, returning the registered claims. If claims is not
+     * nil, the payload is also decoded into it */
+    _, payload, err := VerifyJWS(token, v.Lookup)
+    if err != nil {
+        return nil, err
+    }
+    registered := new(JWTClaims)
+    if json.Unmarshal(payload, registered) != nil {
+        return nil, ErrJWSFormat
+    }
+    if claims != nil {
+        if err := json.Unmarshal(payload, claims); err != nil {
+            return nil, err
+        }
+    }
+    skew := v.MaxSkew
+    if skew == 0 {
+        skew = JWTMaxSkew
+    }
+    late := now.Add(-skew).Unix()
+    early := now.Add(skew).Unix()
+    switch {
+    case !registered.hasExpiresAt && v.RequireExpiry:
+        return nil, ErrJWTClaims
+    case registered.hasExpiresAt && late >= registered.ExpiresAt:
+        return nil, ErrJWTExpired
+    case registered.hasNotBefore && early < registered.NotBefore:
+        return nil, ErrJWTNotYetValid
+    case registered.hasIssuedAt && early < registered.IssuedAt:
+        return nil, ErrJWTClaims
+    case v.Issuer != "" && registered.Issuer != v.Issuer:
+        return nil, ErrJWTClaims
+    }
+    // A token for other audiences is refused, as section 4.1.3 requires
+    if len(registered.Audience) > 0 {
+        found := false
+        for _, audience := range registered.Audience {
+            found = found || v.Audience != "" && audience == v.Audience
+        }
+        if !found {
+            return nil, ErrJWTClaims
+        }
+    } else if v.Audience != "" {
+        return nil, ErrJWTClaims
+    }
+    return registered, nil
+}
@@ -0,0 +1,166 @@
+package sha
+
+import (
+    "runtime"
+    "sync"
+)
+
+/* Functions for KangarooTwelve (KT128 & KT256, RFC 9861) */
+
+// Size in bytes of each chunk of the KangarooTwelve tree
+const K12ChunkSize = 8192
+
+// A KangarooTwelve hash, which can absorb input with Write and then
+// squeeze an output of any length with Read. Full chunks are hashed in
+// parallel as soon as enough of them have been written
+type KangarooTwelve struct {
+    C []byte              // Customization string
+    newSponge func(D byte) *TurboSHAKE
+    cvSize int            // Size in bytes of each chaining value
+    final *TurboSHAKE     // Final node, once the input spans several chunks
+    buf []byte            // Input not yet added to the tree
+    chunks int            // Number of chunks added to the final node
+    squeezing bool        // Whether the final node has been padded
+}
+
+func NewKT128(C []byte) *KangarooTwelve {
+    /* Returns a KT128 hash using the customization string C */
+    k := &KangarooTwelve{newSponge: NewTurboSHAKE128, cvSize: 32}
+    k.C = append(k.C, C...)
+    return k
+}
+
+func NewKT256(C []byte) *KangarooTwelve {
+    /* Returns a KT256 hash using the customization string C */
+    k := &KangarooTwelve{newSponge: NewTurboSHAKE256, cvSize: 64}
+    k.C = append(k.C, C...)
+    return k
+}
+
+func LengthEncode(x int) []byte {
+    /* Encodes x as a big-endian integer with no leading zeros, followed
+     * by a byte giving the length of that integer */
+    var output []byte
+    for ; x > 0; x >>= 8 {
+        output = append([]byte{byte(x)}, output...)
+    }
+    return append(output, byte(len(output)))
+}
+
+func (k *KangarooTwelve) chainingValues(S []byte) []byte {
+    /* Takes a series of chunks S (all full apart from possibly the last)
+     * and returns their concatenated chaining values, hashing the chunks
+     * in parallel */
+    n := (len(S) + K12ChunkSize - 1) / K12ChunkSize
+    CV := make([]byte, n*k.cvSize)
+    workers := runtime.GOMAXPROCS(0)
+    if workers > n {
+        workers = n
+    }
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            // Each worker takes every n'th chunk
+            for i := w; i < n; i += workers {
+                end := (i + 1) * K12ChunkSize
+                if end > len(S) {
+                    end = len(S)
+                }
+                s := k.newSponge(0x0B)
+                s.Write(S[i*K12ChunkSize:end])
+                s.Read(CV[i*k.cvSize:(i+1)*k.cvSize])
+            }
+        }(w)
+    }
+    wg.Wait()
+    return CV
+}
+
+func (k *KangarooTwelve) addChunks(S []byte) {
+    /* Adds a series of chunks to the tree, starting the final node with
+     * S_0 if it has not already been started */
+    if k.final == nil {
+        k.final = k.newSponge(0x06)
+        k.final.Write(S[:K12ChunkSize])
+        k.final.Write([]byte{0x03, 0, 0, 0, 0, 0, 0, 0})
+        S = S[K12ChunkSize:]
+        k.chunks = 1
+    }
+    if len(S) > 0 {
+        k.final.Write(k.chainingValues(S))
+        k.chunks += (len(S) + K12ChunkSize - 1) / K12ChunkSize
+    }
+}
+
+func (k *KangarooTwelve) Write(p []byte) (int, error) {
+    /* Absorbs p into the hash. Writing after reading is not allowed */
+    if k.squeezing {
+        panic("sha: KangarooTwelve written to after being read")
+    }
+    k.buf = append(k.buf, p...)
+    // Wait until there is a chunk for each thread, plus S_0 if needed
+    batch := runtime.GOMAXPROCS(0) * K12ChunkSize
+    if k.final == nil {
+        batch += K12ChunkSize
+    }
+    if len(k.buf) >= batch {
+        whole := len(k.buf) - len(k.buf)%K12ChunkSize
+        k.addChunks(k.buf[:whole])
+        k.buf = append(k.buf[:0], k.buf[whole:]...)
+    }
+    return len(p), nil
+}
+
+func (k *KangarooTwelve) Read(out []byte) (int, error) {
+    /* Squeezes len(out) bytes from the hash. The first call finishes
+     * building the tree, so the hash can no longer be written to */
+    if !k.squeezing {
+        // S = M || C || length_encode(|C|)
+        k.buf = append(k.buf, k.C...)
+        k.buf = append(k.buf, LengthEncode(len(k.C))...)
+        if k.final == nil && len(k.buf) <= K12ChunkSize {
+            // A single chunk is hashed directly
+            k.final = k.newSponge(0x07)
+            k.final.Write(k.buf)
+        } else {
+            // Otherwise finish the final node
+            k.addChunks(k.buf)
+            k.final.Write(LengthEncode(k.chunks - 1))
+            k.final.Write([]byte{0xFF, 0xFF})
+        }
+        k.buf = nil
+        k.squeezing = true
+    }
+    return k.final.Read(out)
+}
+
+func (k *KangarooTwelve) Reset() {
+    /* Resets the hash to its initial state, keeping the customization
+     * string */
+    k.final = nil
+    k.buf = nil
+    k.chunks = 0
+    k.squeezing = false
+}
+
+func KT128(M []byte, C []byte, L int) []byte {
+    /* Takes a message M, a customization string C and an output length
+     * L in bytes, and returns the KT128 output */
+    k := NewKT128(C)
+    k.Write(M)
+    output := make([]byte, L)
+    k.Read(output)
+    return output
+}
+
+func KT256(M []byte, C []byte, L int) []byte {
+    /* Takes a message M, a customization string C and an output length
+     * L in bytes, and returns the KT256 output */
+    k := NewKT256(C)
+    k.Write(M)
+    output := make([]byte, L)
+    k.Read(output)
+    return output
+}
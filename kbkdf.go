@@ -0,0 +1,132 @@
+package sha
+
+import (
+    "errors"
+)
+
+/* Key derivation using pseudorandom functions (NIST SP 800-108), with
+ * HMAC as the pseudorandom function */
+
+// The three KBKDF modes of SP 800-108
+type KBKDFMode int
+
+const (
+    KBKDFCounter KBKDFMode = iota
+    KBKDFFeedback
+    KBKDFDoublePipeline
+)
+
+// Where the counter goes in the input to each PRF call. In counter mode
+// there is no iteration variable, so the ...Iter locations are the same
+// as CounterBeforeFixed
+type CounterLocation int
+
+const (
+    CounterBeforeFixed CounterLocation = iota  // [i] || fixed
+    CounterAfterFixed                          // iter || fixed || [i]
+    CounterMiddleFixed                         // fixed[:n] || [i] || fixed[n:]
+    CounterBeforeIter                          // [i] || iter || fixed
+    CounterAfterIter                           // iter || [i] || fixed
+)
+
+// Settings for a KBKDF
+type KBKDFParams struct {
+    Mode KBKDFMode
+    Hash Hash                 // Hash function for the HMAC PRF
+    CounterLen int            // Counter width, r, in bits (8, 16, 24 or 32),
+                              // or 0 for no counter in the feedback and
+                              // double-pipeline modes
+    Location CounterLocation  // Position of the counter
+    MiddleOffset int          // Bytes of fixed data before the counter, for
+                              // CounterMiddleFixed
+    IV []byte                 // K(0) for feedback mode, which may be empty
+}
+
+func KBKDFFixedInput(label []byte, context []byte, separator bool, L int, LLen int) []byte {
+    /* Returns the fixed input data Label || 0x00 || Context || [L]_2,
+     * where L is the length of the derived key in bits encoded as a
+     * big-endian integer of LLen bytes. The 0x00 separator is left out if
+     * separator is false, and [L]_2 is left out if LLen is 0 */
+    var fixed []byte
+    fixed = append(fixed, label...)
+    if separator {
+        fixed = append(fixed, 0x00)
+    }
+    fixed = append(fixed, context...)
+    for i := LLen - 1; i >= 0; i-- {
+        fixed = append(fixed, byte(uint64(L) >> (8 * uint(i))))
+    }
+    return fixed
+}
+
+func KBKDF(params KBKDFParams, KI []byte, fixed []byte, L int) ([]byte, error) {
+    /* Takes the KBKDF settings, the key-derivation key KI, the fixed
+     * input data and an output length L in bytes, and returns the derived
+     * key K_O */
+    r := params.CounterLen
+    if r % 8 != 0 || r < 0 || r > 32 || (r == 0 && params.Mode == KBKDFCounter) {
+        return nil, errors.New("sha: KBKDF counter must be 8, 16, 24 or 32 bits")
+    }
+    if L <= 0 {
+        return nil, errors.New("sha: KBKDF output length must be positive")
+    }
+    if params.Location == CounterMiddleFixed && (params.MiddleOffset < 0 || params.MiddleOffset > len(fixed)) {
+        return nil, errors.New("sha: KBKDF counter offset is outside the fixed input data")
+    }
+    h := params.Hash.Size()
+    n := (L + h - 1) / h  // Number of PRF outputs needed
+    if (r > 0 && uint64(n) > (uint64(1) << uint(r)) - 1) || uint64(n) > 0xffffffff {
+        return nil, errors.New("sha: KBKDF output is too long for the counter")
+    }
+    prf := NewHMAC(params.Hash, KI)
+    var K, A []byte  // K(i) and A(i)
+    switch params.Mode {
+    case KBKDFFeedback:
+        K = append(K, params.IV...)
+    case KBKDFDoublePipeline:
+        A = append(A, fixed...)
+    }
+    KO := make([]byte, 0, n*h)
+    counter := make([]byte, r/8)
+    for i := 1; i <= n; i++ {
+        // [i]_2 as a big-endian integer of r bits
+        for j := range counter {
+            counter[j] = byte(i >> (8 * uint(len(counter)-1-j)))
+        }
+        // The iteration variable is K(i-1) in feedback mode, or
+        // A(i) = PRF(KI, A(i-1)) in double-pipeline mode
+        var iter []byte
+        switch params.Mode {
+        case KBKDFFeedback:
+            iter = K
+        case KBKDFDoublePipeline:
+            prf.Reset()
+            prf.Write(A)
+            A = prf.Sum(A[:0])
+            iter = A
+        }
+        prf.Reset()
+        switch params.Location {
+        case CounterBeforeFixed, CounterBeforeIter:
+            prf.Write(counter)
+            prf.Write(iter)
+            prf.Write(fixed)
+        case CounterAfterIter:
+            prf.Write(iter)
+            prf.Write(counter)
+            prf.Write(fixed)
+        case CounterAfterFixed:
+            prf.Write(iter)
+            prf.Write(fixed)
+            prf.Write(counter)
+        case CounterMiddleFixed:
+            prf.Write(iter)
+            prf.Write(fixed[:params.MiddleOffset])
+            prf.Write(counter)
+            prf.Write(fixed[params.MiddleOffset:])
+        }
+        K = prf.Sum(K[:0])
+        KO = append(KO, K...)
+    }
+    return KO[:L], nil
+}
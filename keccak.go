@@ -0,0 +1,266 @@
+package sha
+
+import (
+    "encoding/binary"
+)
+
+/* Functions for the Keccak-p permutations, and algorithms based on the
+ * 12-round Keccak-p[1600] (TurboSHAKE128 & TurboSHAKE256) */
+
+// Keccak round constants
+var RC = [24]uint64{0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000, 0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009, 0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a, 0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003, 0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a, 0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008}
+
+// Rotation offsets for the rho step, indexed by x + 5y
+var RhoOffsets = [25]uint64{0, 1, 62, 28, 27, 36, 44, 6, 55, 20, 3, 10, 43, 25, 39, 41, 45, 15, 21, 8, 18, 2, 61, 56, 14}
+
+func KeccakP1600(A *[25]uint64, nr int) {
+    /* Applies the Keccak-p[1600, nr] permutation to the state A, whose
+     * lanes are indexed by x + 5y. Keccak-p[1600, nr] consists of the last
+     * nr rounds of Keccak-f[1600], so nr = 24 gives the full permutation */
+    var C, D [5]uint64  // Column parities
+    var B [25]uint64    // Temporary state
+    for ir := 24 - nr; ir < 24; ir++ {
+        // Theta: xor each lane with the parities of two nearby columns
+        for x := 0; x < 5; x++ {
+            C[x] = A[x] ^ A[x+5] ^ A[x+10] ^ A[x+15] ^ A[x+20]
+        }
+        for x := 0; x < 5; x++ {
+            D[x] = C[(x+4)%5] ^ ROTL_64(C[(x+1)%5], 1)
+        }
+        for i := 0; i < 25; i++ {
+            A[i] ^= D[i%5]
+        }
+        // Rho & pi: rotate each lane, then move lane (x, y) to (y, 2x + 3y)
+        for x := 0; x < 5; x++ {
+            for y := 0; y < 5; y++ {
+                B[y+5*((2*x+3*y)%5)] = ROTL_64(A[x+5*y], RhoOffsets[x+5*y])
+            }
+        }
+        // Chi: combine each lane with the next two lanes in its row
+        for y := 0; y < 25; y += 5 {
+            for x := 0; x < 5; x++ {
+                A[x+y] = B[x+y] ^ (^B[(x+1)%5+y] & B[(x+2)%5+y])
+            }
+        }
+        // Iota: add the round constant to lane (0, 0)
+        A[0] ^= RC[ir]
+    }
+}
+
+func KeccakRC(ir int, w uint) uint64 {
+    /* Returns the round constant for round ir of a Keccak-p permutation
+     * with lanes of w bits. Rounds outside 0-23 occur when a permutation
+     * has more rounds than Keccak-f, and their constants are calculated
+     * with the linear feedback shift register from FIPS 202 */
+    var mask uint64 = ^uint64(0) >> (64 - w)
+    if ir >= 0 && ir < 24 {
+        return RC[ir] & mask
+    }
+    var output uint64
+    for j := uint(0); 1<<j <= w; j++ {
+        // Find rc(j + 7ir) by stepping the LFSR (j + 7ir) mod 255 times
+        t := (int(j) + 7*ir) % 255
+        if t < 0 {
+            t += 255
+        }
+        var R uint64 = 1
+        for i := 0; i < t; i++ {
+            R <<= 1
+            if R & 0x100 != 0 {
+                R ^= 0x171
+            }
+        }
+        output |= (R & 1) << (1<<j - 1)
+    }
+    return output
+}
+
+func ROTL_W(x uint64, n uint64, w uint) uint64 {
+    /* Circular shift x left by n bits, within a lane of w bits */
+    n %= uint64(w)
+    if n == 0 {
+        return x
+    }
+    var mask uint64 = ^uint64(0) >> (64 - w)
+    return ((x << n) | (x >> (uint64(w) - n))) & mask
+}
+
+func KeccakP(A *[25]uint64, w uint, nr int) {
+    /* Applies the Keccak-p[25w, nr] permutation to the state A, whose
+     * lanes of w bits (1, 2, 4, 8, 16, 32 or 64) are indexed by x + 5y.
+     * Keccak-f[25w] has 12 + 2l rounds, where w = 2^l, and Keccak-p[25w, nr]
+     * consists of the last nr of those rounds */
+    // Keccak-p[1600] has its own faster implementation
+    if w == 64 && nr <= 24 {
+        KeccakP1600(A, nr)
+        return
+    }
+    var l int
+    for 1<<uint(l) < w {
+        l++
+    }
+    var C, D [5]uint64  // Column parities
+    var B [25]uint64    // Temporary state
+    for ir := 12 + 2*l - nr; ir < 12 + 2*l; ir++ {
+        // Theta
+        for x := 0; x < 5; x++ {
+            C[x] = A[x] ^ A[x+5] ^ A[x+10] ^ A[x+15] ^ A[x+20]
+        }
+        for x := 0; x < 5; x++ {
+            D[x] = C[(x+4)%5] ^ ROTL_W(C[(x+1)%5], 1, w)
+        }
+        for i := 0; i < 25; i++ {
+            A[i] ^= D[i%5]
+        }
+        // Rho & pi
+        for x := 0; x < 5; x++ {
+            for y := 0; y < 5; y++ {
+                B[y+5*((2*x+3*y)%5)] = ROTL_W(A[x+5*y], RhoOffsets[x+5*y], w)
+            }
+        }
+        // Chi, keeping each lane within w bits
+        var mask uint64 = ^uint64(0) >> (64 - w)
+        for y := 0; y < 25; y += 5 {
+            for x := 0; x < 5; x++ {
+                A[x+y] = (B[x+y] ^ (^B[(x+1)%5+y] & B[(x+2)%5+y])) & mask
+            }
+        }
+        // Iota
+        A[0] ^= KeccakRC(ir, w)
+    }
+}
+
+/* TurboSHAKE (RFC 9861) */
+
+// A TurboSHAKE sponge, which can absorb input with Write and then
+// squeeze an output of any length with Read
+type TurboSHAKE struct {
+    A [25]uint64      // State
+    Rate int          // Rate in bytes
+    D byte            // Domain separation byte
+    buf [200]byte     // Partial block of input or output
+    n int             // Number of bytes used in buf
+    squeezing bool    // Whether the sponge has been padded
+}
+
+func NewTurboSHAKE128(D byte) *TurboSHAKE {
+    /* Returns a TurboSHAKE128 sponge (capacity of 256 bits) using the
+     * domain separation byte D, which must be in the range 0x01-0x7F */
+    checkTurboSHAKEDomain(D)
+    return &TurboSHAKE{Rate: 168, D: D}
+}
+
+func NewTurboSHAKE256(D byte) *TurboSHAKE {
+    /* Returns a TurboSHAKE256 sponge (capacity of 512 bits) using the
+     * domain separation byte D, which must be in the range 0x01-0x7F */
+    checkTurboSHAKEDomain(D)
+    return &TurboSHAKE{Rate: 136, D: D}
+}
+
+func checkTurboSHAKEDomain(D byte) {
+    /* Panics unless D is in the range 0x01-0x7F of RFC 9861, as any other
+     * byte would collide with the padding */
+    if D == 0x00 || D >= 0x80 {
+        panic("sha: TurboSHAKE domain separation byte must be in the range 0x01-0x7F")
+    }
+}
+
+func (s *TurboSHAKE) absorbBlock(block []byte) {
+    /* Xors a block of Rate bytes into the state and permutes it */
+    for i := 0; i < s.Rate/8; i++ {
+        s.A[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+    }
+    KeccakP1600(&s.A, 12)
+}
+
+func (s *TurboSHAKE) Write(p []byte) (int, error) {
+    /* Absorbs p into the sponge. Writing after reading is not allowed */
+    if s.squeezing {
+        panic("sha: TurboSHAKE written to after being read")
+    }
+    written := len(p)
+    // Fill any partial block first
+    if s.n > 0 {
+        c := copy(s.buf[s.n:s.Rate], p)
+        s.n += c
+        p = p[c:]
+        if s.n < s.Rate {
+            return written, nil
+        }
+        s.absorbBlock(s.buf[:s.Rate])
+        s.n = 0
+    }
+    // Absorb whole blocks directly from the input
+    for len(p) >= s.Rate {
+        s.absorbBlock(p[:s.Rate])
+        p = p[s.Rate:]
+    }
+    // Keep the remainder for later
+    s.n = copy(s.buf[:], p)
+    return written, nil
+}
+
+func (s *TurboSHAKE) Read(out []byte) (int, error) {
+    /* Squeezes len(out) bytes from the sponge. The first call pads the
+     * input, so the sponge can no longer be written to afterwards */
+    if !s.squeezing {
+        // Add the domain separation byte, then zeros and a final '1' bit
+        for i := s.n; i < s.Rate; i++ {
+            s.buf[i] = 0
+        }
+        s.buf[s.n] ^= s.D
+        s.buf[s.Rate-1] ^= 0x80
+        s.absorbBlock(s.buf[:s.Rate])
+        s.squeezing = true
+        s.squeezeBlock()
+    }
+    read := len(out)
+    for len(out) > 0 {
+        // Permute for another block once the last one is used up
+        if s.n == s.Rate {
+            KeccakP1600(&s.A, 12)
+            s.squeezeBlock()
+        }
+        c := copy(out, s.buf[s.n:s.Rate])
+        s.n += c
+        out = out[c:]
+    }
+    return read, nil
+}
+
+func (s *TurboSHAKE) squeezeBlock() {
+    /* Copies the first Rate bytes of the state into buf for output */
+    for i := 0; i < s.Rate/8; i++ {
+        binary.LittleEndian.PutUint64(s.buf[i*8:], s.A[i])
+    }
+    s.n = 0
+}
+
+func (s *TurboSHAKE) Reset() {
+    /* Resets the sponge to its initial state, keeping the rate and D */
+    s.A = [25]uint64{}
+    s.n = 0
+    s.squeezing = false
+}
+
+func TurboSHAKE128(M []byte, D byte, L int) []byte {
+    /* Takes a message M, a domain separation byte D in the range
+     * 0x01-0x7F and an output length L in bytes, and returns the
+     * TurboSHAKE128 output */
+    s := NewTurboSHAKE128(D)
+    s.Write(M)
+    output := make([]byte, L)
+    s.Read(output)
+    return output
+}
+
+func TurboSHAKE256(M []byte, D byte, L int) []byte {
+    /* Takes a message M, a domain separation byte D in the range
+     * 0x01-0x7F and an output length L in bytes, and returns the
+     * TurboSHAKE256 output */
+    s := NewTurboSHAKE256(D)
+    s.Write(M)
+    output := make([]byte, L)
+    s.Read(output)
+    return output
+}
@@ -0,0 +1,155 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/hex"
+)
+
+func ptn(n int) []byte {
+    /* Returns the RFC 9861 test pattern of n bytes: 00 01 .. F9 FA 00 01.. */
+    output := make([]byte, n)
+    for i := 0; i < n; i++ {
+        output[i] = byte(i % 251)
+    }
+    return output
+}
+
+func TestKeccakP1600(t *testing.T) {
+    // Input: all-zero state, Keccak-f[1600] (24 rounds)
+    var A [25]uint64
+    KeccakP1600(&A, 24)
+    // Expected: first lanes from the Keccak team's KeccakF-1600 intermediate values
+    expected := []uint64{0xF1258F7940E1DDE7, 0x84D5CCF933C0478A, 0xD598261EA65AA9EE, 0xBD1547306F80494D}
+    for i := 0; i < len(expected); i++ {
+        if A[i] != expected[i] {
+            t.Errorf("\nLane:     %d\nResult:   0x%016X\nExpected: 0x%016X\n", i, A[i], expected[i])
+        }
+    }
+}
+
+func TestTurboSHAKE128(t *testing.T) {
+    // Test vectors from RFC 9861 section 5
+    tests := []struct {
+        M []byte
+        D byte
+        L int
+        expected string
+    }{
+        {[]byte{}, 0x1F, 32, "1e415f1c5983aff2169217277d17bb538cd945a397ddec541f1ce41af2c1b74c"},
+        {[]byte{}, 0x1F, 64, "1e415f1c5983aff2169217277d17bb538cd945a397ddec541f1ce41af2c1b74c3e8ccae2a4dae56c84a04c2385c03c15e8193bdf58737363321691c05462c8df"},
+        {ptn(1), 0x1F, 32, "55cedd6f60af7bb29a4042ae832ef3f58db7299f893ebb9247247d856958daa9"},
+        {ptn(17), 0x1F, 32, "9c97d036a3bac819db70ede0ca554ec6e4c2a1a4ffbfd9ec269ca6a111161233"},
+        {ptn(17*17), 0x1F, 32, "96c77c279e0126f7fc07c9b07f5cdae1e0be60bdbe10620040e75d7223a624d2"},
+        {ptn(17*17*17), 0x1F, 32, "d4976eb56bcf118520582b709f73e1d6853e001fdaf80e1b13e0d0599d5fb372"},
+        {ptn(17*17*17*17), 0x1F, 32, "da67c7039e98bf530cf7a37830c6664e14cbab7f540f58403b1b82951318ee5c"},
+        {[]byte{0xFF, 0xFF, 0xFF}, 0x01, 32, "bf323f940494e88ee1c540fe660be8a0c93f43d15ec006998462fa994eed5dab"},
+        {[]byte{0xFF}, 0x06, 32, "8ec9c66465ed0d4a6c35d13506718d687a25cb05c74cca1e42501abd83874a67"},
+        {[]byte{0xFF, 0xFF, 0xFF}, 0x07, 32, "b658576001cad9b1e5f399a9f77723bba05458042d68206f7252682dba3663ed"},
+        {bytes.Repeat([]byte{0xFF}, 7), 0x0B, 32, "8deeaa1aec47ccee569f659c21dfa8e112db3cee37b18178b2acd805b799cc37"},
+        {[]byte{0xFF}, 0x30, 32, "553122e2135e363c3292bed2c6421fa232bab03daa07c7d6636603286506325b"},
+        {[]byte{0xFF, 0xFF, 0xFF}, 0x7F, 32, "16274cc656d44cefd422395d0f9053bda6d28e122aba15c765e5ad0e6eaf26f9"},
+    }
+    for _, test := range tests {
+        result := hex.EncodeToString(TurboSHAKE128(test.M, test.D, test.L))
+        if result != test.expected {
+            t.Errorf("\nTest: TurboSHAKE128(%d bytes, 0x%02X, %d)\nResult:   %s\nExpected: %s\n", len(test.M), test.D, test.L, result, test.expected)
+        }
+    }
+}
+
+func TestTurboSHAKE128Long(t *testing.T) {
+    // Input: empty message, D = 0x1F, 10032 bytes of output
+    // Expected: last 32 bytes from RFC 9861 section 5
+    expected := "a3b9b0385900ce761f22aed548e754da10a5242d62e8c658e3f3a923a7555607"
+    result := hex.EncodeToString(TurboSHAKE128([]byte{}, 0x1F, 10032)[10000:])
+    if result != expected {
+        t.Errorf("\nResult:   %s\nExpected: %s\n", result, expected)
+    }
+}
+
+func TestTurboSHAKE256(t *testing.T) {
+    // Test vectors from RFC 9861 section 5
+    tests := []struct {
+        M []byte
+        D byte
+        expected string
+    }{
+        {[]byte{}, 0x1F, "367a329dafea871c7802ec67f905ae13c57695dc2c6663c61035f59a18f8e7db11edc0e12e91ea60eb6b32df06dd7f002fbafabb6e13ec1cc20d995547600db0"},
+        {ptn(1), 0x1F, "3e1712f928f8eaf1054632b2aa0a246ed8b0c378728f60bc970410155c28820e90cc90d8a3006aa2372c5c5ea176b0682bf22bae7467ac94f74d43d39b0482e2"},
+        {ptn(17), 0x1F, "b3bab0300e6a191fbe6137939835923578794ea54843f5011090fa2f3780a9e5cb22c59d78b40a0fbff9e672c0fbe0970bd2c845091c6044d687054da5d8e9c7"},
+        {ptn(17*17), 0x1F, "66b810db8e90780424c0847372fdc95710882fde31c6df75beb9d4cd9305cfcae35e7b83e8b7e6eb4b78605880116316fe2c078a09b94ad7b8213c0a738b65c0"},
+        {ptn(17*17*17), 0x1F, "c74ebc919a5b3b0dd1228185ba02d29ef442d69d3d4276a93efe0bf9a16a7dc0cd4eabadab8cd7a5edd96695f5d360abe09e2c6511a3ec397da3b76b9e1674fb"},
+        {ptn(17*17*17*17), 0x1F, "02cc3a8897e6f4f6ccb6fd46631b1f5207b66c6de9c7b55b2d1a23134a170afdac234eaba9a77cff88c1f020b73724618c5687b362c430b248cd38647f848a1d"},
+        {ptn(17*17*17*17*17), 0x1F, "add53b06543e584b5823f626996aee50fe45ed15f20243a7165485acb4aa76b4ffda75cedf6d8cdc95c332bd56f4b986b58bb17d1778bfc1b1a97545cdf4ec9f"},
+        {[]byte{0xFF, 0xFF, 0xFF}, 0x01, "d21c6fbbf587fa2282f29aea620175fb0257413af78a0b1b2a87419ce031d933ae7a4d383327a8a17641a34f8a1d1003ad7da6b72dba84bb62fef28f62f12424"},
+        {[]byte{0xFF}, 0x06, "738d7b4e37d18b7f22ad1b5313e357e3dd7d07056a26a303c433fa3533455280f4f5a7d4f700efb437fe6d281405e07be32a0a972e22e63adc1b090daefe004b"},
+        {[]byte{0xFF, 0xFF, 0xFF}, 0x07, "18b3b5b7061c2e67c1753a00e6ad7ed7ba1c906cf93efb7092eaf27fbeebb755ae6e292493c110e48d260028492b8e09b5500612b8f2578985ded5357d00ec67"},
+        {bytes.Repeat([]byte{0xFF}, 7), 0x0B, "bb36764951ec97e9d85f7ee9a67a7718fc005cf42556be79ce12c0bde50e5736d6632b0d0dfb202d1bbb8ffe3dd74cb00834fa756cb03471bab13a1e2c16b3c0"},
+        {[]byte{0xFF}, 0x30, "f3fe12873d34bcbb2e608779d6b70e7f86bec7e90bf113cbd4fdd0c4e2f4625e148dd7ee1a52776cf77f240514d9ccfc3b5ddab8ee255e39ee389072962c111a"},
+        {[]byte{0xFF, 0xFF, 0xFF}, 0x7F, "abe569c1f77ec340f02705e7d37c9ab7e155516e4a6a150021d70b6fac0bb40c069f9a9828a0d575cd99f9bae435ab1acf7ed9110ba97ce0388d074bac768776"},
+    }
+    for _, test := range tests {
+        result := hex.EncodeToString(TurboSHAKE256(test.M, test.D, 64))
+        if result != test.expected {
+            t.Errorf("\nTest: TurboSHAKE256(%d bytes, 0x%02X, 64)\nResult:   %s\nExpected: %s\n", len(test.M), test.D, result, test.expected)
+        }
+    }
+}
+
+func TestTurboSHAKE256Long(t *testing.T) {
+    // Input: empty message, D = 0x1F, 10032 bytes of output
+    // Expected: last 32 bytes from RFC 9861 section 5
+    expected := "abefa11630c661269249742685ec082f207265dccf2f43534e9c61ba0c9d1d75"
+    result := hex.EncodeToString(TurboSHAKE256([]byte{}, 0x1F, 10032)[10000:])
+    if result != expected {
+        t.Errorf("\nResult:   %s\nExpected: %s\n", result, expected)
+    }
+}
+
+func TestTurboSHAKEDomain(t *testing.T) {
+    // Input: the domain separation bytes either side of the range 0x01-0x7F
+    for _, D := range []byte{0x00, 0x80, 0xFF} {
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("\nTest: NewTurboSHAKE128(0x%02X)\nResult:   no panic\nExpected: panic\n", D)
+                }
+            }()
+            NewTurboSHAKE128(D)
+        }()
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("\nTest: NewTurboSHAKE256(0x%02X)\nResult:   no panic\nExpected: panic\n", D)
+                }
+            }()
+            NewTurboSHAKE256(D)
+        }()
+    }
+}
+
+func TestTurboSHAKEStreaming(t *testing.T) {
+    // Input: 1000 bytes written and read in uneven pieces
+    input := ptn(1000)
+    expected := TurboSHAKE128(input, 0x1F, 500)
+    s := NewTurboSHAKE128(0x1F)
+    for i := 0; i < len(input); i += 37 {
+        end := i + 37
+        if end > len(input) {
+            end = len(input)
+        }
+        s.Write(input[i:end])
+    }
+    result := make([]byte, 500)
+    for i := 0; i < len(result); i += 99 {
+        end := i + 99
+        if end > len(result) {
+            end = len(result)
+        }
+        s.Read(result[i:end])
+    }
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
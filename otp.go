@@ -0,0 +1,233 @@
+package sha
+
+import (
+    "crypto/subtle"
+    "encoding/base32"
+    "encoding/binary"
+    "errors"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+/* One-time passwords: HOTP (RFC 4226), TOTP (RFC 6238), and the otpauth://
+ * URIs used to provision authenticator apps */
+
+// Limits on the number of digits in a code. The truncated HMAC has 31 bits,
+// so codes of 10 digits are the longest that carry any more of it
+const (
+    OTPDigitsMin = 6
+    OTPDigitsMax = 10
+)
+
+// The defaults for otpauth:// URIs which leave out a parameter
+const (
+    OTPDigitsDefault = 6
+    TOTPPeriodDefault = 30
+)
+
+// Returned by ParseOTPAuthURI for a URI it cannot parse
+var ErrOTPAuthURI = errors.New("sha: invalid otpauth URI")
+
+// Secrets in otpauth:// URIs are base32 without padding
+var otpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// The algorithm names of otpauth:// URIs
+var otpAlgorithms = map[string]Hash{
+    "SHA1": HashSHA1,
+    "SHA256": HashSHA256,
+    "SHA512": HashSHA512,
+}
+
+// A one-time password key, as held in an otpauth:// URI
+type OTPKey struct {
+    TOTP bool           // TOTP if true, otherwise HOTP
+    Issuer string       // The provider, which may be empty
+    Account string      // The user's account name
+    Secret []byte
+    Hash Hash           // The HMAC hash function: SHA-1, SHA-256 or SHA-512
+    Digits int
+    Counter uint64      // The initial counter of an HOTP key
+    Period int          // The time step of a TOTP key in seconds
+}
+
+func HOTP(h Hash, secret []byte, counter uint64, digits int) (string, error) {
+    /* Returns the HOTP code of the given number of digits for a counter,
+     * from the dynamic truncation of HMAC(secret, counter) */
+    if digits < OTPDigitsMin || digits > OTPDigitsMax {
+        return "", errors.New("sha: OTP codes must have 6 to 10 digits")
+    }
+    var c [8]byte
+    binary.BigEndian.PutUint64(c[:], counter)
+    mac := HMACSum(h, secret, c[:])
+    // The last 4 bits choose the offset of 31 bits of the MAC
+    offset := mac[len(mac)-1] & 0xf
+    value := uint64(binary.BigEndian.Uint32(mac[offset:]) & 0x7fffffff)
+    code := strconv.FormatUint(value, 10)
+    if len(code) > digits {
+        code = code[len(code)-digits:]
+    }
+    return strings.Repeat("0", digits - len(code)) + code, nil
+}
+
+func TOTP(h Hash, secret []byte, t time.Time, period int, digits int) (string, error) {
+    /* Returns the TOTP code at time t: the HOTP code for the number of
+     * periods of the given number of seconds since the Unix epoch */
+    counter, err := totpCounter(t, period)
+    if err != nil {
+        return "", err
+    }
+    return HOTP(h, secret, counter, digits)
+}
+
+func totpCounter(t time.Time, period int) (uint64, error) {
+    /* Returns the time step of t */
+    if period <= 0 {
+        return 0, errors.New("sha: TOTP period must be positive")
+    }
+    if t.Unix() < 0 {
+        return 0, errors.New("sha: TOTP time is before the Unix epoch")
+    }
+    return uint64(t.Unix()) / uint64(period), nil
+}
+
+func otpEqual(h Hash, secret []byte, counter uint64, digits int, code string) bool {
+    /* Checks a code against the one for counter in constant time */
+    expected, err := HOTP(h, secret, counter, digits)
+    return err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1
+}
+
+func VerifyHOTP(h Hash, secret []byte, counter uint64, digits int, window int, code string) (uint64, bool) {
+    /* Checks a code against the counters from counter up to window ahead of
+     * it, to resynchronise with a token which has generated codes that were
+     * not used. On success it returns the counter to store, one past the
+     * match, so that no code is accepted twice */
+    for i := uint64(0); i <= uint64(max(window, 0)) && counter + i >= counter; i++ {
+        if otpEqual(h, secret, counter + i, digits, code) {
+            return counter + i + 1, true
+        }
+    }
+    return counter, false
+}
+
+func VerifyTOTP(h Hash, secret []byte, t time.Time, period int, digits int, skew int, code string) (uint64, bool) {
+    /* Checks a code against the time steps up to skew periods either side of
+     * time t, nearest first, to allow for clock drift and slow entry. On
+     * success it returns the matching time step: callers should store it
+     * and reject later codes from the same or earlier steps */
+    now, err := totpCounter(t, period)
+    if err != nil {
+        return 0, false
+    }
+    for i := uint64(0); i <= uint64(max(skew, 0)); i++ {
+        // The earlier step first, unless it is before the epoch
+        var steps []uint64
+        if i <= now {
+            steps = append(steps, now - i)
+        }
+        if i > 0 {
+            steps = append(steps, now + i)
+        }
+        for _, step := range steps {
+            if otpEqual(h, secret, step, digits, code) {
+                return step, true
+            }
+        }
+    }
+    return 0, false
+}
+
+/* otpauth:// URIs */
+
+func ParseOTPAuthURI(uri string) (*OTPKey, error) {
+    /* Parses a URI of the form otpauth://totp/Issuer:account?secret=...,
+     * with the optional parameters issuer, algorithm, digits, and period for
+     * TOTP or the required counter for HOTP */
+    u, err := url.Parse(uri)
+    if err != nil || u.Scheme != "otpauth" || len(u.Path) < 2 {
+        return nil, ErrOTPAuthURI
+    }
+    key := &OTPKey{Hash: HashSHA1, Digits: OTPDigitsDefault}
+    switch strings.ToLower(u.Host) {
+    case "totp":
+        key.TOTP = true
+        key.Period = TOTPPeriodDefault
+    case "hotp":
+    default:
+        return nil, ErrOTPAuthURI
+    }
+    // The label is the account, optionally after the issuer and a colon
+    key.Account = u.Path[1:]
+    if issuer, account, found := strings.Cut(key.Account, ":"); found {
+        key.Issuer = issuer
+        key.Account = strings.TrimLeft(account, " ")
+    }
+    query := u.Query()
+    if issuer := query.Get("issuer"); issuer != "" {
+        key.Issuer = issuer
+    }
+    // Secrets are often shown in lower case, with spaces or padding
+    secret := strings.ToUpper(strings.NewReplacer(" ", "", "=", "").Replace(query.Get("secret")))
+    if key.Secret, err = otpBase32.DecodeString(secret); err != nil || len(key.Secret) == 0 {
+        return nil, ErrOTPAuthURI
+    }
+    if algorithm := query.Get("algorithm"); algorithm != "" {
+        h, ok := otpAlgorithms[strings.ToUpper(algorithm)]
+        if !ok {
+            return nil, ErrOTPAuthURI
+        }
+        key.Hash = h
+    }
+    if digits := query.Get("digits"); digits != "" {
+        key.Digits, err = strconv.Atoi(digits)
+        if err != nil || key.Digits < OTPDigitsMin || key.Digits > OTPDigitsMax {
+            return nil, ErrOTPAuthURI
+        }
+    }
+    if key.TOTP {
+        if period := query.Get("period"); period != "" {
+            key.Period, err = strconv.Atoi(period)
+            if err != nil || key.Period <= 0 {
+                return nil, ErrOTPAuthURI
+            }
+        }
+    } else {
+        key.Counter, err = strconv.ParseUint(query.Get("counter"), 10, 64)
+        if err != nil {
+            return nil, ErrOTPAuthURI
+        }
+    }
+    return key, nil
+}
+
+func (key *OTPKey) URI() string {
+    /* Returns the otpauth:// URI of the key, leaving out parameters with
+     * their default values */
+    label := key.Account
+    query := url.Values{"secret": {otpBase32.EncodeToString(key.Secret)}}
+    if key.Issuer != "" {
+        label = key.Issuer + ":" + label
+        query.Set("issuer", key.Issuer)
+    }
+    for name, h := range otpAlgorithms {
+        if h == key.Hash && h != HashSHA1 {
+            query.Set("algorithm", name)
+        }
+    }
+    if key.Digits != OTPDigitsDefault {
+        query.Set("digits", strconv.Itoa(key.Digits))
+    }
+    kind := "hotp"
+    if key.TOTP {
+        kind = "totp"
+        if key.Period != TOTPPeriodDefault {
+            query.Set("period", strconv.Itoa(key.Period))
+        }
+    } else {
+        query.Set("counter", strconv.FormatUint(key.Counter, 10))
+    }
+    // Authenticator apps expect spaces as %20 rather than '+', which is
+    // otherwise escaped as %2B
+    return "otpauth://" + kind + "/" + url.PathEscape(label) + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
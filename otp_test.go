@@ -0,0 +1,192 @@
+package sha
+
+import (
+    "testing"
+    "reflect"
+    "time"
+)
+
+// Test vectors from RFC 4226 appendix D. The 10-digit codes are the
+// decimal values of the truncated HMACs
+var hotpTests = []struct {
+    code string
+    value string
+}{
+    {"755224", "1284755224"},
+    {"287082", "1094287082"},
+    {"359152", "0137359152"},
+    {"969429", "1726969429"},
+    {"338314", "1640338314"},
+    {"254676", "0868254676"},
+    {"287922", "1918287922"},
+    {"162583", "0082162583"},
+    {"399871", "0673399871"},
+    {"520489", "0645520489"},
+}
+
+// Test vectors from RFC 6238 appendix B, with a 30 second period and 8
+// digits. Each hash function has a seed of its output size
+var totpSecrets = map[Hash][]byte{
+    HashSHA1: []byte("12345678901234567890"),
+    HashSHA256: []byte("12345678901234567890123456789012"),
+    HashSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+}
+
+var totpTests = []struct {
+    time int64
+    codes map[Hash]string
+}{
+    {59, map[Hash]string{HashSHA1: "94287082", HashSHA256: "46119246", HashSHA512: "90693936"}},
+    {1111111109, map[Hash]string{HashSHA1: "07081804", HashSHA256: "68084774", HashSHA512: "25091201"}},
+    {1111111111, map[Hash]string{HashSHA1: "14050471", HashSHA256: "67062674", HashSHA512: "99943326"}},
+    {1234567890, map[Hash]string{HashSHA1: "89005924", HashSHA256: "91819424", HashSHA512: "93441116"}},
+    {2000000000, map[Hash]string{HashSHA1: "69279037", HashSHA256: "90698825", HashSHA512: "38618901"}},
+    {20000000000, map[Hash]string{HashSHA1: "65353130", HashSHA256: "77737706", HashSHA512: "47863826"}},
+}
+
+func TestHOTP(t *testing.T) {
+    secret := []byte("12345678901234567890")
+    for i, test := range hotpTests {
+        for _, expected := range []string{test.code, test.value} {
+            code, err := HOTP(HashSHA1, secret, uint64(i), len(expected))
+            if err != nil || code != expected {
+                t.Errorf("\nTest: counter %d\nResult:   %s %v\nExpected: %s\n", i, code, err, expected)
+            }
+        }
+    }
+    for _, digits := range []int{5, 11} {
+        if _, err := HOTP(HashSHA1, secret, 0, digits); err == nil {
+            t.Errorf("\nTest: %d digits\nExpected an error\n", digits)
+        }
+    }
+}
+
+func TestVerifyHOTP(t *testing.T) {
+    secret := []byte("12345678901234567890")
+    // Input: a code 3 ahead of the counter, within a window of 3 but not 2
+    if next, ok := VerifyHOTP(HashSHA1, secret, 1, 6, 3, "338314"); !ok || next != 5 {
+        t.Errorf("\nResult:   %d %v\nExpected: 5 true\n", next, ok)
+    }
+    if next, ok := VerifyHOTP(HashSHA1, secret, 1, 6, 2, "338314"); ok || next != 1 {
+        t.Errorf("\nResult:   %d %v\nExpected: 1 false\n", next, ok)
+    }
+    // Input: a code already used, and a code of the wrong length
+    if _, ok := VerifyHOTP(HashSHA1, secret, 5, 6, 10, "338314"); ok {
+        t.Errorf("\nA code behind the counter was accepted\n")
+    }
+    if _, ok := VerifyHOTP(HashSHA1, secret, 0, 6, 0, "0755224"); ok {
+        t.Errorf("\nA code with a leading zero was accepted\n")
+    }
+    // Input: the last counter, where the window must not wrap around
+    if next, ok := VerifyHOTP(HashSHA1, secret, 1 << 64 - 1, 6, 10, "755224"); ok || next != 1 << 64 - 1 {
+        t.Errorf("\nResult:   %d %v\nExpected: no match\n", next, ok)
+    }
+}
+
+func TestTOTP(t *testing.T) {
+    for _, test := range totpTests {
+        for h, expected := range test.codes {
+            code, err := TOTP(h, totpSecrets[h], time.Unix(test.time, 0), 30, 8)
+            if err != nil || code != expected {
+                t.Errorf("\nTest: %s %d\nResult:   %s %v\nExpected: %s\n", h, test.time, code, err, expected)
+            }
+        }
+    }
+    if _, err := TOTP(HashSHA1, totpSecrets[HashSHA1], time.Unix(-1, 0), 30, 8); err == nil {
+        t.Errorf("\nExpected an error before the epoch\n")
+    }
+    if _, err := TOTP(HashSHA1, totpSecrets[HashSHA1], time.Unix(59, 0), 0, 8); err == nil {
+        t.Errorf("\nExpected an error for a period of 0\n")
+    }
+}
+
+func TestVerifyTOTP(t *testing.T) {
+    secret := totpSecrets[HashSHA1]
+    // Input: the code for 1111111111 (step 37037037) checked from 70
+    // seconds later and earlier, which needs a skew of 2 and 3 steps
+    for _, test := range []struct {
+        time int64
+        skew int
+        ok bool
+    }{
+        {1111111111, 0, true},
+        {1111111181, 1, false},
+        {1111111181, 2, true},
+        {1111111041, 2, false},
+        {1111111041, 3, true},
+    } {
+        step, ok := VerifyTOTP(HashSHA1, secret, time.Unix(test.time, 0), 30, 8, test.skew, "14050471")
+        if ok != test.ok || (ok && step != 37037037) {
+            t.Errorf("\nTest: %d skew %d\nResult:   %d %v\nExpected: %v\n", test.time, test.skew, step, ok, test.ok)
+        }
+    }
+    // Input: the code for step 1 near the epoch, where earlier steps do not
+    // exist
+    if step, ok := VerifyTOTP(HashSHA1, secret, time.Unix(0, 0), 30, 8, 5, "94287082"); !ok || step != 1 {
+        t.Errorf("\nResult:   %d %v\nExpected: 1 true\n", step, ok)
+    }
+}
+
+func TestOTPAuthURI(t *testing.T) {
+    tests := []struct {
+        uri string
+        key OTPKey
+        secret string
+        canonical string
+    }{
+        // The examples of the Google Authenticator key URI format
+        {"otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example",
+            OTPKey{TOTP: true, Issuer: "Example", Account: "alice@google.com", Hash: HashSHA1, Digits: 6, Period: 30},
+            "48656c6c6f21deadbeef",
+            "otpauth://totp/Example:alice@google.com?issuer=Example&secret=JBSWY3DPEHPK3PXP"},
+        {"otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA1&digits=6&period=30",
+            OTPKey{TOTP: true, Issuer: "ACME Co", Account: "john.doe@email.com", Hash: HashSHA1, Digits: 6, Period: 30},
+            "3dc6caa4824a6d288767b2331e20b43166cb85d9",
+            "otpauth://totp/ACME%20Co:john.doe@email.com?issuer=ACME%20Co&secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ"},
+        // An HOTP key, a label without an issuer, and a lower case secret
+        // with padding
+        {"otpauth://hotp/bob?secret=gezdgnbvgy3tqojqgezdgnbvgy3tqojq&counter=7&digits=8&algorithm=sha256",
+            OTPKey{Account: "bob", Hash: HashSHA256, Digits: 8, Counter: 7},
+            "3132333435363738393031323334353637383930",
+            "otpauth://hotp/bob?algorithm=SHA256&counter=7&digits=8&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+        {"otpauth://TOTP/Big%20Corp:%20carol?secret=GEZDGNBVGY%3D%3D%3D%3D%3D%3D&period=60&algorithm=SHA512",
+            OTPKey{TOTP: true, Issuer: "Big Corp", Account: "carol", Hash: HashSHA512, Digits: 6, Period: 60},
+            "313233343536",
+            "otpauth://totp/Big%20Corp:carol?algorithm=SHA512&issuer=Big%20Corp&period=60&secret=GEZDGNBVGY"},
+    }
+    for _, test := range tests {
+        key, err := ParseOTPAuthURI(test.uri)
+        if err != nil {
+            t.Errorf("\nTest: %s\nError: %v\n", test.uri, err)
+            continue
+        }
+        test.key.Secret = mustHex(test.secret)
+        if !reflect.DeepEqual(*key, test.key) {
+            t.Errorf("\nTest: %s\nResult:   %+v\nExpected: %+v\n", test.uri, *key, test.key)
+        }
+        if uri := key.URI(); uri != test.canonical {
+            t.Errorf("\nTest: %s\nResult:   %s\nExpected: %s\n", test.uri, uri, test.canonical)
+        }
+        if again, err := ParseOTPAuthURI(key.URI()); err != nil || !reflect.DeepEqual(again, key) {
+            t.Errorf("\nTest: %s\nURI did not parse back %v\n", test.uri, err)
+        }
+    }
+    // Input: URIs with a wrong scheme or type, no label, no secret, invalid
+    // base32, an unknown algorithm, too few digits, a zero period, and an
+    // HOTP key without a counter
+    for _, uri := range []string{
+        "https://totp/a?secret=JBSWY3DP",
+        "otpauth://motp/a?secret=JBSWY3DP",
+        "otpauth://totp?secret=JBSWY3DP",
+        "otpauth://totp/a",
+        "otpauth://totp/a?secret=JBSWY3D!",
+        "otpauth://totp/a?secret=JBSWY3DP&algorithm=MD5",
+        "otpauth://totp/a?secret=JBSWY3DP&digits=4",
+        "otpauth://totp/a?secret=JBSWY3DP&period=0",
+        "otpauth://hotp/a?secret=JBSWY3DP",
+    } {
+        if _, err := ParseOTPAuthURI(uri); err != ErrOTPAuthURI {
+            t.Errorf("\nTest: %s\nResult:   %v\nExpected: %v\n", uri, err, ErrOTPAuthURI)
+        }
+    }
+}
@@ -0,0 +1,317 @@
+package passwd
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/base64"
+    "errors"
+    "strconv"
+    "strings"
+
+    "github.com/xrmon/sha"
+)
+
+/* Verification and generation of password hashes in the formats found in
+ * LDAP directories, Apache htpasswd files, Django, Cisco IOS configurations
+ * and PHC strings, detecting the format from the stored hash */
+
+// A password hash format
+type Format int
+
+const (
+    Unknown Format = iota
+    LDAPSHA              // {SHA}, also used by htpasswd
+    LDAPSSHA             // {SSHA}, SHA-1 with a salt
+    LDAPSHA256           // {SHA256}
+    LDAPSSHA256          // {SSHA256}
+    LDAPSHA512           // {SHA512}
+    LDAPSSHA512          // {SSHA512}
+    DjangoPBKDF2SHA1     // pbkdf2_sha1$iterations$salt$hash
+    DjangoPBKDF2SHA256   // pbkdf2_sha256$iterations$salt$hash
+    CiscoType8           // $8$salt$hash, PBKDF2-HMAC-SHA256
+    CiscoType9           // $9$salt$hash, scrypt
+    PHCPBKDF2SHA1        // $pbkdf2$
+    PHCPBKDF2SHA256      // $pbkdf2-sha256$
+    PHCPBKDF2SHA512      // $pbkdf2-sha512$
+    SHACrypt256          // $5$, optionally prefixed by {CRYPT}
+    SHACrypt512          // $6$, optionally prefixed by {CRYPT}
+)
+
+// Returned for a hash in an unrecognised format
+var ErrUnknownFormat = errors.New("passwd: unknown password hash format")
+
+// Returned for a hash in a recognised format which cannot be parsed
+var ErrMalformed = errors.New("passwd: malformed password hash")
+
+// The hash function and prefix of each format, and the iteration count
+// used by Generate when none is given. PBKDF2 counts follow the OWASP
+// recommendations, and Django's its current default
+var formats = map[Format]struct {
+    name string
+    prefix string
+    h sha.Hash
+    iterations int
+}{
+    LDAPSHA: {"LDAP {SHA}", "{SHA}", sha.HashSHA1, 0},
+    LDAPSSHA: {"LDAP {SSHA}", "{SSHA}", sha.HashSHA1, 0},
+    LDAPSHA256: {"LDAP {SHA256}", "{SHA256}", sha.HashSHA256, 0},
+    LDAPSSHA256: {"LDAP {SSHA256}", "{SSHA256}", sha.HashSHA256, 0},
+    LDAPSHA512: {"LDAP {SHA512}", "{SHA512}", sha.HashSHA512, 0},
+    LDAPSSHA512: {"LDAP {SSHA512}", "{SSHA512}", sha.HashSHA512, 0},
+    DjangoPBKDF2SHA1: {"Django PBKDF2-SHA1", "pbkdf2_sha1$", sha.HashSHA1, 1000000},
+    DjangoPBKDF2SHA256: {"Django PBKDF2-SHA256", "pbkdf2_sha256$", sha.HashSHA256, 1000000},
+    CiscoType8: {"Cisco type 8", "$8$", sha.HashSHA256, 20000},
+    CiscoType9: {"Cisco type 9", "$9$", sha.HashSHA256, 0},
+    PHCPBKDF2SHA1: {"PHC PBKDF2-SHA1", "$pbkdf2$", sha.HashSHA1, 1300000},
+    PHCPBKDF2SHA256: {"PHC PBKDF2-SHA256", "$pbkdf2-sha256$", sha.HashSHA256, 600000},
+    PHCPBKDF2SHA512: {"PHC PBKDF2-SHA512", "$pbkdf2-sha512$", sha.HashSHA512, 210000},
+    SHACrypt256: {"SHA-256-crypt", "$5$", sha.HashSHA256, 5000},
+    SHACrypt512: {"SHA-512-crypt", "$6$", sha.HashSHA512, 5000},
+}
+
+// The base64 alphabet of Cisco type 8 & 9 hashes, which is the crypt(3)
+// alphabet in the standard bit order
+var ciscoEncoding = base64.NewEncoding("./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz").WithPadding(base64.NoPadding)
+
+// passlib's "adapted base64" for $pbkdf2-...$ hashes without parameter
+// names, which uses '.' in place of '+'
+var ab64Encoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").WithPadding(base64.NoPadding)
+
+func (f Format) String() string {
+    if info, ok := formats[f]; ok {
+        return info.name
+    }
+    return "Unknown"
+}
+
+func Identify(hash string) Format {
+    /* Returns the format of a stored hash from its prefix, or Unknown.
+     * LDAP scheme names are not case sensitive */
+    upper := strings.ToUpper(hash)
+    if strings.HasPrefix(upper, "{CRYPT}") {
+        hash = hash[len("{CRYPT}"):]
+        upper = upper[len("{CRYPT}"):]
+        if !strings.HasPrefix(hash, "$5$") && !strings.HasPrefix(hash, "$6$") {
+            return Unknown
+        }
+    }
+    for f, info := range formats {
+        prefix := info.prefix
+        if strings.HasPrefix(prefix, "{") {
+            if strings.HasPrefix(upper, prefix) {
+                return f
+            }
+        } else if strings.HasPrefix(hash, prefix) {
+            return f
+        }
+    }
+    return Unknown
+}
+
+func Verify(password []byte, hash string) (bool, error) {
+    /* Checks in constant time whether password matches a stored hash in
+     * any of the supported formats. An error is returned if the format is
+     * unknown or the hash cannot be parsed */
+    f := Identify(hash)
+    switch f {
+    case Unknown:
+        return false, ErrUnknownFormat
+    case SHACrypt256, SHACrypt512:
+        if strings.HasPrefix(strings.ToUpper(hash), "{CRYPT}") {
+            hash = hash[len("{CRYPT}"):]
+        }
+        // Crypt is run once, as rounds may be up to 999999999
+        output, err := sha.Crypt(password, hash)
+        if err != nil {
+            return false, ErrMalformed
+        }
+        return subtle.ConstantTimeCompare([]byte(output), []byte(hash)) == 1, nil
+    }
+    h := formats[f].h
+    var expected, output []byte
+    switch f {
+    case LDAPSHA, LDAPSSHA, LDAPSHA256, LDAPSSHA256, LDAPSHA512, LDAPSSHA512:
+        // base64(H(password || salt) || salt), where unsalted schemes have
+        // an empty salt
+        data, err := base64.StdEncoding.DecodeString(hash[len(formats[f].prefix):])
+        if err != nil || len(data) < h.Size() || (len(data) > h.Size()) != isSalted(f) {
+            return false, ErrMalformed
+        }
+        expected = data[:h.Size()]
+        output = h.Sum(append(append([]byte(nil), password...), data[h.Size():]...))
+    case DjangoPBKDF2SHA1, DjangoPBKDF2SHA256:
+        fields := strings.Split(hash, "$")
+        if len(fields) != 4 {
+            return false, ErrMalformed
+        }
+        iter, err := strconv.Atoi(fields[1])
+        if err != nil || iter < 1 {
+            return false, ErrMalformed
+        }
+        expected, err = base64.StdEncoding.DecodeString(fields[3])
+        if err != nil || len(expected) == 0 {
+            return false, ErrMalformed
+        }
+        output, _ = sha.PBKDF2(password, []byte(fields[2]), iter, len(expected), h)
+    case CiscoType8, CiscoType9:
+        fields := strings.Split(hash, "$")
+        if len(fields) != 4 {
+            return false, ErrMalformed
+        }
+        var err error
+        expected, err = ciscoEncoding.DecodeString(fields[3])
+        if err != nil || len(expected) != 32 {
+            return false, ErrMalformed
+        }
+        output = ciscoHash(f, password, []byte(fields[2]))
+    case PHCPBKDF2SHA1, PHCPBKDF2SHA256, PHCPBKDF2SHA512:
+        var salt []byte
+        var iter int
+        var err error
+        salt, expected, iter, err = parsePHC(hash)
+        if err != nil {
+            return false, err
+        }
+        output, _ = sha.PBKDF2(password, salt, iter, len(expected), h)
+    }
+    return subtle.ConstantTimeCompare(output, expected) == 1, nil
+}
+
+func isSalted(f Format) bool {
+    return f == LDAPSSHA || f == LDAPSSHA256 || f == LDAPSSHA512
+}
+
+func ciscoHash(f Format, password []byte, salt []byte) []byte {
+    /* Returns the 32-byte Cisco type 8 or type 9 hash of a password */
+    if f == CiscoType9 {
+        output, _ := sha.Scrypt(password, salt, 16384, 1, 1, 32)
+        return output
+    }
+    output, _ := sha.PBKDF2(password, salt, formats[CiscoType8].iterations, 32, sha.HashSHA256)
+    return output
+}
+
+func parsePHC(hash string) ([]byte, []byte, int, error) {
+    /* Parses a $pbkdf2-...$ hash, returning the salt, the hash and the
+     * iteration count. PHC strings give parameters as i=N,l=L and use
+     * standard base64, while passlib gives only the count and uses its
+     * adapted base64 */
+    fields := strings.Split(hash, "$")
+    if len(fields) != 5 {
+        return nil, nil, 0, ErrMalformed
+    }
+    encoding := base64.RawStdEncoding
+    var iter int
+    var err error
+    if strings.Contains(fields[2], "=") {
+        for _, param := range strings.Split(fields[2], ",") {
+            name, value, _ := strings.Cut(param, "=")
+            if name == "i" {
+                iter, err = strconv.Atoi(value)
+            }
+        }
+    } else {
+        iter, err = strconv.Atoi(fields[2])
+        encoding = ab64Encoding
+    }
+    if err != nil || iter < 1 {
+        return nil, nil, 0, ErrMalformed
+    }
+    salt, err := encoding.DecodeString(fields[3])
+    if err != nil {
+        return nil, nil, 0, ErrMalformed
+    }
+    expected, err := encoding.DecodeString(fields[4])
+    if err != nil || len(expected) == 0 {
+        return nil, nil, 0, ErrMalformed
+    }
+    return salt, expected, iter, nil
+}
+
+/* Generating hashes */
+
+func randomSalt(n int, alphabet string) ([]byte, error) {
+    /* Returns n random bytes, or n random characters from alphabet if it
+     * is not empty */
+    salt := make([]byte, n)
+    if _, err := rand.Read(salt); err != nil {
+        return nil, err
+    }
+    if alphabet == "" {
+        return salt, nil
+    }
+    // Bytes past the last multiple of len(alphabet) are redrawn, so that
+    // every character is equally likely
+    limit := 256 - 256 % len(alphabet)
+    for i := range salt {
+        for int(salt[i]) >= limit {
+            if _, err := rand.Read(salt[i:i+1]); err != nil {
+                return nil, err
+            }
+        }
+        salt[i] = alphabet[int(salt[i]) % len(alphabet)]
+    }
+    return salt, nil
+}
+
+func Generate(f Format, password []byte, iterations int) (string, error) {
+    /* Hashes a password in the format f with a random salt. An iterations
+     * value of 0 gives the default for the format, and formats with fixed
+     * parameters accept only 0 */
+    info, ok := formats[f]
+    if !ok {
+        return "", ErrUnknownFormat
+    }
+    fixed := info.iterations == 0 || f == CiscoType8
+    if iterations < 0 || (iterations != 0 && fixed) {
+        return "", errors.New("passwd: iteration count cannot be set for " + info.name)
+    }
+    if iterations == 0 {
+        iterations = info.iterations
+    }
+    h := info.h
+    switch f {
+    case LDAPSHA, LDAPSHA256, LDAPSHA512:
+        return info.prefix + base64.StdEncoding.EncodeToString(h.Sum(password)), nil
+    case LDAPSSHA, LDAPSSHA256, LDAPSSHA512:
+        salt, err := randomSalt(8, "")
+        if err != nil {
+            return "", err
+        }
+        data := h.Sum(append(append([]byte(nil), password...), salt...))
+        return info.prefix + base64.StdEncoding.EncodeToString(append(data, salt...)), nil
+    case DjangoPBKDF2SHA1, DjangoPBKDF2SHA256:
+        salt, err := randomSalt(22, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+        if err != nil {
+            return "", err
+        }
+        output, err := sha.PBKDF2(password, salt, iterations, h.Size(), h)
+        if err != nil {
+            return "", err
+        }
+        return info.prefix + strconv.Itoa(iterations) + "$" + string(salt) + "$" + base64.StdEncoding.EncodeToString(output), nil
+    case CiscoType8, CiscoType9:
+        salt, err := randomSalt(14, "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+        if err != nil {
+            return "", err
+        }
+        return info.prefix + string(salt) + "$" + ciscoEncoding.EncodeToString(ciscoHash(f, password, salt)), nil
+    case PHCPBKDF2SHA1, PHCPBKDF2SHA256, PHCPBKDF2SHA512:
+        salt, err := randomSalt(16, "")
+        if err != nil {
+            return "", err
+        }
+        output, err := sha.PBKDF2(password, salt, iterations, h.Size(), h)
+        if err != nil {
+            return "", err
+        }
+        params := "i=" + strconv.Itoa(iterations) + ",l=" + strconv.Itoa(h.Size())
+        return info.prefix + params + "$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(output), nil
+    }
+    // SHA-crypt
+    setting, err := sha.SHACryptSetting(h, iterations)
+    if err != nil {
+        return "", err
+    }
+    return sha.Crypt(password, setting)
+}
@@ -0,0 +1,111 @@
+package passwd
+
+import (
+    "testing"
+    "strings"
+)
+
+// Stored hashes of the password "hashcat". The Cisco, Django, passlib and
+// salted LDAP examples are from the hashcat example hashes, and the rest
+// were made with Python's hashlib. The {CRYPT} hash is Drepper's first
+// SHA-512-crypt test vector, whose password is "Hello world!"
+var passwdTests = []struct {
+    hash string
+    format Format
+}{
+    {"{SHA}uJ6qx+YUFzQbcQtyd2gpTQ5qJ3s=", LDAPSHA},
+    {"{SSHA}AZKja92fbuuB9SpRlHqaoXxbTc43Mzc2MDM1Ng==", LDAPSSHA},
+    {"{ssha}AZKja92fbuuB9SpRlHqaoXxbTc43Mzc2MDM1Ng==", LDAPSSHA},
+    {"{SHA256}En5vv+JKdQ5ykwwiCo4TgnVla45dj0ipjDyS3yyrqTU=", LDAPSHA256},
+    {"{SSHA256}OZiz0cnQ5hgyel3Emh7NCbhBRCQ+HVBwYplQunHYnER7TLuV", LDAPSSHA256},
+    {"{SHA512}gqndqCnrf4/+n75J5F1H0trZZk+7et9ySS48gevT4pE02bwSISv4PGhA8Q6CRrnbVKSFm3zNASPYblhyweUILw==", LDAPSHA512},
+    {"{SSHA512}UxtsBUHdBh+gzvGQOvjuuZAWfQ7H9K/s+8DIjDUjP2TdhxLE5J+wfYbvTWvF+dp2v42TJyV7aiTUU9WCo9ZAQKGyw9Tl9gcY", LDAPSSHA512},
+    {"pbkdf2_sha1$10000$Fp0AszqQJd4W$rROie6XCoItdTEP4dcT5hBVW3a4=", DjangoPBKDF2SHA1},
+    {"pbkdf2_sha256$20000$H0dPx8NeajVu$GiC4k5kqbbR9qWBlsRgDywNqC2vd9kqfk7zdorEnNas=", DjangoPBKDF2SHA256},
+    {"$8$TnGX/fE4KGHOVU$pEhnEvxrvaynpi8j4f.EMHr6M.FzU8xnZnBr/tJdFWk", CiscoType8},
+    {"$9$2MJBozw/9R3UsU$2lFhcKvpghcyw8deP25GOfyZaagyUOGBymkryvOdfo6", CiscoType9},
+    {"$pbkdf2$i=1000,l=20$AAECAwQFBgcICQoLDA0ODw$7THVTfbb0qQJoy4qp7daksbkoOc", PHCPBKDF2SHA1},
+    {"$pbkdf2-sha256$i=1000,l=32$AAECAwQFBgcICQoLDA0ODw$41t67iG56WfQv9Vu27wpEfkSgNcfP7oJnvqIpvUmgAM", PHCPBKDF2SHA256},
+    {"$pbkdf2-sha256$29000$x9h7j/Ge8x6DMEao1VqrdQ$kra3R1wEnY8mPdDWOpTqOTINaAmZvRMcYd8u5OBQP9A", PHCPBKDF2SHA256},
+    {"$pbkdf2-sha512$i=1000,l=64$AAECAwQFBgcICQoLDA0ODw$R7Q8tYZS87boEs9WkdUpniGGCJlLoRRVEj0sNAqmLK05KmRShwM6eQ0xsckzogEmsTcXLUjsSz4YELrVzJDeSQ", PHCPBKDF2SHA512},
+    {"{CRYPT}$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", SHACrypt512},
+}
+
+func TestIdentify(t *testing.T) {
+    for _, test := range passwdTests {
+        if f := Identify(test.hash); f != test.format {
+            t.Errorf("\nTest: %s\nResult:   %s\nExpected: %s\n", test.hash, f, test.format)
+        }
+    }
+    for _, hash := range []string{"", "plaintext", "{MD5}X03MO1qnZdYdgyfeuILPmQ==", "{CRYPT}$1$salt$hash", "$2b$10$abc"} {
+        if f := Identify(hash); f != Unknown {
+            t.Errorf("\nTest: %s\nResult:   %s\nExpected: Unknown\n", hash, f)
+        }
+    }
+}
+
+func TestVerify(t *testing.T) {
+    for _, test := range passwdTests {
+        password := "hashcat"
+        if test.format == SHACrypt512 {
+            password = "Hello world!"
+        }
+        ok, err := Verify([]byte(password), test.hash)
+        if !ok || err != nil {
+            t.Errorf("\nTest: %s\nResult:   %v %v\nExpected: true\n", test.hash, ok, err)
+        }
+        ok, err = Verify([]byte(password + "!"), test.hash)
+        if ok || err != nil {
+            t.Errorf("\nTest: %s (wrong password)\nResult:   %v %v\nExpected: false\n", test.hash, ok, err)
+        }
+    }
+    // Input: hashes which cannot be parsed
+    malformed := []string{
+        "{SSHA}AZKja92fbuuB9SpRlHqaoXxbTc4=",
+        "{SHA}AZKja92fbuuB9SpRlHqaoXxbTc43Mzc2MDM1Ng==",
+        "{SHA}not base64",
+        "pbkdf2_sha256$many$H0dPx8NeajVu$GiC4k5kqbbR9qWBlsRgDywNqC2vd9kqfk7zdorEnNas=",
+        "pbkdf2_sha256$20000$H0dPx8NeajVu",
+        "$8$TnGX/fE4KGHOVU$pEhnEvxrvaynpi8j4f",
+        "$pbkdf2-sha256$i=0$AAECAwQFBgcICQoLDA0ODw$41t67iG56WfQv9Vu27wpEfkSgNcfP7oJnvqIpvUmgAM",
+        "$5$rounds=$salt$hash",
+    }
+    for _, hash := range malformed {
+        if _, err := Verify([]byte("hashcat"), hash); err != ErrMalformed {
+            t.Errorf("\nTest: %s\nResult:   %v\nExpected: %v\n", hash, err, ErrMalformed)
+        }
+    }
+    if _, err := Verify([]byte("hashcat"), "$2b$10$abc"); err != ErrUnknownFormat {
+        t.Errorf("\nResult:   %v\nExpected: %v\n", err, ErrUnknownFormat)
+    }
+}
+
+func TestGenerate(t *testing.T) {
+    for f := range formats {
+        // Small iteration counts keep the test fast
+        iterations := 0
+        if formats[f].iterations != 0 && f != CiscoType8 {
+            iterations = 1000
+        }
+        hash, err := Generate(f, []byte("correct horse"), iterations)
+        if err != nil {
+            t.Errorf("\nTest: %s\nError: %v\n", f, err)
+            continue
+        }
+        if Identify(hash) != f {
+            t.Errorf("\nTest: %s\nResult:   %s identified as %s\n", f, hash, Identify(hash))
+        }
+        if ok, err := Verify([]byte("correct horse"), hash); !ok || err != nil {
+            t.Errorf("\nTest: %s\nResult:   %s not verified %v\n", f, hash, err)
+        }
+        if f == DjangoPBKDF2SHA256 && !strings.HasPrefix(hash, "pbkdf2_sha256$1000$") {
+            t.Errorf("\nTest: %s\nResult:   %s\n", f, hash)
+        }
+    }
+    if _, err := Generate(LDAPSSHA, []byte("password"), 1000); err == nil {
+        t.Errorf("\nExpected an error for iterations with {SSHA}\n")
+    }
+    if _, err := Generate(Unknown, []byte("password"), 0); err != ErrUnknownFormat {
+        t.Errorf("\nResult:   %v\nExpected: %v\n", err, ErrUnknownFormat)
+    }
+}
@@ -0,0 +1,102 @@
+package sha
+
+import (
+    "bytes"
+    "errors"
+)
+
+/* PBKDF1 (RFC 8018) and OpenSSL's EVP_BytesToKey, as used by the
+ * "Salted__" files written by openssl enc */
+
+// The header at the start of files encrypted by openssl enc with a salt
+var SaltedHeader = []byte("Salted__")
+
+func PBKDF1(password []byte, salt []byte, iter int, keyLen int, h Hash) ([]byte, error) {
+    /* Takes a password, a salt, an iteration count and a key length in
+     * bytes, and returns a key derived using PBKDF1 with the hash function
+     * h: T_1 = Hash(P || S), T_j = Hash(T_{j-1}) and DK = T_c[:dkLen] */
+    if iter <= 0 || keyLen <= 0 {
+        return nil, errors.New("sha: PBKDF1 iteration count and key length must be positive")
+    }
+    if keyLen > h.Size() {
+        return nil, errors.New("sha: PBKDF1 key length is limited to the hash size")
+    }
+    d := h.New()
+    d.Write(password)
+    d.Write(salt)
+    T := d.Sum(nil)
+    for j := 1; j < iter; j++ {
+        d.Reset()
+        d.Write(T)
+        T = d.Sum(T[:0])
+    }
+    return T[:keyLen], nil
+}
+
+func EVPBytesToKey(h Hash, salt []byte, data []byte, count int, keyLen int, ivLen int) ([]byte, []byte, error) {
+    /* Takes a hash function, an optional 8-byte salt, the password data,
+     * an iteration count and the key and IV lengths in bytes, and returns
+     * the key and IV derived by OpenSSL's EVP_BytesToKey. Blocks of output
+     * are D_i = Hash^count(D_{i-1} || data || salt), where D_0 is empty */
+    if count <= 0 || keyLen < 0 || ivLen < 0 {
+        return nil, nil, errors.New("sha: EVP_BytesToKey count must be positive and lengths not negative")
+    }
+    d := h.New()
+    var output, D []byte
+    for len(output) < keyLen + ivLen {
+        d.Reset()
+        d.Write(D)
+        d.Write(data)
+        d.Write(salt)
+        D = d.Sum(D[:0])
+        for i := 1; i < count; i++ {
+            d.Reset()
+            d.Write(D)
+            D = d.Sum(D[:0])
+        }
+        output = append(output, D...)
+    }
+    return output[:keyLen], output[keyLen:keyLen+ivLen], nil
+}
+
+func SplitSalted(data []byte) ([]byte, []byte, error) {
+    /* Takes the output of openssl enc with a salt, and returns the 8-byte
+     * salt and the ciphertext which follow the "Salted__" header */
+    if len(data) < 16 || !bytes.Equal(data[:8], SaltedHeader) {
+        return nil, nil, errors.New("sha: data does not start with a Salted__ header")
+    }
+    return data[8:16], data[16:], nil
+}
+
+func ParseSalted(data []byte, password []byte, h Hash, keyLen int, ivLen int) ([]byte, []byte, []byte, error) {
+    /* Takes the output of openssl enc, the password, the digest given with
+     * -md, and the key and IV lengths of the cipher, and returns the key,
+     * IV and ciphertext. The key and IV are derived with EVP_BytesToKey,
+     * which openssl enc uses unless -pbkdf2 or -iter is given */
+    salt, ciphertext, err := SplitSalted(data)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    key, iv, err := EVPBytesToKey(h, salt, password, 1, keyLen, ivLen)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    return key, iv, ciphertext, nil
+}
+
+func ParseSaltedPBKDF2(data []byte, password []byte, h Hash, iter int, keyLen int, ivLen int) ([]byte, []byte, []byte, error) {
+    /* Takes the output of openssl enc -pbkdf2, the password, the digest
+     * given with -md, the iteration count given with -iter (10000 by
+     * default), and the key and IV lengths of the cipher, and returns the
+     * key, IV and ciphertext */
+    salt, ciphertext, err := SplitSalted(data)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    // The key and IV are consecutive parts of one PBKDF2 output
+    DK, err := PBKDF2(password, salt, iter, keyLen + ivLen, h)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    return DK[:keyLen], DK[keyLen:], ciphertext, nil
+}
@@ -0,0 +1,128 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "encoding/hex"
+    "os"
+)
+
+func TestPBKDF1(t *testing.T) {
+    // Input: "password", salt 78578e5a5d63cb06, 1000 iterations
+    salt, _ := hex.DecodeString("78578e5a5d63cb06")
+    tests := []struct {
+        h Hash
+        keyLen int
+        expected string
+    }{
+        {HashSHA1, 16, "dc19847e05c64d2faf10ebfb4a3d2a20"},
+        {HashSHA256, 32, "9697db8e657a3500b33babe789ee7b747ed93d0da7e13245a5e467b82c4a833a"},
+        {HashSHA512, 64, "70a5212afb9eb4b7f6fb4d391dbe2783a6142b92c775288a54257113831b5d3152bc055479fa70a66592a6017f483015494f5cf1f98143b3ab1ee37a529515fc"},
+    }
+    for _, test := range tests {
+        DK, err := PBKDF1([]byte("password"), salt, 1000, test.keyLen, test.h)
+        if err != nil {
+            t.Errorf("\nTest: PBKDF1-%s\nError: %s\n", test.h, err)
+            continue
+        }
+        result := hex.EncodeToString(DK)
+        if result != test.expected {
+            t.Errorf("\nTest: PBKDF1-%s\nResult:   %s\nExpected: %s\n", test.h, result, test.expected)
+        }
+    }
+    // Keys longer than the hash are not allowed, nor counts and lengths
+    // below 1
+    for _, params := range [][2]int{{1000, 21}, {0, 16}, {-1, 16}, {1000, 0}, {1000, -1}} {
+        if _, err := PBKDF1([]byte("password"), salt, params[0], params[1], HashSHA1); err == nil {
+            t.Errorf("\nTest: iter=%d keyLen=%d\nExpected an error\n", params[0], params[1])
+        }
+    }
+}
+
+func TestEVPBytesToKey(t *testing.T) {
+    // Expected: from openssl enc -aes-256-cbc -md sha512 -pass pass:password -S 0102030405060708 -P
+    salt, _ := hex.DecodeString("0102030405060708")
+    key, iv, err := EVPBytesToKey(HashSHA512, salt, []byte("password"), 1, 32, 16)
+    expectedKey := "73035584eb80000d683e7f822fec12d5fd2d3d9ada71eccdd32614d31a98a2b9"
+    expectedIV := "d740143da8110bb38e14268535d3f4e4"
+    if err != nil || hex.EncodeToString(key) != expectedKey || hex.EncodeToString(iv) != expectedIV {
+        t.Errorf("\nResult:   %x %x %v\nExpected: %s %s\n", key, iv, err, expectedKey, expectedIV)
+    }
+    // Input: SHA-1 over several blocks, with 3 iterations per block
+    key, iv, err = EVPBytesToKey(HashSHA1, salt, []byte("password"), 3, 32, 16)
+    expectedKey = "dfcba56b39f1cbadca0874724d50b5acb107bcef6a8141de56f76ca49c907721"
+    expectedIV = "2565d93654b8b9cb6464ed7c033264c3"
+    if err != nil || hex.EncodeToString(key) != expectedKey || hex.EncodeToString(iv) != expectedIV {
+        t.Errorf("\nResult:   %x %x %v\nExpected: %s %s\n", key, iv, err, expectedKey, expectedIV)
+    }
+    // Input: a count below 1 and negative lengths
+    for _, params := range [][3]int{{0, 32, 16}, {1, -1, 16}, {1, 32, -1}} {
+        if _, _, err := EVPBytesToKey(HashSHA1, salt, []byte("password"), params[0], params[1], params[2]); err == nil {
+            t.Errorf("\nTest: count=%d keyLen=%d ivLen=%d\nExpected an error\n", params[0], params[1], params[2])
+        }
+    }
+    if _, _, _, err := ParseSalted(append(append([]byte(nil), SaltedHeader...), salt...), []byte("password"), HashSHA1, 32, -1); err == nil {
+        t.Errorf("\nTest: ParseSalted with ivLen=-1\nExpected an error\n")
+    }
+}
+
+// Files in testdata written by openssl enc, encrypting the line
+// "The quick brown fox jumps over the lazy dog"
+var saltedTests = []struct {
+    file string
+    password string
+    h Hash
+    iter int  // 0 for EVP_BytesToKey
+    keyLen int
+    key string
+    iv string
+}{
+    {"openssl_aes256_sha256.bin", "correct horse", HashSHA256, 0, 32, "9283157254284b9632744a826aa097d5c42ddeeae20651a01bb9db54064e856a", "bee83ceb2dc0b5ebf1aae87576be5f6d"},
+    {"openssl_aes128_sha1.bin", "hunter2", HashSHA1, 0, 16, "0e06dd3d4321ae409b2eba2e27debf07", "bd757d8d87a841439176259e2ef3a7bd"},
+    {"openssl_aes256_pbkdf2_sha512.bin", "secret", HashSHA512, 1000, 32, "a5587c6caecaf6819b0486caaa6ec2da45448f6f279c83ea1df46d2341df9a33", "825b3d47d73730b11fe976b2da674a23"},
+}
+
+func TestParseSalted(t *testing.T) {
+    for _, test := range saltedTests {
+        data, err := os.ReadFile("testdata/" + test.file)
+        if err != nil {
+            t.Fatal(err)
+        }
+        var key, iv, ciphertext []byte
+        if test.iter == 0 {
+            key, iv, ciphertext, err = ParseSalted(data, []byte(test.password), test.h, test.keyLen, 16)
+        } else {
+            key, iv, ciphertext, err = ParseSaltedPBKDF2(data, []byte(test.password), test.h, test.iter, test.keyLen, 16)
+        }
+        if err != nil {
+            t.Errorf("\nTest: %s\nError: %s\n", test.file, err)
+            continue
+        }
+        // Expected: the key and IV printed by openssl enc -d -P
+        if hex.EncodeToString(key) != test.key || hex.EncodeToString(iv) != test.iv {
+            t.Errorf("\nTest: %s\nResult:   %x %x\nExpected: %s %s\n", test.file, key, iv, test.key, test.iv)
+            continue
+        }
+        // The ciphertext should decrypt to the original line
+        block, _ := aes.NewCipher(key)
+        plaintext := make([]byte, len(ciphertext))
+        cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+        plaintext = plaintext[:len(plaintext)-int(plaintext[len(plaintext)-1])]
+        expected := []byte("The quick brown fox jumps over the lazy dog\n")
+        if !bytes.Equal(plaintext, expected) {
+            t.Errorf("\nTest: %s\nResult:   %q\nExpected: %q\n", test.file, plaintext, expected)
+        }
+    }
+}
+
+func TestSplitSalted(t *testing.T) {
+    // Data without the header should be rejected
+    if _, _, err := SplitSalted([]byte("Unsalted0123456789abcdef")); err == nil {
+        t.Errorf("\nExpected an error for data without a Salted__ header\n")
+    }
+    if _, _, err := SplitSalted([]byte("Salted__1234")); err == nil {
+        t.Errorf("\nExpected an error for a truncated salt\n")
+    }
+}
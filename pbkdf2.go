@@ -0,0 +1,69 @@
+package sha
+
+import (
+    "encoding/binary"
+    "errors"
+    "runtime"
+    "sync"
+)
+
+/* PBKDF2 password-based key derivation (RFC 8018) */
+
+func PBKDF2(password []byte, salt []byte, iter int, keyLen int, h Hash) ([]byte, error) {
+    /* Takes a password, a salt, an iteration count and a key length in
+     * bytes, and returns a key derived using PBKDF2 with HMAC over the hash
+     * function h. Each hLen-byte block of the key is independent, so they
+     * are computed in parallel */
+    if iter <= 0 || keyLen <= 0 {
+        return nil, errors.New("sha: PBKDF2 iteration count and key length must be positive")
+    }
+    hLen := h.Size()
+    // RFC 8018 numbers the blocks with 32 bits
+    if uint64(keyLen) > (1<<32 - 1) * uint64(hLen) {
+        return nil, errors.New("sha: PBKDF2 key length is too large")
+    }
+    l := (keyLen + hLen - 1) / hLen  // Number of blocks
+    DK := make([]byte, l*hLen)
+    // The HMAC midstates for the password are shared by all blocks
+    prf := NewHMAC(h, password)
+    workers := runtime.GOMAXPROCS(0)
+    if workers > l {
+        workers = l
+    }
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            m := prf.clone()
+            // Each worker takes every n'th block
+            for i := w; i < l; i += workers {
+                pbkdf2Block(m, salt, iter, uint32(i+1), DK[i*hLen:(i+1)*hLen])
+            }
+        }(w)
+    }
+    wg.Wait()
+    return DK[:keyLen], nil
+}
+
+func pbkdf2Block(m *HMAC, salt []byte, iter int, i uint32, T []byte) {
+    /* Computes block i of the derived key into T:
+     * T_i = U_1 xor U_2 xor ... xor U_c, where U_1 = PRF(P, S || INT(i))
+     * and U_j = PRF(P, U_{j-1}) */
+    var buf [64]byte
+    var INT [4]byte
+    binary.BigEndian.PutUint32(INT[:], i)
+    m.Reset()
+    m.Write(salt)
+    m.Write(INT[:])
+    U := m.Sum(buf[:0])
+    copy(T, U)
+    for j := 1; j < iter; j++ {
+        m.Reset()
+        m.Write(U)
+        U = m.Sum(U[:0])
+        for k := range T {
+            T[k] ^= U[k]
+        }
+    }
+}
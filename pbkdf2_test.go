@@ -0,0 +1,67 @@
+package sha
+
+import (
+    "testing"
+    "encoding/hex"
+)
+
+func TestPBKDF2(t *testing.T) {
+    // Test vectors from RFC 6070 (SHA-1) and RFC 7914 section 11 (SHA-256),
+    // and from Python's hashlib (SHA-512). The RFC 6070 vector with
+    // 16777216 iterations is left out to keep the tests fast
+    tests := []struct {
+        password string
+        salt string
+        iter int
+        keyLen int
+        h Hash
+        expected string
+    }{
+        {"password", "salt", 1, 20, HashSHA1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+        {"password", "salt", 2, 20, HashSHA1, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+        {"password", "salt", 4096, 20, HashSHA1, "4b007901b765489abead49d926f721d065a429c1"},
+        {"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25, HashSHA1, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+        {"pass\x00word", "sa\x00lt", 4096, 16, HashSHA1, "56fa6aa75548099dcc37d7f03425e0c3"},
+        {"passwd", "salt", 1, 64, HashSHA256, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+        {"Password", "NaCl", 80000, 64, HashSHA256, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+        {"password", "salt", 1000, 100, HashSHA512, "afe6c5530785b6cc6b1c6453384731bd5ee432ee549fd42fb6695779ad8a1c5bf59de69c48f774efc4007d5298f9033c0241d5ab69305e7b64eceeb8d834cfec6afdec3c1c23982a121f2d4be008889378a49a0dfb104f0d2856e38f44271cdaf6de4341"},
+    }
+    for _, test := range tests {
+        key, err := PBKDF2([]byte(test.password), []byte(test.salt), test.iter, test.keyLen, test.h)
+        result := hex.EncodeToString(key)
+        if err != nil || result != test.expected {
+            t.Errorf("\nTest: PBKDF2-HMAC-%s(%q, %q, %d, %d)\nResult:   %s %v\nExpected: %s\n", test.h, test.password, test.salt, test.iter, test.keyLen, result, err, test.expected)
+        }
+    }
+}
+
+func TestPBKDF2Parameters(t *testing.T) {
+    // Iteration counts and key lengths which are not positive, or too long
+    // for 32-bit block numbers, should be rejected
+    tests := []struct {
+        iter int
+        keyLen int
+    }{
+        {0, 32},
+        {-1, 32},
+        {1, 0},
+        {1, -1},
+        {1, (1<<32 - 1) * 20 + 1},
+    }
+    for _, test := range tests {
+        if _, err := PBKDF2([]byte("password"), []byte("salt"), test.iter, test.keyLen, HashSHA1); err == nil {
+            t.Errorf("\nTest: PBKDF2(%d, %d)\nExpected an error\n", test.iter, test.keyLen)
+        }
+    }
+}
+
+func TestPBKDF2Allocations(t *testing.T) {
+    // The inner loop should not allocate, so the allocations for 1 and
+    // 1000 iterations should be the same
+    password, salt := []byte("password"), []byte("salt")
+    few := testing.AllocsPerRun(10, func() { PBKDF2(password, salt, 1, 32, HashSHA256) })
+    many := testing.AllocsPerRun(10, func() { PBKDF2(password, salt, 1000, 32, HashSHA256) })
+    if many > few {
+        t.Errorf("\nResult:   %.0f allocations for 1000 iterations\nExpected: %.0f\n", many, few)
+    }
+}
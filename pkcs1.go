@@ -0,0 +1,325 @@
+package sha
+
+import (
+    "bytes"
+    "crypto/subtle"
+    "encoding/asn1"
+    "encoding/binary"
+    "errors"
+)
+
+/* Encoding methods for RSA signatures and encryption (PKCS #1, RFC 8017).
+ * These work on the encoded message EM, which the caller converts to and
+ * from an integer for the RSA operation itself */
+
+// Returned by EMEOAEPDecode for any invalid encoded message, so that the
+// cause of the failure is not revealed
+var ErrOAEPDecode = errors.New("sha: OAEP decryption error")
+
+// Returned for an EMSA-PKCS1-v1_5 encoded message or DigestInfo which is
+// not valid
+var ErrPKCS1Encoding = errors.New("sha: invalid PKCS #1 v1.5 encoding")
+
+// Returned for an EMSA-PKCS1-v1_5 encoded message which a lenient parser
+// would accept, but which has extra bytes where Bleichenbacher's forgery
+// against small public exponents hides its garbage: after the digest, in
+// the algorithm parameters, or in lengths not in their shortest form
+var ErrPKCS1Forgery = errors.New("sha: PKCS #1 v1.5 encoding has the layout of a forged signature")
+
+// Salt length for EMSAPSSVerify which accepts any salt length, recovering
+// it from the encoded message
+const PSSSaltLengthAuto = -1
+
+func MGF1(h Hash, seed []byte, maskLen int) []byte {
+    /* Mask generation function MGF1, which concatenates the hashes of
+     * seed || counter for a 32-bit counter starting at 0, until there are
+     * maskLen bytes */
+    d := h.New()
+    var counter [4]byte
+    mask := make([]byte, 0, maskLen+h.Size())
+    for i := 0; len(mask) < maskLen; i++ {
+        binary.BigEndian.PutUint32(counter[:], uint32(i))
+        d.Reset()
+        d.Write(seed)
+        d.Write(counter[:])
+        mask = d.Sum(mask)
+    }
+    return mask[:maskLen]
+}
+
+func xorMGF1(h Hash, seed []byte, out []byte) {
+    /* Xors out with MGF1(seed, len(out)) */
+    mask := MGF1(h, seed, len(out))
+    for i := range out {
+        out[i] ^= mask[i]
+    }
+}
+
+/* EMSA-PSS (RFC 8017 section 9.1) */
+
+func EMSAPSSEncode(M []byte, emBits int, salt []byte, h Hash, mgfHash Hash) ([]byte, error) {
+    /* Takes a message M, the maximum length of the result in bits (one
+     * less than the RSA modulus length), a random salt, the message hash
+     * function and the hash function for MGF1, and returns the encoded
+     * message EM of ceil(emBits/8) bytes */
+    hLen := h.Size()
+    emLen := (emBits + 7) / 8
+    if emLen < hLen + len(salt) + 2 {
+        return nil, errors.New("sha: PSS encoding error, modulus is too short for the hash and salt")
+    }
+    // H = Hash(0x00 00 00 00 00 00 00 00 || mHash || salt)
+    d := h.New()
+    d.Write(make([]byte, 8))
+    d.Write(h.Sum(M))
+    d.Write(salt)
+    H := d.Sum(nil)
+    // maskedDB = (PS || 0x01 || salt) xor MGF(H), then EM = maskedDB || H || 0xbc
+    EM := make([]byte, emLen)
+    DB := EM[:emLen-hLen-1]
+    DB[len(DB)-len(salt)-1] = 0x01
+    copy(DB[len(DB)-len(salt):], salt)
+    xorMGF1(mgfHash, H, DB)
+    DB[0] &= 0xff >> uint(8*emLen - emBits)
+    copy(EM[emLen-hLen-1:], H)
+    EM[emLen-1] = 0xbc
+    return EM, nil
+}
+
+func EMSAPSSVerify(M []byte, EM []byte, emBits int, sLen int, h Hash, mgfHash Hash) bool {
+    /* Takes a message M, an encoded message EM of ceil(emBits/8) bytes,
+     * the salt length (or PSSSaltLengthAuto), the message hash function and
+     * the hash function for MGF1, and returns whether EM is a valid
+     * encoding of M. If the RSA modulus length is 1 mod 8, the leading zero
+     * byte of the RSA output must be removed before calling this */
+    hLen := h.Size()
+    emLen := (emBits + 7) / 8
+    if len(EM) != emLen || emLen < hLen + 2 || (sLen >= 0 && emLen < hLen + sLen + 2) {
+        return false
+    }
+    if EM[emLen-1] != 0xbc {
+        return false
+    }
+    // The unused leftmost bits of maskedDB must be zero
+    topMask := byte(0xff >> uint(8*emLen - emBits))
+    if EM[0] & ^topMask != 0 {
+        return false
+    }
+    H := EM[emLen-hLen-1:emLen-1]
+    DB := append([]byte(nil), EM[:emLen-hLen-1]...)
+    xorMGF1(mgfHash, H, DB)
+    DB[0] &= topMask
+    // DB = PS || 0x01 || salt, where PS is zeros
+    i := 0
+    for i < len(DB) && DB[i] == 0 {
+        i++
+    }
+    if i == len(DB) || DB[i] != 0x01 {
+        return false
+    }
+    salt := DB[i+1:]
+    if sLen >= 0 && len(salt) != sLen {
+        return false
+    }
+    d := h.New()
+    d.Write(make([]byte, 8))
+    d.Write(h.Sum(M))
+    d.Write(salt)
+    return subtle.ConstantTimeCompare(d.Sum(nil), H) == 1
+}
+
+/* EME-OAEP (RFC 8017 section 7.1) */
+
+func EMEOAEPEncode(M []byte, label []byte, seed []byte, k int, h Hash, mgfHash Hash) ([]byte, error) {
+    /* Takes a message M, an optional label, a random seed of hLen bytes,
+     * the length k of the RSA modulus in bytes, the label hash function and
+     * the hash function for MGF1, and returns the encoded message EM of
+     * k bytes */
+    hLen := h.Size()
+    if len(seed) != hLen {
+        return nil, errors.New("sha: OAEP seed must be the same length as the hash")
+    }
+    if k < 2*hLen + 2 || len(M) > k - 2*hLen - 2 {
+        return nil, errors.New("sha: OAEP message too long")
+    }
+    // EM = 0x00 || maskedSeed || maskedDB, where DB = lHash || PS || 0x01 || M
+    EM := make([]byte, k)
+    maskedSeed := EM[1:1+hLen]
+    DB := EM[1+hLen:]
+    copy(DB, h.Sum(label))
+    DB[len(DB)-len(M)-1] = 0x01
+    copy(DB[len(DB)-len(M):], M)
+    copy(maskedSeed, seed)
+    xorMGF1(mgfHash, maskedSeed, DB)
+    xorMGF1(mgfHash, DB, maskedSeed)
+    return EM, nil
+}
+
+func EMEOAEPDecode(EM []byte, label []byte, h Hash, mgfHash Hash) ([]byte, error) {
+    /* Takes an encoded message EM of k bytes, the label, the label hash
+     * function and the hash function for MGF1, and returns the message M.
+     * All invalid encodings return ErrOAEPDecode after the same amount of
+     * work, so the error does not leak which check failed */
+    hLen := h.Size()
+    k := len(EM)
+    if k < 2*hLen + 2 {
+        return nil, ErrOAEPDecode
+    }
+    seed := append([]byte(nil), EM[1:1+hLen]...)
+    DB := append([]byte(nil), EM[1+hLen:]...)
+    xorMGF1(mgfHash, DB, seed)
+    xorMGF1(mgfHash, seed, DB)
+    // Check Y = 0, lHash' = lHash, and find the 0x01 after PS, without
+    // branching on secret data
+    good := subtle.ConstantTimeByteEq(EM[0], 0)
+    good &= subtle.ConstantTimeCompare(DB[:hLen], h.Sum(label))
+    lookingForIndex, index := 1, 0
+    invalid := 0
+    for i := hLen; i < len(DB); i++ {
+        equals0 := subtle.ConstantTimeByteEq(DB[i], 0)
+        equals1 := subtle.ConstantTimeByteEq(DB[i], 1)
+        index = subtle.ConstantTimeSelect(lookingForIndex & equals1, i, index)
+        lookingForIndex = subtle.ConstantTimeSelect(equals1, 0, lookingForIndex)
+        invalid = subtle.ConstantTimeSelect(lookingForIndex & ^equals0, 1, invalid)
+    }
+    if good & ^invalid & ^lookingForIndex & 1 != 1 {
+        return nil, ErrOAEPDecode
+    }
+    return DB[index+1:], nil
+}
+
+/* EMSA-PKCS1-v1_5 (RFC 8017 section 9.2) */
+
+func DigestInfoPrefix(h Hash) []byte {
+    /* Returns the DER encoding of the DigestInfo for the hash function h,
+     * up to the start of the digest itself */
+    oid, _ := asn1.Marshal(h.OID())
+    algorithm := append(oid, 0x05, 0x00)
+    // SEQUENCE { SEQUENCE { OID, NULL }, OCTET STRING }, where every length
+    // fits in a single byte
+    prefix := []byte{0x30, byte(2 + len(algorithm) + 2 + h.Size()), 0x30, byte(len(algorithm))}
+    prefix = append(prefix, algorithm...)
+    return append(prefix, 0x04, byte(h.Size()))
+}
+
+func DigestInfo(h Hash, digest []byte) []byte {
+    /* Returns the DER encoding of the DigestInfo for a digest made with
+     * the hash function h */
+    return append(DigestInfoPrefix(h), digest...)
+}
+
+func EMSAPKCS1v15Encode(M []byte, emLen int, h Hash) ([]byte, error) {
+    /* Takes a message M, the length of the RSA modulus in bytes and a hash
+     * function, and returns the encoded message
+     * EM = 0x00 || 0x01 || PS || 0x00 || T, where PS is 0xff bytes and T is
+     * the DigestInfo of the hash of M */
+    T := DigestInfo(h, h.Sum(M))
+    if emLen < len(T) + 11 {
+        return nil, errors.New("sha: intended encoded message length too short")
+    }
+    EM := make([]byte, emLen)
+    EM[1] = 0x01
+    for i := 2; i < emLen - len(T) - 1; i++ {
+        EM[i] = 0xff
+    }
+    copy(EM[emLen-len(T):], T)
+    return EM, nil
+}
+
+func VerifyEMSAPKCS1v15(M []byte, EM []byte, h Hash) bool {
+    /* Returns whether EM is the encoding of M, by encoding M again and
+     * comparing, which leaves no room for a lenient parser to be fooled */
+    expected, err := EMSAPKCS1v15Encode(M, len(EM), h)
+    return err == nil && subtle.ConstantTimeCompare(expected, EM) == 1
+}
+
+func readDER(b []byte) (byte, []byte, []byte, bool, bool) {
+    /* Reads one tag-length-value element from the start of b, and returns
+     * its tag, its contents, the bytes after it, whether the length was in
+     * its shortest form (as DER requires) and whether b was long enough */
+    if len(b) < 2 {
+        return 0, nil, nil, false, false
+    }
+    tag, n := b[0], int(b[1])
+    b = b[2:]
+    minimal := true
+    if n & 0x80 != 0 {
+        // Long form, where the low bits give the number of length bytes
+        count := n & 0x7f
+        if count == 0 || count > 4 || len(b) < count {
+            return 0, nil, nil, false, false
+        }
+        n = 0
+        for _, c := range b[:count] {
+            n = n<<8 | int(c)
+        }
+        minimal = n >= 0x80 && b[0] != 0
+        b = b[count:]
+    }
+    if n > len(b) {
+        return 0, nil, nil, false, false
+    }
+    return tag, b[:n], b[n:], minimal, true
+}
+
+func ParseDigestInfo(T []byte) (Hash, []byte, error) {
+    /* Parses a DER DigestInfo, resolving the hash function from its OID,
+     * and returns the hash function and digest. Parameters may be NULL or
+     * absent. If the only problems are trailing data, other parameters or
+     * lengths in long form, the hash function and digest are returned with
+     * ErrPKCS1Forgery */
+    var forged bool
+    tag, body, rest, minimal, ok := readDER(T)
+    if !ok || tag != 0x30 {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    forged = len(rest) > 0 || !minimal
+    tag, algorithm, body, minimal, ok := readDER(body)
+    if !ok || tag != 0x30 {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    forged = forged || !minimal
+    tag, oid, params, minimal, ok := readDER(algorithm)
+    if !ok || tag != 0x06 || len(oid) >= 0x80 {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    forged = forged || !minimal
+    var id asn1.ObjectIdentifier
+    if _, err := asn1.Unmarshal(append([]byte{0x06, byte(len(oid))}, oid...), &id); err != nil {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    h, found := HashFromOID(id)
+    if !found {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    forged = forged || (len(params) > 0 && !bytes.Equal(params, []byte{0x05, 0x00}))
+    tag, digest, rest, minimal, ok := readDER(body)
+    if !ok || tag != 0x04 || len(digest) != h.Size() {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    if forged || len(rest) > 0 || !minimal {
+        return h, digest, ErrPKCS1Forgery
+    }
+    return h, digest, nil
+}
+
+func ParseEMSAPKCS1v15(EM []byte) (Hash, []byte, error) {
+    /* Strictly parses an EMSA-PKCS1-v1_5 encoded message, requiring at
+     * least eight 0xff bytes of padding and a DigestInfo which fills the
+     * rest of EM, and returns the hash function and digest. A layout used
+     * by forged signatures returns ErrPKCS1Forgery, as in ParseDigestInfo */
+    if len(EM) < 11 || EM[0] != 0x00 || EM[1] != 0x01 {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    i := 2
+    for i < len(EM) && EM[i] == 0xff {
+        i++
+    }
+    if i == len(EM) || EM[i] != 0x00 {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    h, digest, err := ParseDigestInfo(EM[i+1:])
+    if err == nil && i - 2 < 8 {
+        return 0, nil, ErrPKCS1Encoding
+    }
+    return h, digest, err
+}
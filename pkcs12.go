@@ -0,0 +1,196 @@
+package sha
+
+import (
+    "crypto/x509/pkix"
+    "encoding/asn1"
+    "errors"
+    "unicode/utf16"
+)
+
+/* Password-based key derivation for PKCS #12 (RFC 7292 appendix B), and the
+ * MAC which protects the integrity of PFX (.p12 & .pfx) files */
+
+// The ID byte of PKCS12KDF, which says what the derived bytes are for
+const (
+    PKCS12KeyID byte = 1   // Encryption key
+    PKCS12IVID byte = 2    // IV
+    PKCS12MACID byte = 3   // MAC key
+)
+
+// Returned by VerifyPFXMac when the password is wrong or the file has been
+// changed
+var ErrPKCS12MAC = errors.New("sha: PKCS #12 MAC verification failed")
+
+// OID of the PKCS #7 data content type
+var oidPKCS7Data = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+
+func BMPString(s string) ([]byte, error) {
+    /* Encodes a password for PKCS12KDF as a BMPString: big-endian UCS-2
+     * followed by two zero bytes. Characters outside the Basic Multilingual
+     * Plane cannot be encoded */
+    output := make([]byte, 0, 2*len(s)+2)
+    for _, r := range s {
+        if utf16.IsSurrogate(r) || r > 0xffff {
+            return nil, errors.New("sha: password cannot be encoded as a BMPString")
+        }
+        output = append(output, byte(r >> 8), byte(r))
+    }
+    return append(output, 0, 0), nil
+}
+
+func pkcs12Fill(b []byte, v int) []byte {
+    /* Returns copies of b concatenated to fill the smallest multiple of v
+     * bytes that holds b, or nothing if b is empty */
+    output := make([]byte, (len(b) + v - 1) / v * v)
+    for i := range output {
+        output[i] = b[i % len(b)]
+    }
+    return output
+}
+
+func PKCS12KDF(password []byte, salt []byte, ID byte, iter int, keyLen int, h Hash) []byte {
+    /* Takes a password encoded with BMPString, a salt, the ID byte, an
+     * iteration count and a key length in bytes, and returns a key derived
+     * using the PKCS #12 KDF over the hash function h */
+    u, v := h.Size(), h.BlockSize()
+    D := make([]byte, v)
+    for i := range D {
+        D[i] = ID
+    }
+    I := append(pkcs12Fill(salt, v), pkcs12Fill(password, v)...)
+    d := h.New()
+    output := make([]byte, 0, keyLen+u)
+    B := make([]byte, v)
+    var A []byte
+    for {
+        // A = H^iter(D || I)
+        d.Reset()
+        d.Write(D)
+        d.Write(I)
+        A = d.Sum(A[:0])
+        for i := 1; i < iter; i++ {
+            d.Reset()
+            d.Write(A)
+            A = d.Sum(A[:0])
+        }
+        output = append(output, A...)
+        if len(output) >= keyLen {
+            return output[:keyLen]
+        }
+        // Set each v-byte block of I to I_j + B + 1 mod 2^(8v), where B is
+        // copies of A
+        for i := range B {
+            B[i] = A[i % u]
+        }
+        for j := 0; j < len(I); j += v {
+            addBytes(I[j:j+v], B)
+            addBytes(I[j:j+v], []byte{1})
+        }
+    }
+}
+
+/* PFX MacData (RFC 7292 section 4) */
+
+// The password integrity check of a PFX file. Parsing it once allows
+// passwords to be tested quickly
+type PFXMac struct {
+    Hash Hash          // Hash function for the KDF and HMAC
+    Salt []byte        // macSalt
+    Iterations int     // KDF iteration count
+    Digest []byte      // Expected HMAC
+    Content []byte     // The authSafe contents covered by the HMAC
+}
+
+// The ASN.1 structure of a PFX file, leaving the contents unparsed
+type pfxASN1 struct {
+    Version int
+    AuthSafe struct {
+        ContentType asn1.ObjectIdentifier
+        Content asn1.RawValue `asn1:"tag:0,explicit,optional"`
+    }
+    MacData struct {
+        Mac struct {
+            Algorithm pkix.AlgorithmIdentifier
+            Digest []byte
+        }
+        MacSalt []byte
+        Iterations int `asn1:"optional,default:1"`
+    } `asn1:"optional"`
+}
+
+func ParsePFXMac(pfx []byte) (*PFXMac, error) {
+    /* Parses the DER encoding of a PFX file as far as is needed to check
+     * its MAC. Files using public-key integrity mode, which have no MAC,
+     * return an error */
+    var p pfxASN1
+    rest, err := asn1.Unmarshal(pfx, &p)
+    if err != nil {
+        return nil, err
+    }
+    if len(rest) > 0 {
+        return nil, errors.New("sha: trailing data after PFX")
+    }
+    if p.Version != 3 {
+        return nil, errors.New("sha: unsupported PFX version")
+    }
+    if !p.AuthSafe.ContentType.Equal(oidPKCS7Data) || p.MacData.Mac.Algorithm.Algorithm == nil {
+        return nil, errors.New("sha: PFX is not protected by a password MAC")
+    }
+    var content []byte
+    if _, err := asn1.Unmarshal(p.AuthSafe.Content.Bytes, &content); err != nil {
+        return nil, err
+    }
+    h, ok := HashFromOID(p.MacData.Mac.Algorithm.Algorithm)
+    if !ok {
+        return nil, errors.New("sha: unsupported PFX MAC hash function")
+    }
+    if len(p.MacData.Mac.Digest) != h.Size() {
+        return nil, errors.New("sha: PFX MAC has the wrong length")
+    }
+    if p.MacData.Iterations < 1 {
+        return nil, errors.New("sha: invalid PFX MAC iteration count")
+    }
+    return &PFXMac{
+        Hash: h,
+        Salt: p.MacData.MacSalt,
+        Iterations: p.MacData.Iterations,
+        Digest: p.MacData.Mac.Digest,
+        Content: content,
+    }, nil
+}
+
+func (m *PFXMac) Verify(password string) bool {
+    /* Checks whether the MAC was made with password. For the empty password
+     * both a BMPString of two zero bytes and an empty string are tried, as
+     * some programs use one and some the other */
+    P, err := BMPString(password)
+    if err != nil {
+        return false
+    }
+    candidates := [][]byte{P}
+    if password == "" {
+        candidates = append(candidates, nil)
+    }
+    for _, P := range candidates {
+        key := PKCS12KDF(P, m.Salt, PKCS12MACID, m.Iterations, m.Hash.Size(), m.Hash)
+        mac := NewHMAC(m.Hash, key)
+        mac.Write(m.Content)
+        if mac.Verify(m.Digest) {
+            return true
+        }
+    }
+    return false
+}
+
+func VerifyPFXMac(pfx []byte, password string) error {
+    /* Checks a password against a PFX file, returning ErrPKCS12MAC if it is
+     * wrong */
+    m, err := ParsePFXMac(pfx)
+    if err != nil {
+        return err
+    }
+    if !m.Verify(password) {
+        return ErrPKCS12MAC
+    }
+    return nil
+}
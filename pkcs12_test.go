@@ -0,0 +1,108 @@
+package sha
+
+import (
+    "testing"
+    "encoding/hex"
+    "os"
+)
+
+func TestBMPString(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"", "0000"},
+        {"smeg", "0073006d006500670000"},
+        {"Sésame", "005300e900730061006d00650000"},
+        {"☃", "26030000"},
+    }
+    for _, test := range tests {
+        output, err := BMPString(test.input)
+        if err != nil || hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %q\nResult:   %x %v\nExpected: %s\n", test.input, output, err, test.expected)
+        }
+    }
+    // Input: a character outside the BMP
+    if _, err := BMPString("\U0001f511"); err == nil {
+        t.Errorf("\nExpected an error for a character outside the BMP\n")
+    }
+}
+
+func TestPKCS12KDF(t *testing.T) {
+    // The smeg and queeg vectors are from the Bouncy Castle tests, and
+    // sesame and the leading zero case from golang.org/x/crypto/pkcs12.
+    // Others are from the PKCS12KDF implementation in OpenSSL 3.0, which
+    // also reproduces these
+    tests := []struct {
+        h Hash
+        password string
+        salt string
+        ID byte
+        iter int
+        expected string
+    }{
+        {HashSHA1, "smeg", "0a58cf64530d823f", PKCS12KeyID, 1, "8aaae6297b6cb04642ab5b077851284eb7128f1a2a7fbca3"},
+        {HashSHA1, "sesame", "ffffffffffffffff", PKCS12KeyID, 2048, "7cd9fd3e2b3be7691a44e3bef0f9ea0fb9b897d4e325d9d1"},
+        {HashSHA1, "queeg", "05dec959acff72f7", PKCS12IVID, 1000, "11dedad7758d4860"},
+        {HashSHA256, "correct horse", "8a3f6ce2a9c01b5e", PKCS12KeyID, 2048, "cd12544704f9a839c5b47ff993a5dfb81aa0d9cafa1b37252edbca0d5002d68c"},
+        {HashSHA256, "Sésame", "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff0011223344556677", PKCS12IVID, 1, "be13bf82ac0a86c52fd4da0a1c94ac7b"},
+        {HashSHA512, "correct horse", "8a3f6ce2a9c01b5e", PKCS12MACID, 1000, "e1676303c372ac7604aebd3ac952a298448e99c5b9dd3247460125d86f29d74e3de39bd25b0ff353080e240888749ab5597336e0d5e9362be07a1da44957c943e2d8867d1c60da20ff23d33e2b05151470b68ff1a0ee53b3fdf0ee202b1994cd55b809d6fd590173f93ca9b09de98c56b908bceff1ed1b347006d293f5b75c1381501425c21095757e7638c037892105f46b398a8048"},
+        {HashSHA256, "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", "01", PKCS12MACID, 3, "3973a899ee9a761c132971cd9ca2c998f65f86fd407b051caeb9e3857911178bdadd38df4edb3527951d960873e8d327156379c16cc4f67ebe168192e13f9640e8cc161e19b5931037b30db50908e14e1a6cff95bb82e7c2f727f50fd0898b72361f05a2"},
+    }
+    for _, test := range tests {
+        P, _ := BMPString(test.password)
+        output := PKCS12KDF(P, mustHex(test.salt), test.ID, test.iter, len(test.expected) / 2, test.h)
+        if hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %s %s\nResult:   %x\nExpected: %s\n", test.h, test.password, output, test.expected)
+        }
+    }
+    // Input: a password which makes I_j start with a zero byte
+    output := PKCS12KDF([]byte{0, 0}, mustHex("f37e05b518324b4b"), PKCS12KeyID, 2048, 24, HashSHA1)
+    expected := "00f759ff47d14dd03665d5943cb3c4a39a2555c02aed66e1"
+    if hex.EncodeToString(output) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+    }
+}
+
+func TestVerifyPFXMac(t *testing.T) {
+    // Files made with `openssl pkcs12 -export`, and the Windows files from
+    // the golang.org/x/crypto/pkcs12 tests. windows_null.p12 uses an empty
+    // password with no BMPString terminator
+    tests := []struct {
+        file string
+        h Hash
+        iter int
+        password string
+    }{
+        {"openssl_sha1.p12", HashSHA1, 2048, "Sésame"},
+        {"openssl_sha256.p12", HashSHA256, 2048, "correct horse"},
+        {"openssl_sha512.p12", HashSHA512, 1000, "correct horse"},
+        {"windows_null.p12", HashSHA1, 1, ""},
+        {"windows_empty.p12", HashSHA1, 2048, ""},
+    }
+    for _, test := range tests {
+        pfx, err := os.ReadFile("testdata/" + test.file)
+        if err != nil {
+            t.Fatal(err)
+        }
+        m, err := ParsePFXMac(pfx)
+        if err != nil {
+            t.Errorf("\nTest: %s\nError: %v\n", test.file, err)
+            continue
+        }
+        if m.Hash != test.h || m.Iterations != test.iter {
+            t.Errorf("\nTest: %s\nResult:   %s %d\nExpected: %s %d\n", test.file, m.Hash, m.Iterations, test.h, test.iter)
+        }
+        if err := VerifyPFXMac(pfx, test.password); err != nil {
+            t.Errorf("\nTest: %s\nError: %v\n", test.file, err)
+        }
+        if err := VerifyPFXMac(pfx, test.password + "x"); err != ErrPKCS12MAC {
+            t.Errorf("\nTest: %s (wrong password)\nResult:   %v\nExpected: %v\n", test.file, err, ErrPKCS12MAC)
+        }
+        // Changing the contents breaks the MAC
+        m.Content[len(m.Content) / 2] ^= 1
+        if m.Verify(test.password) {
+            t.Errorf("\nTest: %s (modified)\nMAC verified\n", test.file)
+        }
+    }
+}
@@ -0,0 +1,373 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/hex"
+    "strings"
+)
+
+func TestMGF1(t *testing.T) {
+    // Expected values from Python's hashlib
+    tests := []struct {
+        h Hash
+        seed string
+        maskLen int
+        expected string
+    }{
+        {HashSHA1, "foo", 3, "1ac907"},
+        {HashSHA1, "foo", 5, "1ac9075cd4"},
+        {HashSHA1, "bar", 5, "bc0c655e01"},
+        {HashSHA1, "bar", 50, "bc0c655e016bc2931d85a2e675181adcef7f581f76df2739da74faac41627be2f7f415c89e983fd0ce80ced9878641cb4876"},
+        {HashSHA256, "bar", 50, "382576a7841021cc28fc4c0948753fb8312090cea942ea4c4e735d10dc724b155f9f6069f289d61daca0cb814502ef04eae1"},
+        {HashSHA512, "bar", 100, "8625c97145f50577911b25359975c8f942487e7aa0167e6db44239680d08547afcef6d3b7080cde5c1d9a8b17acfe7d95b9f8d776c5227e5ddd9801de41840f04afe273c2269e4d0bd4c99e363aeb55281eed2914c8f2826e682db474b73d9121d882cd5"},
+    }
+    for _, test := range tests {
+        result := hex.EncodeToString(MGF1(test.h, []byte(test.seed), test.maskLen))
+        if result != test.expected {
+            t.Errorf("\nResult:   %s\nExpected: %s\n", result, test.expected)
+        }
+    }
+}
+
+// Encoded messages recovered with the public key from RSA-PSS signatures
+// made by OpenSSL 3.0, using a 2048-bit key (emBits = 2047), for the hashes
+// and salt lengths which the PKCS #1 examples below do not use
+var pssTests = []struct {
+    h Hash
+    mgfHash Hash
+    sLen int
+    M string
+    EM string
+}{
+    {HashSHA1, HashSHA1, 20, "5b60593eab4da51bbbebaa4b87f8aacbfa6dc73c2f426ca83b2a69672143bf9e2dee0c4a89",
+        "0472be939932c9d3984ec4e2529947f13f820bb2515f2bb2d4a68aed22cbc3069f9c2f074831336edf74645ed75b36d6eb7cddf0b7ec7ec1ee5c57c8dd167cb1c2b35740d4178ccfc94d2e7fb4808a70619e6534ce94d8938c188c2db6bfcf876492a70d98ff6651950d2bc9df35f87e98b8532286fe5d8d9356d2adfa2c8f9ab5eeea91d9897da90ca1aec09d566d84dc70a3d51ac710221c9b6a229117fae0d7183e1f83c2a6b7c42077dce1bce654cd0671db2cde4458a72cf4b6eb5941a118abe849cd91faa3f83e63e0431fb5986b1ec51daf1a2386d0df07e727b01166b1d34151d31d04e17af869068a88987330dc06a5fdb4c3ecfe11c607695417bc"},
+    {HashSHA256, HashSHA256, 32, "37c0df7dd18a3db5778be23e77968d71323ebb596894934c94c39e097d9afdbf934e83ac76",
+        "465ad7ebdf5739996ff1c86db10d21ad4b7b29a88dc1b2968087494d957d0ae83de6802e6844e0513eb0951e92977b8c741af0bf464afc99ab881b626559b9ad64ec9f61e849b12fad38392614e08567dcc696cc3762cc0a74577f0af3153d8d2225e5c5d66d835140f87d486e20ab5d38e3e1917d3e6fa4fd6c0eb18b102f7e6deadd67b5bdf4dc7ef202a62ef8fd818ca3df84966656b695da378a68ed45d0f1b9d75427ed3e33f4327bb7c8be69b5bf3a1a76caf61a16def7a303ae85d8fd1061663071b15a14a82efd1f4aedfdfc2c81acc62118b19113047343a990a296cd09ddb3cd3e4e5bc3f0c4dafe8d093043f05af8318c24afe1a3548123d142bc"},
+    {HashSHA256, HashSHA1, 20, "b1117e6975316ab88e039aee6293a60109628831c5d48dead3d5facbd19f8ed8e67e7e9db8",
+        "0188589a45f4b187c03a284ab067ef18dfac13ca001afcace070f209ac637584c79e41a6fbb76a39e220187253ab9aacf85d60482ec70665ded6485423840d4b6041660fa61792f5e9e81d64a96245e1c096d95bbb26d7bddd61173fe9d2cd927569023cc19413cd713d0a212255754f2b4582aaf3ee31691c872f477033d1ed9cebdfc2f1607dc0e1ae7316dbbc9705416e40cb18222c3c4676882ed64ce296359f562d7ffb58edcc3d5884def31e41c1394a2ad5da798f6fc897f6c6dbce8ee679e78c2b763185c72a5bf03b9ae122182e8ad78165da565e6b86b62e0500a7548191e6e32f77191b849f62cdec5ce1b5b0cd815cff89e651d2e1eb3d93bebc"},
+    {HashSHA512, HashSHA256, 0, "d0fe42b4195296ae2da0bd9ec520abb710638c1d6fdae9e8552e75a985481294e55e9e2ca4",
+        "29952661daba7443ca0dcc94603e9448162fee15b462cb541d26e71025cb68538e9f5eeda989e0bd1602eae9be7ba44d546b520737f5cbb018bf4cdaf5fe206ac16b59417685a43eba9e10de710fbde4d53b23582482051a59d687c148195c31489b6ee00b7c7c4abece1aca87a48af65f62caa13c96e6b2623ff7ae0ae8b09ecabee81eef656ba1aa9270b20de72901152f31414eb8c836901530cff27b254e3672027c1b8ded653501a421829311097781ccba31694d4ce3502bb9cb026c55b7b8a23e0093f28e051943035a5e896fa8c7758cf7251d4bac33e2fbe170de791576829aace1c76756b0b5002fc781f6b1f5bcb711296edecfdc5b74d5fdd8bc"},
+    {HashSHA384, HashSHA512, 48, "11a04216680f0acf29adda513c949ddc5cf30bedbccc2c944b6a568701c6a0d892dcb97547",
+        "4939f040aecf24ec5a266056d52158c664c0a12baa2167cd3f26a54749ff6bba3a7260e4c4f58feed24e0e971d0df406bb1beb51a7a450f7d64e2935a6a1dfbaf399d70fdeca7588391ba4a230481c477a479f2dc7cb2fe4abc42c870816db094bf3b47065c0bf882b69b78ae85236e7a6c93e6109a7d43b1228a60f54d4c5835fe07f8638b19e071b448eb2c41976f4be29ce095d566158e40deb53a76ebf84ed014321df1578c5ea92002bc9760daa991de0921488152218f735d0e9298652ba58831cf894a5b15ee90a28068f98951e4e43f4bf8c4e65542ca685ad93924ef4674d177a1310924a733f17eb3538f0076d210060c60c17fe0987d6bd1846bc"},
+    {HashSHA224, HashSHA3_256, 10, "5ade5130295835ab84922ec458dcb84252716f23ea168fc65c42695430983f0063898bee26",
+        "3cdee09a1157fa118b086808652de31a49725a6735359633d11ccd5c42647982a7f717325c64f9dce624bf87b48a2048fefe149935c95a5dda28fdae16ad4bff17faf3489a0a9377c114648dfa8b94b647c123f1699e6244945a7dfb8e4029363c750cf71cefeac96ff74b1c1d14dfd9f409977cc20008f1eb36ba4dac2a61454602dbe12d24ca51ef9937a6ee3f127d555e463acb61d743be872fa8d3878c1b68ea97dccec4638328592078cce1986f0d4c3dda562192339e9c0aefcc0961bffb2f5c7a141d86682054867e8df61f6ade8b3847f2c0f7ce6d493465d5ca27b24ba25e7e9e5ea1a61a2ed3901e15f39607a41839e119ba8981250c77804e91bc"},
+}
+
+// Encoded messages recovered with the private key from RSA-OAEP ciphertexts
+// made by OpenSSL 3.0, using a 2048-bit key, for the hashes and labels
+// which the PKCS #1 examples below do not use
+var oaepTests = []struct {
+    h Hash
+    mgfHash Hash
+    label string
+    M string
+    EM string
+}{
+    {HashSHA1, HashSHA1, "", "53741cf04ed9adb4349bb69701e86a4c76e7551c56a63215ab77f2531c",
+        "00d83b3a77213a92a1c2c72a76ae7d40cb7e18179286ce1d6a3e8b6cb46eb78c38a53fc215a2f00066f7a8a5c6530546e409133c8067c1ca934e3a3695e3eaab16ecdd4fd6c5edfbcd619847f6b054f4c1b71803b7846b71230a46a9865dcc616bb342480d0e5aa309d9c3c279de164b2074b660ed0a9907da1501760a6f2c8ebd014a12cd0d6ebef96c0928bb01f7f1d1d56ae17ec70bd4b5de66187667256092ee2f496f8ee7ddb2377b14491ca398261241104afcb93836636bf7507af37e8708678e30462b508b0d0d656ff301169bea5b1fba46e09fb25cfc5b6cc2106b2cf17afad918af7f16f429e7a9752234428aa81cafa544dc18eeee930ea3c1db"},
+    {HashSHA256, HashSHA256, "6c6162656c", "14e6af0eb1d430a4c57c8f7474a70e21353a6c028a5a38cd85bdb5046b",
+        "00331a1c2b06f85a996f444edf51b95e3606f762b256b7956e75b828484765e38a196776147009e004a4e3dda0df362043556d6971ca531eb3956dbde0f5194119a87dfd69ed5b102ef44204ea84b4f68cdeec3370225bdb7f120c926ac576c0dac5ce933890ba405fc0448a4c8587e93d58488ab5d77dc323b4bb2067ace71162f2e3e96641c43b5d7d9fe33c05d98da01c03de43b6b18b256b4339630a0c54e6c3bfc63e7d5c92917ec067ed6cfdcbb1aee270bf4605c9f1bd49a3d6ab4d67502e1fe0a38312df31d1866a4013deb96ab9e69494717ddfeb36ffc3157881809c6f4056b1c74a7e58e9f5467e7710e8631f7fc60788d8bcb80b1050669fcd3f"},
+    {HashSHA256, HashSHA1, "", "300c0d97e7984afa600730b694d529599ec03e552072153a094b65e512",
+        "0057090382ffc53244df14a88f914e31c429ade203134e1a4eefcae2bff06a2bc5087d9e3ac54e2961655c7937059265fcc673a0f75f793f0dceef2b9f3f1e994adf8b5d1b5b515c03e1fe5cbdfe225b405ed566aee78abf650a8139226c7ba8898ab4903381473e05275da6157d9cc3cce8a55c79bac08d1b17d7602f65c1019eeb413742d912d649599d42e7c88dba3204aa4e108153af2f6c2e419f6f41aa9f8aed399bbb74fcaaf6a271200516c8e9c7e66ec4b73f73c35559ce89c4c31b6ead4a8bcbe41c04321ed5610b32b8e3d6e401261187c2f3d2721a1b805b429f60f58511e60c850556f7e91300fa408ed663140d7546b08e34b03dbd03422b3f"},
+    {HashSHA512, HashSHA256, "0102030405", "43c979ef111ffb951a17e38c29ed1807270c79adc6b041f2f3df3382e1",
+        "009593453563290334ccc01741acd732259189d7ae254472d2947c6329cc66d5a8bd0b18ed929e6390a645a95afc68df863a41b37af4458f59194858ba8ba91b008a3c8af8d11fc9432aecfc710f043355591b6d912ae23dffb85e6b21b0b288bca75f10575f968eca8c6d397d9a9483fd7bfe1850510df02805ac17c6d77530d3ef7952bed062c78532ee636f69d7d8ad4011a15389e25de909a341c551d446f785d202648709c15ab02bcce464eefd94ecbc9815de9e44e7b6190bfca6395ccfb5f97d879fdc1929d598653f282628f6d1342cceb86a965dd4a9a3e89ef2d85303147ccf855798c96b65396d73f4d269a84bb609183bf7f43ec3e956a0d3db"},
+    {HashSHA384, HashSHA512, "", "b466354d38d1191796ea8ac719d83d580140d8d1934c276a1b99474134",
+        "00a1f2af63bbe063a95972e4db8b7bbfaf9fe04d8d87e20a4e5efd8cffe5ebb841c2e169cd17bf7d39545db5d0249516da3b92cdec94465f7cd71102d5c8203e9c7c96c72b614d602be6ae85a095f4b8dbba0450dea5972d5f793d134e3b134c4b9dd60b281dceb49cefbb17f09d97640eaae7847c882d95cbc24341db532d5e47e8bf68b4aea48d867a092990c190d1bc02a93cf31feaf039d715a08d54908271a629b2b5633ca3d7412d4b4135d3b22d25d85cbab9ee15c23095c076f34df1c9eb129960ed542041880932a9ad3658b47c8b4f4c4069cffa9cb1bea64268400f76caf7045f6ceff355f5dbc95564fc96dca65ae8ce3b4d89927bbb09ad1f35"},
+}
+
+// RSASSA-PSS examples from the PKCS #1 v2.1 test vectors (pss-vect.txt),
+// one per key, with SHA-1 and MGF1-SHA-1. EM is each signature raised to
+// the public exponent
+var pssVectTests = []struct {
+    example string
+    emBits int
+    M string
+    salt string
+    EM string
+}{
+    {"1.4", 1023, "bc656747fa9eafb3f0", "056f00985de14d8ef5cea9e82f8c27bef720335e",
+        "6ad87ad6e7296cd595396e4e73fa2fc125172ba7100242d8ffd8ba04b9b4f22e63f18a15c00ca7da7b1000bc86925d7fdac3e1d78c1a048c87a56b17f6e48643ad4ee3b5195fc1bc4840a9dcc7951e5879265b83095b5a012b972d48d7642122cd8bc114b50c5f9e74c0d95c239d44da346df02d2d33d3bb70bb31d0513f78bc"},
+    {"2.6", 1024, "049f9154d871ac4a7c7ab45325ba7545a1ed08f70525b2667cf1", "37810def1055ed922b063df798de5d0aabf886ee",
+        "466bc8a9d14b39664e5ed6ae62625f2738e44bf36e570cc385ab0728af2074c05c9d1b0b635be1190fb15a2a8bc38703b337320e7e04da46b87842b9373e9103e5b1aabe9729dd85e645d3f20a7a0413366edd99d1cdfb0ead45682271876761cd59dfd530c454925cba7e85620b6fcd66ed0a162e064e90c649b5bbaf77b5bc"},
+    {"3.6", 1025, "efd237bb098a443aeeb2bf6c3f8c81b8c01b7fcb3feb", "b0de3fc25b65f5af96b1d5cc3b27d0c6053087b3",
+        "00c5fb988ce1fe5c023fa9b7feea332c3c3369c3bea6b0c606b4ff7e884e1ebed7ecf8f8e6d8d1d2f8a3715e2dac4a100dfc407ed435c425e99eccb58f7c4b9ab22e206cbe461f5c91698872dfd7630ae9163c8b15d82ef514dfc5811769d8a5f9d1a5d558f05f76209bfc4e4c6c8f23b3eccb48630b7069d90476f0d295c816bc"},
+    {"4.1", 1026, "9fb03b827c8217d9", "ed7c98c95f30974fbe4fbddcf0f28d6021c0e91d",
+        "0374c696add376aa25514ca7c48fba313ae434d740b4ead074f8aaaee4e5f41cd1c4b3ca592f4623d39a183cf4e871e2dfa5a257d99d36b93e2bf4dc1e0474e29cd037d216efa5ec92a81c2b8c8c1a5d36c0e456dea621e9c34d4da2777281e9224cbd150d4eed5bdc191fdea14130d4c2bc6aff3140fb884a7e1b63d5b3a828bc"},
+    {"5.4", 1027, "328c659e0a6437433cceb73c14", "9aec4a7480d5bbc42920d7ca235db674989c9aac",
+        "07367ac7bca6f5ff7c3b6cb369c1f32d305fabdb962af7f70444ed16d9cfd7e49efdbc88e001ce1577b0036a1c8c257a23384ce5b62b85d2cea253a82868b4cc4e8bfb144f19cda9d64335879c2ccf23fd71438d122561f2457a9cbf7fc00125f98b19e85f4882babc74965cd6d9f5f1879ae40470cd58bf0a3f9b2246df9ae5bc"},
+    {"6.3", 1028, "0f6195d04a6e6fc7e2c9600dbf840c39ea8d4d624fd53507016b0e26858a5e0aecd7ada543ae5c0ab3a62599cba0a54e6bf446e262f989978f9ddf5e9a41", "a87b8aed07d7b8e2daf14ddca4ac68c4d0aabff8",
+        "01171ad0034858586299a5eb71b8dc6aef310ee96200391e39148324b6017e1f21ada796117d56a3eedbd19c78b8681d21da91d018ab3af2aa6013ce6f89299fc7752c504ff075d70b52ac36b57f3425968f80fb1c45ed060cc41a3f76169ac8f3fcc20c98e017d0294bea21ee79438a22e1461fd5bfbb4cd01e990fbe38a8adbc"},
+    {"7.2", 1029, "8d80d2d08dbd19c154df3f14673a14bd03735231f24e86bf153d0e69e74cbff7b1836e664de83f680124370fc0f96c9b65c07a366b644c4ab3", "0c09582266df086310821ba7e18df64dfee6de09",
+        "0d4453bf4ca4e1059a514703a1155338dd5b67b0238604f96c45ea8a6e19eb958e73f0a85ce5041df7fd2120cf8119a6a84249f27a050f8e60a25aeceec77a0d7ae3202587ae821bd2b3f583bb8d5e34074f3db5be8c0edc356a3501a85e33b6b4af57c62b9bde7a6c16e13227c026585ed3e72a7d2f0fd1375bf3ad0f112bfbbc"},
+    {"8.2", 1030, "e2f96eaf0e05e7ba326ecca0ba7fd2f7c02356f3cede9d0faabf4fcc8e60a973e5595fd9ea08", "435c098aa9909eb2377f1248b091b68987ff1838",
+        "3fe7a26361411b399556bfed2e786a47436cb6706ef191907326ef57e00d9d3522c549adb3f1e1281c82cd54fbd02e47b6dc0e89514b01571888abf96a3af1e706fe6ef85ee9194aae20ceff8c1c270785da0b72c965f2aaa9e4b8dd77fbe5037ef304b508b171c461467345ca302dd5874acd95182e695bcdeacf72ebd6fc89bc"},
+    {"9.2", 1535, "c8c9c6af04acda414d227ef23e0820c3732c500dc87275e95b0d095413993c2658bc1d988581ba879c2d201f14cb88ced153a01969a7bf0a7be79c84c1486bc12b3fa6c59871b6827c8ce253ca5fefa8a8c690bf326e8e37cdb96d90a82ebab69f86350e1822e8bd536a2e", "b307c43b4850a8dac2f15f32e37839ef8c5c0e91",
+        "400474130d7921a9bff3cb753395dc1ab955b1c5409fdcd608cb0c8b988941483176e6319401a62a775c0cbf34ce011412896cff1745a5d721b5d29fcb71a550c5a1f5700382aa31197f71fc4806cf927b13f93347f33ecdae399b1ea41d7bb55b6533cb95a6a7f0abb88dd3e4701221b5de033a0d27ea93572be700f9329c2b61555c1bc6067b5130b50dabc5d75a544a758a350b15e5062c35b58c523ad26b05d5e28887455b13c710f921d9990625d16c3cf60353a1d3023d5b4a1bd80cbc"},
+    {"10.1", 2047, "883177e5126b9be2d9a9680327d5370c6f26861f5820c43da67a3ad609", "04e215ee6ff934b9da70d7730c8734abfcecde89",
+        "2605a969da18abc1fef2197a34b9501a213e80aa199f426dab7df73d44251a589f922d1ab90399942e48ba4626d50aac1dede9a93e3fbc00236fa053ee41e228adfc164b0e32d3fa081e5d027893acf10d63db0fd809a2395e77cd4eb76bceba234b09bb23cbd9a200267638261a68a46f2f618c24e1a98e61c19f7939dea9cf68f0e22d954ddb8145f86af8126a3de6b0c7ff991979d3fb7bf0b0bc91ae3c6da4b6bf62f2cebb584a44ccdcdd98dc0bfe39f4ca6d5220c3e44b353080bae6b37e7a85b2794ab4fb4c54f416d4a560fd349de0fec37596a94387ba3194d939a2b3fa2352b3d9ffca743da103c59476e9d939ba79e8171cd2ccdcfd969f1bebbc"},
+}
+
+// RSAES-OAEP examples from the PKCS #1 v2.1 test vectors (oaep-vect.txt),
+// as included in Go's crypto/rsa tests, with SHA-1, MGF1-SHA-1 and an
+// empty label. EM is each ciphertext decrypted with the private key
+var oaepVectTests = []struct {
+    example string
+    k int
+    M string
+    seed string
+    EM string
+}{
+    {"1.1", 128, "6628194e12073db03ba94cda9ef9532397d50dba79b987004afefe34", "18b776ea21069d69776a33e96bad48e1dda0a5ef",
+        "001016e93a98ac3af3a8c48fc01e87bd7000db8bdf691f90e4dbabb03f76003765f55b7ed7a202635ea92feda9465ec40f3e0f64b8b0e444956245c5d5d068cb0b1deca88b2f99ae0df4e80f017c595cdba4c92f2f46f25bdbf352beb93766d24554106f42d9326d59339ca24f392e738c703dc876e402ee16542095b77a6994"},
+    {"1.2", 128, "750c4047f547e8e41411856523298ac9bae245efaf1397fbe56f9dd5", "0cc742ce4a9b7f32f951bcb251efd925fe4fe35f",
+        "00aaae74c8ec3c36065e46ca8e57ab0987fdcd1fa4e7f9d260d54a1b74dca875d8ddff2b74281459676c82aea3a51d3fb4b7fe5cd2f07fd8d9a9b0ce26c1267496f5f64c8f667f5df16838d40362e930c8a1c184976220fdd70335c125451b86813da492c0d3ddfa861ddf0abbf4c056f7a2b03b52f7a5894c69349146d957fb"},
+    {"1.3", 128, "d94ae0832e6445ce42331cb06d531a82b1db4baad30f746dc916df24d4e3c2451fff59a6423eb0e1d02d4fe646cf699dfd818c6e97b051", "2514df4695755a67b288eaf4905c36eec66fd2fd",
+        "000e97f9d579b9907c854849011964fb7631cd51fb8a9d55e5d37b872dad632d6b1c843f6595b6f31aa9433f06467bf8f33545841156915343d7e16d80641445354e937d5e48ece0797b448eab0fc45fc6a171ee37b15551984457e3c3563a5027afa51d1a0a90190d14ed3d93406276a3aa002386ca98b26e0243a7bcb1b2f1"},
+    {"10.1", 256, "8bba6bf82a6c0f86d5f1756e97956870b08953b06b4eb205bc1694ee", "47e1ab7119fee56c95ee5eaad86f40d0aa63bd33",
+        "009aeff546462e50bfec1dc191d5d0ce459069756f33635ad62317ffa3981d2b674ed6e83547e479ca90cef1eb74cba8f36004f73b477b159b4fe4f3b5bda05e51d7c8c674c2b9bd2060c9574e661311f4ad7ffc4c0373f1d987505de434a32db898b0d167d188eb9645219d5222eb107a7faae431705e1a3dc8f47cd936b96a02d951e997199635e49b523fd01e1d4c00cbd551f395202f771007505e1dd48b7b04a82b892fe728e190b71e6d4128571c9bed19c06123db3eea1a4ec645419fc879b98f82b6563b7a2c6280db9b0434a756502306e0b244459dd012ca7198a6300058121e70917b49f6402ee738a6c60bfebd3cd130cdfb11392ab73da9a8ca"},
+}
+
+func TestEMSAPSSVerify(t *testing.T) {
+    for _, test := range pssTests {
+        M, EM := mustHex(test.M), mustHex(test.EM)
+        if !EMSAPSSVerify(M, EM, 2047, test.sLen, test.h, test.mgfHash) {
+            t.Errorf("\nTest: %s with MGF1-%s\nResult:   false\nExpected: true\n", test.h, test.mgfHash)
+        }
+        if !EMSAPSSVerify(M, EM, 2047, PSSSaltLengthAuto, test.h, test.mgfHash) {
+            t.Errorf("\nTest: %s with MGF1-%s, any salt length\nResult:   false\nExpected: true\n", test.h, test.mgfHash)
+        }
+        // Wrong salt length, wrong message, changed EM and wrong MGF hash
+        M[0] ^= 1
+        EM[100] ^= 1
+        mgfHash := HashSHA256
+        if test.mgfHash == HashSHA256 {
+            mgfHash = HashSHA1
+        }
+        if EMSAPSSVerify(mustHex(test.M), mustHex(test.EM), 2047, test.sLen + 1, test.h, test.mgfHash) ||
+            EMSAPSSVerify(M, mustHex(test.EM), 2047, test.sLen, test.h, test.mgfHash) ||
+            EMSAPSSVerify(mustHex(test.M), EM, 2047, test.sLen, test.h, test.mgfHash) ||
+            EMSAPSSVerify(mustHex(test.M), mustHex(test.EM), 2047, test.sLen, test.h, mgfHash) {
+            t.Errorf("\nTest: %s with MGF1-%s, invalid\nResult:   true\nExpected: false\n", test.h, test.mgfHash)
+        }
+    }
+}
+
+func TestEMSAPSSEncode(t *testing.T) {
+    // The salt is recovered from each OpenSSL encoding, which should then
+    // be reproduced exactly
+    for _, test := range pssTests {
+        EM := mustHex(test.EM)
+        hLen := test.h.Size()
+        DB := EM[:len(EM)-hLen-1]
+        xorMGF1(test.mgfHash, EM[len(EM)-hLen-1:len(EM)-1], DB)
+        salt := DB[len(DB)-test.sLen:]
+        result, err := EMSAPSSEncode(mustHex(test.M), 2047, salt, test.h, test.mgfHash)
+        if err != nil || hex.EncodeToString(result) != test.EM {
+            t.Errorf("\nTest: %s with MGF1-%s\nResult:   %x %v\nExpected: %s\n", test.h, test.mgfHash, result, err, test.EM)
+        }
+    }
+    // Odd lengths, where the top bits of EM must be cleared
+    for emBits := 500; emBits < 520; emBits++ {
+        EM, err := EMSAPSSEncode([]byte("abc"), emBits, byteRange(0, 20), HashSHA256, HashSHA1)
+        if err != nil || !EMSAPSSVerify([]byte("abc"), EM, emBits, 20, HashSHA256, HashSHA1) {
+            t.Errorf("\nTest: emBits = %d\nResult:   %x %v\n", emBits, EM, err)
+        }
+    }
+    // emLen must be at least hLen + sLen + 2
+    if _, err := EMSAPSSEncode([]byte("abc"), 8*(64+64+1), byteRange(0, 64), HashSHA512, HashSHA512); err == nil {
+        t.Errorf("Expected an error for a modulus too short for the hash and salt")
+    }
+}
+
+func TestEMSAPSSVectors(t *testing.T) {
+    for _, test := range pssVectTests {
+        M, EM := mustHex(test.M), mustHex(test.EM)
+        result, err := EMSAPSSEncode(M, test.emBits, mustHex(test.salt), HashSHA1, HashSHA1)
+        if err != nil || hex.EncodeToString(result) != test.EM {
+            t.Errorf("\nTest: Example %s\nResult:   %x %v\nExpected: %s\n", test.example, result, err, test.EM)
+        }
+        if !EMSAPSSVerify(M, EM, test.emBits, 20, HashSHA1, HashSHA1) {
+            t.Errorf("\nTest: Example %s\nResult:   false\nExpected: true\n", test.example)
+        }
+        // The MGF1 output unmasks DB = PS || 0x01 || salt
+        DB := EM[:len(EM)-21]
+        xorMGF1(HashSHA1, EM[len(EM)-21:len(EM)-1], DB)
+        DB[0] &= 0xff >> (8*len(EM) - test.emBits)
+        expected := strings.Repeat("00", len(DB)-21) + "01" + test.salt
+        if hex.EncodeToString(DB) != expected {
+            t.Errorf("\nTest: Example %s DB\nResult:   %x\nExpected: %s\n", test.example, DB, expected)
+        }
+    }
+}
+
+func TestEMEOAEPDecode(t *testing.T) {
+    for _, test := range oaepTests {
+        M, err := EMEOAEPDecode(mustHex(test.EM), mustHex(test.label), test.h, test.mgfHash)
+        if err != nil || hex.EncodeToString(M) != test.M {
+            t.Errorf("\nTest: %s with MGF1-%s\nResult:   %x %v\nExpected: %s\n", test.h, test.mgfHash, M, err, test.M)
+        }
+        // Wrong label, changed EM and nonzero first byte
+        EM := mustHex(test.EM)
+        EM[0] = 1
+        if _, err := EMEOAEPDecode(mustHex(test.EM), []byte("x"), test.h, test.mgfHash); err != ErrOAEPDecode {
+            t.Errorf("\nTest: %s wrong label\nResult:   %v\nExpected: %v\n", test.h, err, ErrOAEPDecode)
+        }
+        if _, err := EMEOAEPDecode(EM, mustHex(test.label), test.h, test.mgfHash); err != ErrOAEPDecode {
+            t.Errorf("\nTest: %s nonzero Y\nResult:   %v\nExpected: %v\n", test.h, err, ErrOAEPDecode)
+        }
+        EM[0] = 0
+        EM[200] ^= 1
+        if _, err := EMEOAEPDecode(EM, mustHex(test.label), test.h, test.mgfHash); err != ErrOAEPDecode {
+            t.Errorf("\nTest: %s changed EM\nResult:   %v\nExpected: %v\n", test.h, err, ErrOAEPDecode)
+        }
+    }
+}
+
+func TestEMEOAEPEncode(t *testing.T) {
+    // The seed is recovered from each OpenSSL encoding, which should then
+    // be reproduced exactly
+    for _, test := range oaepTests {
+        EM := mustHex(test.EM)
+        hLen := test.h.Size()
+        seed := EM[1:1+hLen]
+        xorMGF1(test.mgfHash, EM[1+hLen:], seed)
+        result, err := EMEOAEPEncode(mustHex(test.M), mustHex(test.label), seed, 256, test.h, test.mgfHash)
+        if err != nil || hex.EncodeToString(result) != test.EM {
+            t.Errorf("\nTest: %s with MGF1-%s\nResult:   %x %v\nExpected: %s\n", test.h, test.mgfHash, result, err, test.EM)
+        }
+    }
+    // The longest message for SHA-256 and a 2048-bit modulus is
+    // 256 - 2*32 - 2 = 190 bytes
+    M := byteRange(0, 190)
+    EM, err := EMEOAEPEncode(M, nil, byteRange(0, 32), 256, HashSHA256, HashSHA256)
+    if result, _ := EMEOAEPDecode(EM, nil, HashSHA256, HashSHA256); err != nil || !bytes.Equal(result, M) {
+        t.Errorf("\nResult:   %x %v\nExpected: %x\n", result, err, M)
+    }
+    if _, err := EMEOAEPEncode(byteRange(0, 191), nil, byteRange(0, 32), 256, HashSHA256, HashSHA256); err == nil {
+        t.Errorf("Expected an error for a message of 191 bytes")
+    }
+}
+
+func TestEMEOAEPVectors(t *testing.T) {
+    for _, test := range oaepVectTests {
+        result, err := EMEOAEPEncode(mustHex(test.M), nil, mustHex(test.seed), test.k, HashSHA1, HashSHA1)
+        if err != nil || hex.EncodeToString(result) != test.EM {
+            t.Errorf("\nTest: Example %s\nResult:   %x %v\nExpected: %s\n", test.example, result, err, test.EM)
+        }
+        M, err := EMEOAEPDecode(mustHex(test.EM), nil, HashSHA1, HashSHA1)
+        if err != nil || hex.EncodeToString(M) != test.M {
+            t.Errorf("\nTest: Example %s\nResult:   %x %v\nExpected: %s\n", test.example, M, err, test.M)
+        }
+        // The MGF1 output of maskedDB unmasks the seed
+        EM := mustHex(test.EM)
+        seed := EM[1:21]
+        xorMGF1(HashSHA1, EM[21:], seed)
+        if hex.EncodeToString(seed) != test.seed {
+            t.Errorf("\nTest: Example %s seed\nResult:   %x\nExpected: %s\n", test.example, seed, test.seed)
+        }
+    }
+}
+
+// DigestInfo prefixes from RFC 8017 section 9.2 note 1, with the SHA-3
+// object identifiers from NIST
+var digestInfoPrefixes = map[Hash]string{
+    HashSHA1:       "3021300906052b0e03021a05000414",
+    HashSHA224:     "302d300d06096086480165030402040500041c",
+    HashSHA256:     "3031300d060960864801650304020105000420",
+    HashSHA384:     "3041300d060960864801650304020205000430",
+    HashSHA512:     "3051300d060960864801650304020305000440",
+    HashSHA512_224: "302d300d06096086480165030402050500041c",
+    HashSHA512_256: "3031300d060960864801650304020605000420",
+    HashSHA3_224:   "302d300d06096086480165030402070500041c",
+    HashSHA3_256:   "3031300d060960864801650304020805000420",
+    HashSHA3_384:   "3041300d060960864801650304020905000430",
+    HashSHA3_512:   "3051300d060960864801650304020a05000440",
+}
+
+func TestDigestInfoPrefix(t *testing.T) {
+    for h, expected := range digestInfoPrefixes {
+        result := hex.EncodeToString(DigestInfoPrefix(h))
+        if result != expected {
+            t.Errorf("\nTest: %s\nResult:   %s\nExpected: %s\n", h, result, expected)
+        }
+        // Resolving the OID should give back the same hash
+        if found, ok := HashFromOID(h.OID()); !ok || found != h {
+            t.Errorf("\nTest: %s\nResult:   %s %v\nExpected: %s\n", h.OID(), found, ok, h)
+        }
+    }
+    if h, ok := HashFromOID([]int{1, 2, 840, 113549, 2, 5}); ok {
+        t.Errorf("\nTest: MD5 OID\nResult:   %s\nExpected: not found\n", h)
+    }
+}
+
+func TestEMSAPKCS1v15Encode(t *testing.T) {
+    // DigestInfo values for "abc", recovered from RSA signatures made by
+    // OpenSSL 3.0 with a 1024-bit key
+    tests := map[Hash]string{
+        HashSHA1:       "3021300906052b0e03021a05000414a9993e364706816aba3e25717850c26c9cd0d89d",
+        HashSHA224:     "302d300d06096086480165030402040500041c23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7",
+        HashSHA256:     "3031300d060960864801650304020105000420ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+        HashSHA384:     "3041300d060960864801650304020205000430cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7",
+        HashSHA512:     "3051300d060960864801650304020305000440ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+        HashSHA512_224: "302d300d06096086480165030402050500041c4634270f707b6a54daae7530460842e20e37ed265ceee9a43e8924aa",
+        HashSHA512_256: "3031300d06096086480165030402060500042053048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23",
+        HashSHA3_224:   "302d300d06096086480165030402070500041ce642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf",
+        HashSHA3_256:   "3031300d0609608648016503040208050004203a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+        HashSHA3_384:   "3041300d060960864801650304020905000430ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25",
+        HashSHA3_512:   "3051300d060960864801650304020a05000440b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0",
+    }
+    for h, T := range tests {
+        expected := "0001" + strings.Repeat("ff", 128 - 3 - len(T)/2) + "00" + T
+        EM, err := EMSAPKCS1v15Encode([]byte("abc"), 128, h)
+        if err != nil || hex.EncodeToString(EM) != expected {
+            t.Errorf("\nTest: %s\nResult:   %x %v\nExpected: %s\n", h, EM, err, expected)
+        }
+        if !VerifyEMSAPKCS1v15([]byte("abc"), EM, h) || VerifyEMSAPKCS1v15([]byte("abd"), EM, h) {
+            t.Errorf("\nTest: %s\nVerifyEMSAPKCS1v15 gave the wrong result\n", h)
+        }
+        parsed, digest, err := ParseEMSAPKCS1v15(EM)
+        if err != nil || parsed != h || hex.EncodeToString(digest) != T[len(T)-2*h.Size():] {
+            t.Errorf("\nTest: %s\nResult:   %s %x %v\nExpected: %s %s\n", h, parsed, digest, err, h, T[len(T)-2*h.Size():])
+        }
+    }
+    // The encoding needs at least 8 bytes of padding
+    if _, err := EMSAPKCS1v15Encode([]byte("abc"), 51 + 10, HashSHA256); err == nil {
+        t.Errorf("Expected an error for an encoded message length of 61 bytes")
+    }
+}
+
+func TestParseEMSAPKCS1v15(t *testing.T) {
+    digest := strings.Repeat("ab", 32)
+    pad := func(T string) string {
+        /* Pads a hex DigestInfo to 128 bytes */
+        return "0001" + strings.Repeat("ff", 128 - 3 - len(T)/2) + "00" + T
+    }
+    tests := []struct {
+        name string
+        EM string
+        err error
+    }{
+        {"valid", pad("3031300d060960864801650304020105000420" + digest), nil},
+        {"absent parameters", pad("302f300b0609608648016503040201" + "0420" + digest), nil},
+        {"block type 2", "0002" + pad("3031300d060960864801650304020105000420" + digest)[4:], ErrPKCS1Encoding},
+        {"no separator", "0001" + strings.Repeat("ff", 126) + "30", ErrPKCS1Encoding},
+        {"short padding", "0001ffffffffffffff003031300d060960864801650304020105000420" + digest, ErrPKCS1Encoding},
+        {"unknown OID (MD5)", pad("3020300c06082a864886f70d020505000410" + digest[:32]), ErrPKCS1Encoding},
+        {"wrong digest length", pad("3030300d06096086480165030402010500041f" + digest[:62]), ErrPKCS1Encoding},
+        {"truncated", pad("3031300d060960864801650304020105000420" + digest[:62]), ErrPKCS1Encoding},
+        // Bleichenbacher's 2006 forgery: short padding, then garbage after
+        // the DigestInfo which makes a cube root easy to find
+        {"trailing garbage", "0001ffffffff003031300d060960864801650304020105000420" + digest + strings.Repeat("5a", 70), ErrPKCS1Forgery},
+        {"garbage in the DigestInfo", pad("3033300d060960864801650304020105000420" + digest + "0000"), ErrPKCS1Forgery},
+        {"garbage in the parameters", pad("3039301506096086480165030402010408" + strings.Repeat("5a", 8) + "0420" + digest), ErrPKCS1Forgery},
+        {"long form length", pad("308131300d060960864801650304020105000420" + digest), ErrPKCS1Forgery},
+        {"long form digest length", pad("3032300d06096086480165030402010500048120" + digest), ErrPKCS1Forgery},
+    }
+    for _, test := range tests {
+        _, _, err := ParseEMSAPKCS1v15(mustHex(test.EM))
+        if err != test.err {
+            t.Errorf("\nTest: %s\nResult:   %v\nExpected: %v\n", test.name, err, test.err)
+        }
+    }
+}
@@ -0,0 +1,102 @@
+package sha
+
+import (
+    "crypto/ecdsa"
+    "errors"
+    "math/big"
+)
+
+/* Deterministic nonces for DSA and ECDSA (RFC 6979), generated by
+ * HMAC_DRBG seeded with the private key and the message hash */
+
+func Bits2Int(b []byte, qlen int) *big.Int {
+    /* Converts a bit string to an integer, keeping only the leftmost qlen
+     * bits (RFC 6979 section 2.3.2) */
+    v := new(big.Int).SetBytes(b)
+    if len(b)*8 > qlen {
+        v.Rsh(v, uint(len(b)*8 - qlen))
+    }
+    return v
+}
+
+func Int2Octets(x *big.Int, rlen int) []byte {
+    /* Converts a non-negative integer less than 2^(8*rlen) to a big-endian
+     * string of rlen bytes (RFC 6979 section 2.3.3) */
+    return x.FillBytes(make([]byte, rlen))
+}
+
+func Bits2Octets(b []byte, q *big.Int) []byte {
+    /* Converts a bit string to an integer with Bits2Int, reduces it modulo
+     * q, then converts it back to rlen bytes (RFC 6979 section 2.3.4) */
+    z := Bits2Int(b, q.BitLen())
+    if z.Cmp(q) >= 0 {
+        z.Sub(z, q)
+    }
+    return Int2Octets(z, (q.BitLen() + 7) / 8)
+}
+
+// Generates the nonces k for a DSA or ECDSA signature. Next returns the
+// candidate from step h of RFC 6979 section 3.2, and should be called
+// again if k gives r = 0 or s = 0
+type RFC6979 struct {
+    q *big.Int        // Group order
+    drbg *hmacDRBG    // HMAC_DRBG holding K and V
+    T []byte          // Buffer for the candidate k
+}
+
+func NewRFC6979(h Hash, q *big.Int, x *big.Int, h1 []byte) *RFC6979 {
+    /* Takes a hash function for HMAC, the group order q, the private key x
+     * and the message hash h1, and returns a nonce generator. Steps b-g
+     * instantiate HMAC_DRBG with int2octets(x) || bits2octets(h1) */
+    rlen := (q.BitLen() + 7) / 8
+    d := newHMACDRBG(h).(*hmacDRBG)
+    d.reseed(Int2Octets(x, rlen), Bits2Octets(h1, q))
+    return &RFC6979{q: q, drbg: d, T: make([]byte, rlen)}
+}
+
+func (g *RFC6979) Next() *big.Int {
+    /* Returns the next candidate k in the range [1, q-1]. Each HMAC_DRBG
+     * request ends with K = HMAC_K(V || 0x00) and V = HMAC_K(V), as in
+     * step h.3, so later calls continue the sequence */
+    for {
+        g.drbg.generate(g.T, nil, 0)
+        k := Bits2Int(g.T, g.q.BitLen())
+        if k.Sign() > 0 && k.Cmp(g.q) < 0 {
+            return k
+        }
+    }
+}
+
+func RFC6979Nonce(h Hash, q *big.Int, x *big.Int, h1 []byte) *big.Int {
+    /* Returns the first nonce k for the private key x and message hash h1,
+     * modulo the group order q */
+    return NewRFC6979(h, q, x, h1).Next()
+}
+
+func SignECDSA(priv *ecdsa.PrivateKey, h Hash, digest []byte) (*big.Int, *big.Int, error) {
+    /* Signs a message digest, made with the hash function h, using a
+     * crypto/ecdsa private key and the RFC 6979 nonce, and returns the
+     * signature (r, s). The signature can be checked with ecdsa.Verify */
+    if priv == nil || priv.Curve == nil || priv.D == nil {
+        return nil, nil, errors.New("sha: invalid ECDSA private key")
+    }
+    n := priv.Curve.Params().N
+    e := Bits2Int(digest, n.BitLen())
+    g := NewRFC6979(h, n, priv.D, digest)
+    for {
+        k := g.Next()
+        // r = x(kG) mod n, and s = k^-1 (e + r*d) mod n
+        x, _ := priv.Curve.ScalarBaseMult(k.FillBytes(make([]byte, (n.BitLen() + 7) / 8)))
+        r := x.Mod(x, n)
+        if r.Sign() == 0 {
+            continue
+        }
+        s := new(big.Int).Mul(r, priv.D)
+        s.Add(s, e)
+        s.Mul(s, new(big.Int).ModInverse(k, n))
+        s.Mod(s, n)
+        if s.Sign() != 0 {
+            return r, s, nil
+        }
+    }
+}
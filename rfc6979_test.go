@@ -0,0 +1,173 @@
+package sha
+
+import (
+    "testing"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "encoding/hex"
+    "math/big"
+    "strings"
+)
+
+func hexInt(s string) *big.Int {
+    /* Converts a hex string to an integer, panicking if it is invalid */
+    x, ok := new(big.Int).SetString(s, 16)
+    if !ok {
+        panic("invalid hex integer " + s)
+    }
+    return x
+}
+
+// Group orders and private keys from RFC 6979 appendix A.2
+var (
+    dsa1024Q = hexInt("996F967F6C8E388D9E28D01E205FBA957A5698B1")
+    dsa1024X = hexInt("411602CB19A6CCC34494D79D98EF1E7ED5AF25F7")
+    dsa2048Q = hexInt("F2C3119374CE76C9356990B465374A17F23F9ED35089BD969F61C6DDE9998C1F")
+    dsa2048X = hexInt("69C7548C21D0DFEA6B9A51C9EAD4E27C33D3B3F180316E5BCAB92C933F0E4DBC")
+    p224N = elliptic.P224().Params().N
+    p224D = hexInt("F220266E1105BFE3083E03EC7A3A654651F45E37167E88600BF257C1")
+    p256N = elliptic.P256().Params().N
+    p256D = hexInt("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+    p384N = elliptic.P384().Params().N
+    p384D = hexInt("6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5")
+    p521N = elliptic.P521().Params().N
+    p521D = hexInt("0FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538")
+)
+
+// Nonces k from RFC 6979 appendix A.2.1, A.2.2 and A.2.4-A.2.7
+var rfc6979Tests = []struct {
+    q *big.Int
+    x *big.Int
+    h Hash
+    message string
+    k string
+}{
+    {dsa1024Q, dsa1024X, HashSHA1, "sample", "7BDB6B0FF756E1BB5D53583EF979082F9AD5BD5B"},
+    {dsa1024Q, dsa1024X, HashSHA224, "sample", "562097C06782D60C3037BA7BE104774344687649"},
+    {dsa1024Q, dsa1024X, HashSHA256, "sample", "519BA0546D0C39202A7D34D7DFA5E760B318BCFB"},
+    {dsa1024Q, dsa1024X, HashSHA384, "sample", "95897CD7BBB944AA932DBC579C1C09EB6FCFC595"},
+    {dsa1024Q, dsa1024X, HashSHA512, "sample", "9ECE7CA27D0F5A4DD4E556C9DF1D21D28104F8B"},
+    {dsa1024Q, dsa1024X, HashSHA1, "test", "5C842DF4F9E344EE09F056838B42C7A17F4A6433"},
+    {dsa1024Q, dsa1024X, HashSHA224, "test", "4598B8EFC1A53BC8AECD58D1ABBB0C0C71E67297"},
+    {dsa1024Q, dsa1024X, HashSHA256, "test", "5A67592E8128E03A417B0484410FB72C0B630E1A"},
+    {dsa1024Q, dsa1024X, HashSHA384, "test", "220156B761F6CA5E6C9F1B9CF9C24BE25F98CD89"},
+    {dsa1024Q, dsa1024X, HashSHA512, "test", "65D2C2EEB175E370F28C75BFCDC028D22C7DBE9C"},
+    {dsa2048Q, dsa2048X, HashSHA1, "sample", "888FA6F7738A41BDC9846466ABDB8174C0338250AE50CE955CA16230F9CBD53E"},
+    {dsa2048Q, dsa2048X, HashSHA224, "sample", "BC372967702082E1AA4FCE892209F71AE4AD25A6DFD869334E6F153BD0C4D806"},
+    {dsa2048Q, dsa2048X, HashSHA256, "sample", "8926A27C40484216F052F4427CFD5647338B7B3939BC6573AF4333569D597C52"},
+    {dsa2048Q, dsa2048X, HashSHA384, "sample", "C345D5AB3DA0A5BCB7EC8F8FB7A7E96069E03B206371EF7D83E39068EC564920"},
+    {dsa2048Q, dsa2048X, HashSHA512, "sample", "5A12994431785485B3F5F067221517791B85A597B7A9436995C89ED0374668FC"},
+    {dsa2048Q, dsa2048X, HashSHA1, "test", "6EEA486F9D41A037B2C640BC5645694FF8FF4B98D066A25F76BE641CCB24BA4F"},
+    {dsa2048Q, dsa2048X, HashSHA224, "test", "6BD4C05ED74719106223BE33F2D95DA6B3B541DAD7BFBD7AC508213B6DA6670"},
+    {dsa2048Q, dsa2048X, HashSHA256, "test", "1D6CE6DDA1C5D37307839CD03AB0A5CBB18E60D800937D67DFB4479AAC8DEAD7"},
+    {dsa2048Q, dsa2048X, HashSHA384, "test", "206E61F73DBE1B2DC8BE736B22B079E9DACD974DB00EEBBC5B64CAD39CF9F91C"},
+    {dsa2048Q, dsa2048X, HashSHA512, "test", "AFF1651E4CD6036D57AA8B2A05CCF1A9D5A40166340ECBBDC55BE10B568AA0AA"},
+    {p224N, p224D, HashSHA1, "sample", "7EEFADD91110D8DE6C2C470831387C50D3357F7F4D477054B8B426BC"},
+    {p224N, p224D, HashSHA224, "sample", "C1D1F2F10881088301880506805FEB4825FE09ACB6816C36991AA06D"},
+    {p224N, p224D, HashSHA256, "sample", "AD3029E0278F80643DE33917CE6908C70A8FF50A411F06E41DEDFCDC"},
+    {p224N, p224D, HashSHA384, "sample", "52B40F5A9D3D13040F494E83D3906C6079F29981035C7BD51E5CAC40"},
+    {p224N, p224D, HashSHA512, "sample", "9DB103FFEDEDF9CFDBA05184F925400C1653B8501BAB89CEA0FBEC14"},
+    {p224N, p224D, HashSHA1, "test", "2519178F82C3F0E4F87ED5883A4E114E5B7A6E374043D8EFD329C253"},
+    {p224N, p224D, HashSHA224, "test", "DF8B38D40DCA3E077D0AC520BF56B6D565134D9B5F2EAE0D34900524"},
+    {p224N, p224D, HashSHA256, "test", "FF86F57924DA248D6E44E8154EB69F0AE2AEBAEE9931D0B5A969F904"},
+    {p224N, p224D, HashSHA384, "test", "7046742B839478C1B5BD31DB2E862AD868E1A45C863585B5F22BDC2D"},
+    {p224N, p224D, HashSHA512, "test", "E39C2AA4EA6BE2306C72126D40ED77BF9739BB4D6EF2BBB1DCB6169D"},
+    {p256N, p256D, HashSHA1, "sample", "882905F1227FD620FBF2ABF21244F0BA83D0DC3A9103DBBEE43A1FB858109DB4"},
+    {p256N, p256D, HashSHA224, "sample", "103F90EE9DC52E5E7FB5132B7033C63066D194321491862059967C715985D473"},
+    {p256N, p256D, HashSHA256, "sample", "A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60"},
+    {p256N, p256D, HashSHA384, "sample", "9F634B188CEFD98E7EC88B1AA9852D734D0BC272F7D2A47DECC6EBEB375AAD4"},
+    {p256N, p256D, HashSHA512, "sample", "5FA81C63109BADB88C1F367B47DA606DA28CAD69AA22C4FE6AD7DF73A7173AA5"},
+    {p256N, p256D, HashSHA1, "test", "8C9520267C55D6B980DF741E56B4ADEE114D84FBFA2E62137954164028632A2E"},
+    {p256N, p256D, HashSHA224, "test", "669F4426F2688B8BE0DB3A6BD1989BDAEFFF84B649EEB84F3DD26080F667FAA7"},
+    {p256N, p256D, HashSHA256, "test", "D16B6AE827F17175E040871A1C7EC3500192C4C92677336EC2537ACAEE0008E0"},
+    {p256N, p256D, HashSHA384, "test", "16AEFFA357260B04B1DD199693960740066C1A8F3E8EDD79070AA914D361B3B8"},
+    {p256N, p256D, HashSHA512, "test", "6915D11632ACA3C40D5D51C08DAF9C555933819548784480E93499000D9F0B7F"},
+    {p384N, p384D, HashSHA1, "sample", "4471EF7518BB2C7C20F62EAE1C387AD0C5E8E470995DB4ACF694466E6AB096630F29E5938D25106C3C340045A2DB01A7"},
+    {p384N, p384D, HashSHA224, "sample", "A4E4D2F0E729EB786B31FC20AD5D849E304450E0AE8E3E341134A5C1AFA03CAB8083EE4E3C45B06A5899EA56C51B5879"},
+    {p384N, p384D, HashSHA256, "sample", "180AE9F9AEC5438A44BC159A1FCB277C7BE54FA20E7CF404B490650A8ACC414E375572342863C899F9F2EDF9747A9B60"},
+    {p384N, p384D, HashSHA384, "sample", "94ED910D1A099DAD3254E9242AE85ABDE4BA15168EAF0CA87A555FD56D10FBCA2907E3E83BA95368623B8C4686915CF9"},
+    {p384N, p384D, HashSHA512, "sample", "92FC3C7183A883E24216D1141F1A8976C5B0DD797DFA597E3D7B32198BD35331A4E966532593A52980D0E3AAA5E10EC3"},
+    {p384N, p384D, HashSHA1, "test", "66CC2C8F4D303FC962E5FF6A27BD79F84EC812DDAE58CF5243B64A4AD8094D47EC3727F3A3C186C15054492E30698497"},
+    {p384N, p384D, HashSHA224, "test", "18FA39DB95AA5F561F30FA3591DC59C0FA3653A80DAFFA0B48D1A4C6DFCBFF6E3D33BE4DC5EB8886A8ECD093F2935726"},
+    {p384N, p384D, HashSHA256, "test", "CFAC37587532347DC3389FDC98286BBA8C73807285B184C83E62E26C401C0FAA48DD070BA79921A3457ABFF2D630AD7"},
+    {p384N, p384D, HashSHA384, "test", "15EE46A5BF88773ED9123A5AB0807962D193719503C527B031B4C2D225092ADA71F4A459BC0DA98ADB95837DB8312EA"},
+    {p384N, p384D, HashSHA512, "test", "3780C4F67CB15518B6ACAE34C9F83568D2E12E47DEAB6C50A4E4EE5319D1E8CE0E2CC8A136036DC4B9C00E6888F66B6C"},
+    {p521N, p521D, HashSHA1, "sample", "89C071B419E1C2820962321787258469511958E80582E95D8378E0C2CCDB3CB42BEDE42F50E3FA3C71F5A76724281D31D9C89F0F91FC1BE4918DB1C03A5838D0F9"},
+    {p521N, p521D, HashSHA224, "sample", "121415EC2CD7726330A61F7F3FA5DE14BE9436019C4DB8CB4041F3B54CF31BE0493EE3F427FB906393D895A19C9523F3A1D54BB8702BD4AA9C99DAB2597B92113F3"},
+    {p521N, p521D, HashSHA256, "sample", "EDF38AFCAAECAB4383358B34D67C9F2216C8382AAEA44A3DAD5FDC9C32575761793FEF24EB0FC276DFC4F6E3EC476752F043CF01415387470BCBD8678ED2C7E1A0"},
+    {p521N, p521D, HashSHA384, "sample", "1546A108BC23A15D6F21872F7DED661FA8431DDBD922D0DCDB77CC878C8553FFAD064C95A920A750AC9137E527390D2D92F153E66196966EA554D9ADFCB109C4211"},
+    {p521N, p521D, HashSHA512, "sample", "1DAE2EA071F8110DC26882D4D5EAE0621A3256FC8847FB9022E2B7D28E6F10198B1574FDD03A9053C08A1854A168AA5A57470EC97DD5CE090124EF52A2F7ECBFFD3"},
+    {p521N, p521D, HashSHA1, "test", "BB9F2BF4FE1038CCF4DABD7139A56F6FD8BB1386561BD3C6A4FC818B20DF5DDBA80795A947107A1AB9D12DAA615B1ADE4F7A9DC05E8E6311150F47F5C57CE8B222"},
+    {p521N, p521D, HashSHA224, "test", "40D09FCF3C8A5F62CF4FB223CBBB2B9937F6B0577C27020A99602C25A01136987E452988781484EDBBCF1C47E554E7FC901BC3085E5206D9F619CFF07E73D6F706"},
+    {p521N, p521D, HashSHA256, "test", "1DE74955EFAABC4C4F17F8E84D881D1310B5392D7700275F82F145C61E843841AF09035BF7A6210F5A431A6A9E81C9323354A9E69135D44EBD2FCAA7731B909258"},
+    {p521N, p521D, HashSHA384, "test", "1F1FC4A349A7DA9A9E116BFDD055DC08E78252FF8E23AC276AC88B1770AE0B5DCEB1ED14A4916B769A523CE1E90BA22846AF11DF8B300C38818F713DADD85DE0C88"},
+    {p521N, p521D, HashSHA512, "test", "16200813020EC986863BEDFC1B121F605C1215645018AEA1A7B215A564DE9EB1B38A67AA1128B80CE391C4FB71187654AAA3431027BFC7F395766CA988C964DC56D"},
+}
+
+func TestBits2Octets(t *testing.T) {
+    // Example from RFC 6979 appendix A.1.2, with a 163-bit q
+    q := hexInt("4000000000000000000020108A2E0CC0D99F8A5EF")
+    h1 := SHA256([]byte("sample"))
+    result := hex.EncodeToString(Bits2Octets(h1[:], q))
+    expected := "01795edf0d54db760f156d0dac04c0322b3a204224"
+    if result != expected {
+        t.Errorf("\nResult:   %s\nExpected: %s\n", result, expected)
+    }
+    if result := Bits2Int(h1[:], 163).Text(16); result != "5795edf0d54db760f156f0eb4a7a0fe38d418e813" {
+        t.Errorf("\nResult:   %s\nExpected: 5795edf0d54db760f156f0eb4a7a0fe38d418e813\n", result)
+    }
+}
+
+func TestRFC6979Nonce(t *testing.T) {
+    // Example from RFC 6979 appendix A.1.2
+    q := hexInt("4000000000000000000020108A2E0CC0D99F8A5EF")
+    x := hexInt("09A4D6792295A7F730FC3F2B49CBC0F62E862272F")
+    h1 := SHA256([]byte("sample"))
+    if k := RFC6979Nonce(HashSHA256, q, x, h1[:]); k.Cmp(hexInt("23AF4074C90A02B3FE61D286D5C87F425E6BDD81B")) != 0 {
+        t.Errorf("\nResult:   %X\nExpected: 23AF4074C90A02B3FE61D286D5C87F425E6BDD81B\n", k)
+    }
+    for _, test := range rfc6979Tests {
+        h1 := test.h.Sum([]byte(test.message))
+        k := RFC6979Nonce(test.h, test.q, test.x, h1)
+        if k.Cmp(hexInt(test.k)) != 0 {
+            t.Errorf("\nTest: %d-bit q, %s, %q\nResult:   %X\nExpected: %s\n", test.q.BitLen(), test.h, test.message, k, test.k)
+        }
+    }
+}
+
+func TestSignECDSA(t *testing.T) {
+    // Signatures with SHA-256 from RFC 6979 appendix A.2, and the message
+    // "wv[vnX" whose first candidate k is out of range, from the Go
+    // crypto/ecdsa tests
+    tests := []struct {
+        curve elliptic.Curve
+        d *big.Int
+        message string
+        r string
+        s string
+    }{
+        {elliptic.P224(), p224D, "sample", "61AA3DA010E8E8406C656BC477A7A7189895E7E840CDFE8FF42307BA", "BC814050DAB5D23770879494F9E0A680DC1AF7161991BDE692B10101"},
+        {elliptic.P224(), p224D, "test", "AD04DDE87B84747A243A631EA47A1BA6D1FAA059149AD2440DE6FBA6", "178D49B1AE90E3D8B629BE3DB5683915F4E8C99FDF6E666CF37ADCFD"},
+        {elliptic.P256(), p256D, "sample", "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716", "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"},
+        {elliptic.P256(), p256D, "test", "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367", "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083"},
+        {elliptic.P256(), p256D, "wv[vnX", "EFD9073B652E76DA1B5A019C0E4A2E3FA529B035A6ABB91EF67F0ED7A1F21234", "3DB4706C9D9F4A4FE13BB5E08EF0FAB53A57DBAB2061C83A35FA411C68D2BA33"},
+        {elliptic.P384(), p384D, "sample", "21B13D1E013C7FA1392D03C5F99AF8B30C570C6F98D4EA8E354B63A21D3DAA33BDE1E888E63355D92FA2B3C36D8FB2CD", "F3AA443FB107745BF4BD77CB3891674632068A10CA67E3D45DB2266FA7D1FEEBEFDC63ECCD1AC42EC0CB8668A4FA0AB0"},
+        {elliptic.P384(), p384D, "test", "6D6DEFAC9AB64DABAFE36C6BF510352A4CC27001263638E5B16D9BB51D451559F918EEDAF2293BE5B475CC8F0188636B", "2D46F3BECBCC523D5F1A1256BF0C9B024D879BA9E838144C8BA6BAEB4B53B47D51AB373F9845C0514EEFB14024787265"},
+        {elliptic.P521(), p521D, "sample", "1511BB4D675114FE266FC4372B87682BAECC01D3CC62CF2303C92B3526012659D16876E25C7C1E57648F23B73564D67F61C6F14D527D54972810421E7D87589E1A7", "04A171143A83163D6DF460AAF61522695F207A58B95C0644D87E52AA1A347916E4F7A72930B1BC06DBE22CE3F58264AFD23704CBB63B29B931F7DE6C9D949A7ECFC"},
+        {elliptic.P521(), p521D, "test", "00E871C4A14F993C6C7369501900C4BC1E9C7B0B4BA44E04868B30B41D8071042EB28C4C250411D0CE08CD197E4188EA4876F279F90B3D8D74A3C76E6F1E4656AA8", "0CD52DBAA33B063C3A6CD8058A1FB0A46A4754B034FCC644766CA14DA8CA5CA9FDE00E88C1AD60CCBA759025299079D7A427EC3CC5B619BFBC828E7769BCD694E86"},
+    }
+    for _, test := range tests {
+        priv := &ecdsa.PrivateKey{D: test.d}
+        priv.Curve = test.curve
+        priv.X, priv.Y = test.curve.ScalarBaseMult(test.d.Bytes())
+        digest := HashSHA256.Sum([]byte(test.message))
+        r, s, err := SignECDSA(priv, HashSHA256, digest)
+        if err != nil || r.Cmp(hexInt(test.r)) != 0 || s.Cmp(hexInt(test.s)) != 0 {
+            t.Errorf("\nTest: %s, %q\nResult:   %X %X %v\nExpected: %s %s\n", test.curve.Params().Name, test.message, r, s, err, strings.TrimLeft(test.r, "0"), strings.TrimLeft(test.s, "0"))
+            continue
+        }
+        if !ecdsa.Verify(&priv.PublicKey, digest, r, s) {
+            t.Errorf("\nTest: %s, %q\nResult:   signature rejected by ecdsa.Verify\n", test.curve.Params().Name, test.message)
+        }
+    }
+}
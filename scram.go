@@ -0,0 +1,388 @@
+package sha
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/base64"
+    "errors"
+    "strconv"
+    "strings"
+)
+
+/* Salted Challenge Response Authentication Mechanism (SCRAM, RFC 5802 and
+ * RFC 7677), the SASL mechanism used by PostgreSQL, MongoDB, Kafka and XMPP.
+ * Usernames and passwords are used as given: callers needing SASLprep
+ * should apply it first */
+
+// Returned when a client's proof or a server's signature is wrong
+var ErrSCRAMAuth = errors.New("sha: SCRAM authentication failed")
+
+// Returned for a SCRAM message which cannot be parsed
+var ErrSCRAMFormat = errors.New("sha: malformed SCRAM message")
+
+// The hash functions with SCRAM mechanisms, in the order to parse names
+var scramHashes = []Hash{HashSHA1, HashSHA224, HashSHA256, HashSHA384, HashSHA512}
+
+// A user's stored SCRAM credentials, which are enough to verify a client
+// but not to impersonate one
+type SCRAMCredentials struct {
+    Hash Hash
+    Salt []byte
+    Iterations int
+    StoredKey []byte     // H(ClientKey)
+    ServerKey []byte
+}
+
+// The client side of a SCRAM exchange. It sets its channel binding when
+// using a -PLUS mechanism, or CBSupported when it could bind but the server
+// offered no -PLUS mechanism
+type SCRAMClient struct {
+    Hash Hash
+    Username string
+    Password string
+    Authzid string       // An authorization identity to act as, if any
+    Nonce string         // Random if empty
+    CBType string        // e.g. "tls-exporter" or "tls-server-end-point"
+    CBData []byte
+    CBSupported bool
+    clientFirstBare string
+    gs2Header string
+    serverSignature []byte
+}
+
+// The server side of a SCRAM exchange. It sets its channel binding when it
+// offers a -PLUS mechanism
+type SCRAMServer struct {
+    Hash Hash
+    // Returns the credentials of a user, or an error if there are none
+    Lookup func(username string) (*SCRAMCredentials, error)
+    Nonce string         // The server's part of the nonce, random if empty
+    CBType string
+    CBData []byte
+    Username string      // The identities the client gave, after ServerFirst
+    Authzid string
+    credentials *SCRAMCredentials
+    gs2Header string
+    clientFirstBare string
+    serverFirst string
+    nonce string
+}
+
+func SCRAMMechanism(h Hash) string {
+    /* Returns the SASL mechanism name for h, e.g. "SCRAM-SHA-256" */
+    return "SCRAM-" + h.String()
+}
+
+func NewSCRAMCredentials(h Hash, password string, salt []byte, iterations int) (*SCRAMCredentials, error) {
+    /* Derives the stored credentials for a password */
+    saltedPassword, err := PBKDF2([]byte(password), salt, iterations, h.Size(), h)
+    if err != nil {
+        return nil, err
+    }
+    return &SCRAMCredentials{
+        Hash: h,
+        Salt: salt,
+        Iterations: iterations,
+        StoredKey: h.Sum(HMACSum(h, saltedPassword, []byte("Client Key"))),
+        ServerKey: HMACSum(h, saltedPassword, []byte("Server Key")),
+    }, nil
+}
+
+func (c *SCRAMCredentials) String() string {
+    /* Returns the credentials in the form of RFC 5803 and PostgreSQL,
+     * "SCRAM-SHA-256$iterations:salt$StoredKey:ServerKey" in base64 */
+    b64 := base64.StdEncoding.EncodeToString
+    return SCRAMMechanism(c.Hash) + "$" + strconv.Itoa(c.Iterations) + ":" + b64(c.Salt) + "$" + b64(c.StoredKey) + ":" + b64(c.ServerKey)
+}
+
+func ParseSCRAMCredentials(s string) (*SCRAMCredentials, error) {
+    /* Parses credentials in the form returned by String */
+    err := errors.New("sha: malformed SCRAM credentials")
+    fields := strings.Split(s, "$")
+    if len(fields) != 3 {
+        return nil, err
+    }
+    c := &SCRAMCredentials{}
+    found := false
+    for _, h := range scramHashes {
+        if fields[0] == SCRAMMechanism(h) {
+            c.Hash, found = h, true
+        }
+    }
+    iterations, salt, ok1 := strings.Cut(fields[1], ":")
+    storedKey, serverKey, ok2 := strings.Cut(fields[2], ":")
+    if !found || !ok1 || !ok2 {
+        return nil, err
+    }
+    var errs [4]error
+    c.Iterations, errs[0] = strconv.Atoi(iterations)
+    c.Salt, errs[1] = base64.StdEncoding.DecodeString(salt)
+    c.StoredKey, errs[2] = base64.StdEncoding.DecodeString(storedKey)
+    c.ServerKey, errs[3] = base64.StdEncoding.DecodeString(serverKey)
+    if errors.Join(errs[:]...) != nil || c.Iterations <= 0 || len(c.StoredKey) != c.Hash.Size() || len(c.ServerKey) != c.Hash.Size() {
+        return nil, err
+    }
+    return c, nil
+}
+
+/* Messages */
+
+func scramNonce() (string, error) {
+    /* Returns 24 random printable characters, none of them a comma */
+    b := make([]byte, 18)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func scramEscape(name string) string {
+    /* Escapes a username or authzid as a saslname */
+    return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(name)
+}
+
+func scramUnescape(name string) (string, bool) {
+    /* Decodes a saslname, in which '=' may only start =2C or =3D */
+    if strings.Count(name, "=") != strings.Count(name, "=2C") + strings.Count(name, "=3D") {
+        return "", false
+    }
+    return strings.NewReplacer("=2C", ",", "=3D", "=").Replace(name), true
+}
+
+func scramAttributes(message string, names string) ([]string, error) {
+    /* Splits a message into the values of attributes with the given
+     * single-letter names, in order. Further attributes are extensions, which
+     * are ignored unless they are mandatory */
+    fields := strings.Split(message, ",")
+    if len(fields) < len(names) {
+        return nil, ErrSCRAMFormat
+    }
+    values := make([]string, len(names))
+    for i, field := range fields {
+        if len(field) < 2 || field[1] != '=' {
+            return nil, ErrSCRAMFormat
+        }
+        if i < len(names) {
+            if field[0] != names[i] {
+                return nil, ErrSCRAMFormat
+            }
+            values[i] = field[2:]
+        } else if field[0] == 'm' {
+            return nil, errors.New("sha: unsupported mandatory SCRAM extension")
+        }
+    }
+    return values, nil
+}
+
+func scramProof(h Hash, key []byte, storedKey []byte, authMessage string) []byte {
+    /* Returns key xor the ClientSignature HMAC(StoredKey, AuthMessage): the
+     * ClientProof from the ClientKey, or the ClientKey from the ClientProof */
+    signature := HMACSum(h, storedKey, []byte(authMessage))
+    for i := range signature {
+        signature[i] ^= key[i]
+    }
+    return signature
+}
+
+/* Client */
+
+func NewSCRAMClient(h Hash, username string, password string) *SCRAMClient {
+    /* Returns a client which authenticates as username with password */
+    return &SCRAMClient{Hash: h, Username: username, Password: password}
+}
+
+func (c *SCRAMClient) ClientFirst() (string, error) {
+    /* Returns the client-first-message, which starts the exchange */
+    if c.Nonce == "" {
+        nonce, err := scramNonce()
+        if err != nil {
+            return "", err
+        }
+        c.Nonce = nonce
+    }
+    // The GS2 header says whether and how the client binds to the channel
+    switch {
+    case c.CBType != "":
+        c.gs2Header = "p=" + c.CBType + ","
+    case c.CBSupported:
+        c.gs2Header = "y,"
+    default:
+        c.gs2Header = "n,"
+    }
+    if c.Authzid != "" {
+        c.gs2Header += "a=" + scramEscape(c.Authzid)
+    }
+    c.gs2Header += ","
+    c.clientFirstBare = "n=" + scramEscape(c.Username) + ",r=" + c.Nonce
+    return c.gs2Header + c.clientFirstBare, nil
+}
+
+func (c *SCRAMClient) ClientFinal(serverFirst string) (string, error) {
+    /* Takes the server-first-message and returns the client-final-message,
+     * with the proof that the client knows the password */
+    if c.gs2Header == "" {
+        return "", errors.New("sha: SCRAM ClientFirst has not been called")
+    }
+    values, err := scramAttributes(serverFirst, "rsi")
+    if err != nil {
+        return "", err
+    }
+    nonce := values[0]
+    salt, err := base64.StdEncoding.DecodeString(values[1])
+    if err != nil {
+        return "", ErrSCRAMFormat
+    }
+    iterations, err := strconv.Atoi(values[2])
+    if err != nil || iterations <= 0 {
+        return "", ErrSCRAMFormat
+    }
+    // The server's nonce must extend the client's
+    if !strings.HasPrefix(nonce, c.Nonce) || len(nonce) == len(c.Nonce) {
+        return "", ErrSCRAMAuth
+    }
+    cbind := []byte(c.gs2Header)
+    if c.CBType != "" {
+        cbind = append(cbind, c.CBData...)
+    }
+    clientFinal := "c=" + base64.StdEncoding.EncodeToString(cbind) + ",r=" + nonce
+    authMessage := c.clientFirstBare + "," + serverFirst + "," + clientFinal
+    h := c.Hash
+    saltedPassword, err := PBKDF2([]byte(c.Password), salt, iterations, h.Size(), h)
+    if err != nil {
+        return "", err
+    }
+    clientKey := HMACSum(h, saltedPassword, []byte("Client Key"))
+    serverKey := HMACSum(h, saltedPassword, []byte("Server Key"))
+    c.serverSignature = HMACSum(h, serverKey, []byte(authMessage))
+    return clientFinal + ",p=" + base64.StdEncoding.EncodeToString(scramProof(h, clientKey, h.Sum(clientKey), authMessage)), nil
+}
+
+func (c *SCRAMClient) VerifyServerFinal(serverFinal string) error {
+    /* Checks the server-final-message, which proves that the server knows
+     * the user's credentials, or returns the error the server sent */
+    if c.serverSignature == nil {
+        return errors.New("sha: SCRAM ClientFinal has not been called")
+    }
+    if e, found := strings.CutPrefix(serverFinal, "e="); found {
+        return errors.New("sha: SCRAM server error: " + strings.SplitN(e, ",", 2)[0])
+    }
+    values, err := scramAttributes(serverFinal, "v")
+    if err != nil {
+        return err
+    }
+    signature, err := base64.StdEncoding.DecodeString(values[0])
+    if err != nil {
+        return ErrSCRAMFormat
+    }
+    if subtle.ConstantTimeCompare(signature, c.serverSignature) != 1 {
+        return ErrSCRAMAuth
+    }
+    return nil
+}
+
+/* Server */
+
+func NewSCRAMServer(h Hash, lookup func(username string) (*SCRAMCredentials, error)) *SCRAMServer {
+    /* Returns a server which finds users' credentials with lookup */
+    return &SCRAMServer{Hash: h, Lookup: lookup}
+}
+
+func (s *SCRAMServer) ServerFirst(clientFirst string) (string, error) {
+    /* Takes the client-first-message and returns the server-first-message,
+     * with the user's salt and iteration count */
+    // GS2 header: the channel binding flag and an optional authzid
+    fields := strings.SplitN(clientFirst, ",", 3)
+    if len(fields) != 3 {
+        return "", ErrSCRAMFormat
+    }
+    switch flag := fields[0]; {
+    case flag == "n":
+    case flag == "y":
+        // The client could bind but thought the server could not, which
+        // may mean a downgrade
+        if s.CBType != "" {
+            return "", errors.New("sha: SCRAM client did not use channel binding which the server supports")
+        }
+    case strings.HasPrefix(flag, "p="):
+        if s.CBType == "" {
+            return "", errors.New("sha: SCRAM channel binding is not supported")
+        }
+        if flag[2:] != s.CBType {
+            return "", errors.New("sha: unsupported SCRAM channel binding type")
+        }
+    default:
+        return "", ErrSCRAMFormat
+    }
+    if fields[1] != "" {
+        authzid, found := strings.CutPrefix(fields[1], "a=")
+        var ok bool
+        if s.Authzid, ok = scramUnescape(authzid); !found || !ok {
+            return "", ErrSCRAMFormat
+        }
+    }
+    s.gs2Header = fields[0] + "," + fields[1] + ","
+    s.clientFirstBare = fields[2]
+    values, err := scramAttributes(s.clientFirstBare, "nr")
+    if err != nil {
+        return "", err
+    }
+    username, ok := scramUnescape(values[0])
+    if !ok || values[1] == "" {
+        return "", ErrSCRAMFormat
+    }
+    s.Username = username
+    if s.credentials, err = s.Lookup(username); err != nil {
+        return "", err
+    }
+    if s.credentials.Hash != s.Hash {
+        return "", errors.New("sha: SCRAM credentials use a different hash function")
+    }
+    if s.Nonce == "" {
+        if s.Nonce, err = scramNonce(); err != nil {
+            return "", err
+        }
+    }
+    s.nonce = values[1] + s.Nonce
+    s.serverFirst = "r=" + s.nonce + ",s=" + base64.StdEncoding.EncodeToString(s.credentials.Salt) + ",i=" + strconv.Itoa(s.credentials.Iterations)
+    return s.serverFirst, nil
+}
+
+func (s *SCRAMServer) ServerFinal(clientFinal string) (string, error) {
+    /* Takes the client-final-message and verifies the client's proof. It
+     * returns the server-final-message to send, which carries the server's
+     * signature on success, or an "e=" error value with a non-nil error */
+    if s.credentials == nil {
+        return "e=other-error", errors.New("sha: SCRAM ServerFirst has not succeeded")
+    }
+    end := strings.LastIndex(clientFinal, ",p=")
+    if end < 0 {
+        return "e=invalid-encoding", ErrSCRAMFormat
+    }
+    withoutProof := clientFinal[:end]
+    values, err := scramAttributes(withoutProof, "cr")
+    if err != nil {
+        return "e=invalid-encoding", err
+    }
+    proof, err := base64.StdEncoding.DecodeString(clientFinal[end+3:])
+    if err != nil || len(proof) != s.Hash.Size() {
+        return "e=invalid-encoding", ErrSCRAMFormat
+    }
+    // The channel binding data follows the GS2 header if the client used it
+    cbind := []byte(s.gs2Header)
+    if strings.HasPrefix(s.gs2Header, "p=") {
+        cbind = append(cbind, s.CBData...)
+    }
+    if values[0] != base64.StdEncoding.EncodeToString(cbind) {
+        return "e=channel-bindings-dont-match", ErrSCRAMAuth
+    }
+    if values[1] != s.nonce {
+        return "e=other-error", ErrSCRAMAuth
+    }
+    authMessage := s.clientFirstBare + "," + s.serverFirst + "," + withoutProof
+    clientKey := scramProof(s.Hash, proof, s.credentials.StoredKey, authMessage)
+    if subtle.ConstantTimeCompare(s.Hash.Sum(clientKey), s.credentials.StoredKey) != 1 {
+        return "e=invalid-proof", ErrSCRAMAuth
+    }
+    signature := HMACSum(s.Hash, s.credentials.ServerKey, []byte(authMessage))
+    return "v=" + base64.StdEncoding.EncodeToString(signature), nil
+}
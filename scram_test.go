@@ -0,0 +1,173 @@
+package sha
+
+import (
+    "testing"
+    "encoding/base64"
+    "errors"
+)
+
+// The example conversations of RFC 5802 section 5 and RFC 7677 section 3,
+// for the user "user" with the password "pencil"
+var scramTests = []struct {
+    h Hash
+    clientNonce string
+    serverNonce string
+    salt string
+    messages [4]string
+}{
+    {HashSHA1, "fyko+d2lbbFgONRv9qkxdawL", "3rfcNHYJY1ZVvWVs7j", "QSXCR+Q6sek8bf92", [4]string{
+        "n,,n=user,r=fyko+d2lbbFgONRv9qkxdawL",
+        "r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096",
+        "c=biws,r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,p=v0X8v3Bz2T0CJGbJQyF0X+HI4Ts=",
+        "v=rmF9pqV8S7suAoZWja4dJRkFsKQ=",
+    }},
+    {HashSHA256, "rOprNGfwEbeRWgbNEkqO", "%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0", "W22ZaJ0SNY7soEsUEjb6gQ==", [4]string{
+        "n,,n=user,r=rOprNGfwEbeRWgbNEkqO",
+        "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+        "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=",
+        "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=",
+    }},
+}
+
+func scramLookup(credentials *SCRAMCredentials) func(string) (*SCRAMCredentials, error) {
+    return func(username string) (*SCRAMCredentials, error) {
+        if username != "user" {
+            return nil, errors.New("unknown user")
+        }
+        return credentials, nil
+    }
+}
+
+func scramExchange(client *SCRAMClient, server *SCRAMServer) ([4]string, error) {
+    /* Runs an exchange, returning the messages up to the first error */
+    var messages [4]string
+    var err error
+    if messages[0], err = client.ClientFirst(); err != nil {
+        return messages, err
+    }
+    if messages[1], err = server.ServerFirst(messages[0]); err != nil {
+        return messages, err
+    }
+    if messages[2], err = client.ClientFinal(messages[1]); err != nil {
+        return messages, err
+    }
+    messages[3], err = server.ServerFinal(messages[2])
+    if err != nil {
+        return messages, err
+    }
+    return messages, client.VerifyServerFinal(messages[3])
+}
+
+func TestSCRAM(t *testing.T) {
+    for _, test := range scramTests {
+        salt, _ := base64.StdEncoding.DecodeString(test.salt)
+        client := NewSCRAMClient(test.h, "user", "pencil")
+        client.Nonce = test.clientNonce
+        credentials, _ := NewSCRAMCredentials(test.h, "pencil", salt, 4096)
+        server := NewSCRAMServer(test.h, scramLookup(credentials))
+        server.Nonce = test.serverNonce
+        messages, err := scramExchange(client, server)
+        if err != nil || messages != test.messages || server.Username != "user" {
+            t.Errorf("\nTest: %s\nResult:   %q %v\nExpected: %q\n", SCRAMMechanism(test.h), messages, err, test.messages)
+        }
+    }
+}
+
+func TestSCRAMCredentials(t *testing.T) {
+    // The example of RFC 5803 section 4, for the RFC 5802 conversation
+    const expected = "SCRAM-SHA-1$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE="
+    salt, _ := base64.StdEncoding.DecodeString("QSXCR+Q6sek8bf92")
+    credentials, err := NewSCRAMCredentials(HashSHA1, "pencil", salt, 4096)
+    if err != nil || credentials.String() != expected {
+        t.Errorf("\nResult:   %v %v\nExpected: %s\n", credentials, err, expected)
+    }
+    if _, err := NewSCRAMCredentials(HashSHA1, "pencil", salt, 0); err == nil {
+        t.Errorf("\nTest: 0 iterations\nExpected an error\n")
+    }
+    c, err := ParseSCRAMCredentials(expected)
+    if err != nil || c.String() != expected || c.Hash != HashSHA1 || c.Iterations != 4096 {
+        t.Errorf("\nResult:   %v %v\nExpected: %s\n", c, err, expected)
+    }
+    for _, s := range []string{
+        "SCRAM-MD5$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+        "SCRAM-SHA-256$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+        "SCRAM-SHA-1$0:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+        "SCRAM-SHA-1$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=",
+        "SCRAM-SHA-1$4096$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+    } {
+        if _, err := ParseSCRAMCredentials(s); err == nil {
+            t.Errorf("\nTest: %s\nExpected an error\n", s)
+        }
+    }
+}
+
+func TestSCRAMOptions(t *testing.T) {
+    credentials, _ := NewSCRAMCredentials(HashSHA256, "pencil", []byte("salt"), 4096)
+    newPair := func(password string) (*SCRAMClient, *SCRAMServer) {
+        return NewSCRAMClient(HashSHA256, "user", password), NewSCRAMServer(HashSHA256, scramLookup(credentials))
+    }
+    // Input: channel binding on both sides, with matching data
+    client, server := newPair("pencil")
+    client.CBType, client.CBData = "tls-exporter", []byte("exported key material")
+    server.CBType, server.CBData = "tls-exporter", []byte("exported key material")
+    if messages, err := scramExchange(client, server); err != nil || messages[0][:16] != "p=tls-exporter,," {
+        t.Errorf("\nTest: channel binding\nResult:   %q %v\n", messages, err)
+    }
+    // Input: channel binding data which differs, as behind a man in the
+    // middle
+    client, server = newPair("pencil")
+    client.CBType, client.CBData = "tls-exporter", []byte("exported key material")
+    server.CBType, server.CBData = "tls-exporter", []byte("other key material")
+    if messages, err := scramExchange(client, server); err != ErrSCRAMAuth || messages[3] != "e=channel-bindings-dont-match" {
+        t.Errorf("\nTest: channel binding mismatch\nResult:   %q %v\n", messages, err)
+    }
+    // Input: a client which could bind, talking to a server which offers
+    // binding (a downgrade), a client binding with a type the server does
+    // not use, and a client binding to a server which cannot
+    for _, test := range []struct {
+        clientType string
+        supported bool
+        serverType string
+    }{
+        {"", true, "tls-exporter"},
+        {"tls-unique", false, "tls-exporter"},
+        {"tls-exporter", false, ""},
+    } {
+        client, server = newPair("pencil")
+        client.CBType, client.CBSupported, server.CBType = test.clientType, test.supported, test.serverType
+        if messages, err := scramExchange(client, server); err == nil || messages[1] != "" {
+            t.Errorf("\nTest: %+v\nResult:   %q %v\n", test, messages, err)
+        }
+    }
+    // Input: "y" without server support for binding, and an authzid and
+    // username which need escaping
+    client, server = newPair("pencil")
+    client.CBSupported, client.Authzid = true, "admin,=x"
+    if messages, err := scramExchange(client, server); err != nil || messages[0][:17] != "y,a=admin=2C=3Dx," || server.Authzid != "admin,=x" {
+        t.Errorf("\nTest: authzid\nResult:   %q %q %v\n", messages, server.Authzid, err)
+    }
+    // Input: a wrong password, which the server reports to the client
+    client, server = newPair("pencil!")
+    if messages, err := scramExchange(client, server); err != ErrSCRAMAuth || messages[3] != "e=invalid-proof" || client.VerifyServerFinal(messages[3]) == nil {
+        t.Errorf("\nTest: wrong password\nResult:   %q %v\n", messages, err)
+    }
+    // Input: a server signature which is wrong, and a server nonce which does
+    // not extend the client's
+    client, server = newPair("pencil")
+    messages, _ := scramExchange(client, server)
+    if err := client.VerifyServerFinal("v=" + base64.StdEncoding.EncodeToString(make([]byte, 32))); err != ErrSCRAMAuth {
+        t.Errorf("\nTest: wrong server signature\nResult:   %v\n", err)
+    }
+    client = NewSCRAMClient(HashSHA256, "user", "pencil")
+    client.ClientFirst()
+    if _, err := client.ClientFinal("r=other" + messages[1][2+len(client.Nonce):]); err != ErrSCRAMAuth {
+        t.Errorf("\nTest: wrong nonce\nResult:   %v\n", err)
+    }
+    // Input: an unknown user, a mandatory extension and malformed messages
+    for _, clientFirst := range []string{"n,,n=admin,r=abc", "n,,m=ext,n=user,r=abc", "n,,n=user", "x,,n=user,r=abc", "n,,n=us=er,r=abc"} {
+        _, server = newPair("pencil")
+        if _, err := server.ServerFirst(clientFirst); err == nil {
+            t.Errorf("\nTest: %s\nExpected an error\n", clientFirst)
+        }
+    }
+}
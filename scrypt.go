@@ -0,0 +1,143 @@
+package sha
+
+import (
+    "encoding/binary"
+    "errors"
+    "math"
+    "math/bits"
+    "runtime"
+    "sync"
+)
+
+/* scrypt password-based key derivation (RFC 7914), built on PBKDF2 with
+ * HMAC-SHA-256 */
+
+func Scrypt(password []byte, salt []byte, N int, r int, p int, keyLen int) ([]byte, error) {
+    /* Takes a password, a salt, the CPU/memory cost N (a power of 2), the
+     * block size r, the parallelization p and a key length in bytes, and
+     * returns a key derived using scrypt. Each of the p lanes needs 128*r*N
+     * bytes of memory, and lanes are mixed in parallel up to GOMAXPROCS */
+    if N <= 1 || N & (N-1) != 0 {
+        return nil, errors.New("sha: scrypt N must be a power of 2 greater than 1")
+    }
+    if r <= 0 || p <= 0 || keyLen <= 0 {
+        return nil, errors.New("sha: scrypt r, p and key length must be positive")
+    }
+    // RFC 7914 requires r*p < 2^30, and the sizes must fit in an int
+    if uint64(r) * uint64(p) >= 1 << 30 || r > math.MaxInt / 128 / p || r > math.MaxInt / 256 || N > math.MaxInt / 128 / r {
+        return nil, errors.New("sha: scrypt parameters are too large")
+    }
+    laneLen := 128 * r
+    B, err := PBKDF2(password, salt, 1, p*laneLen, HashSHA256)
+    if err != nil {
+        return nil, err
+    }
+    workers := runtime.GOMAXPROCS(0)
+    if workers > p {
+        workers = p
+    }
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            // V and the working blocks are reused for each lane
+            V := make([]uint32, 32*r*N)
+            X := make([]uint32, 32*r)
+            Y := make([]uint32, 32*r)
+            // Each worker takes every n'th lane
+            for i := w; i < p; i += workers {
+                roMix(B[i*laneLen:(i+1)*laneLen], N, r, V, X, Y)
+            }
+        }(w)
+    }
+    wg.Wait()
+    return PBKDF2(password, B, 1, keyLen, HashSHA256)
+}
+
+func salsa20(B *[16]uint32, rounds int) {
+    /* The Salsa20 core with an even number of rounds of the permutation,
+     * the input added to the output. scrypt uses Salsa20/8 */
+    x := *B
+    for i := 0; i < rounds; i += 2 {
+        // Column round
+        x[4] ^= bits.RotateLeft32(x[0] + x[12], 7)
+        x[8] ^= bits.RotateLeft32(x[4] + x[0], 9)
+        x[12] ^= bits.RotateLeft32(x[8] + x[4], 13)
+        x[0] ^= bits.RotateLeft32(x[12] + x[8], 18)
+        x[9] ^= bits.RotateLeft32(x[5] + x[1], 7)
+        x[13] ^= bits.RotateLeft32(x[9] + x[5], 9)
+        x[1] ^= bits.RotateLeft32(x[13] + x[9], 13)
+        x[5] ^= bits.RotateLeft32(x[1] + x[13], 18)
+        x[14] ^= bits.RotateLeft32(x[10] + x[6], 7)
+        x[2] ^= bits.RotateLeft32(x[14] + x[10], 9)
+        x[6] ^= bits.RotateLeft32(x[2] + x[14], 13)
+        x[10] ^= bits.RotateLeft32(x[6] + x[2], 18)
+        x[3] ^= bits.RotateLeft32(x[15] + x[11], 7)
+        x[7] ^= bits.RotateLeft32(x[3] + x[15], 9)
+        x[11] ^= bits.RotateLeft32(x[7] + x[3], 13)
+        x[15] ^= bits.RotateLeft32(x[11] + x[7], 18)
+        // Row round
+        x[1] ^= bits.RotateLeft32(x[0] + x[3], 7)
+        x[2] ^= bits.RotateLeft32(x[1] + x[0], 9)
+        x[3] ^= bits.RotateLeft32(x[2] + x[1], 13)
+        x[0] ^= bits.RotateLeft32(x[3] + x[2], 18)
+        x[6] ^= bits.RotateLeft32(x[5] + x[4], 7)
+        x[7] ^= bits.RotateLeft32(x[6] + x[5], 9)
+        x[4] ^= bits.RotateLeft32(x[7] + x[6], 13)
+        x[5] ^= bits.RotateLeft32(x[4] + x[7], 18)
+        x[11] ^= bits.RotateLeft32(x[10] + x[9], 7)
+        x[8] ^= bits.RotateLeft32(x[11] + x[10], 9)
+        x[9] ^= bits.RotateLeft32(x[8] + x[11], 13)
+        x[10] ^= bits.RotateLeft32(x[9] + x[8], 18)
+        x[12] ^= bits.RotateLeft32(x[15] + x[14], 7)
+        x[13] ^= bits.RotateLeft32(x[12] + x[15], 9)
+        x[14] ^= bits.RotateLeft32(x[13] + x[12], 13)
+        x[15] ^= bits.RotateLeft32(x[14] + x[13], 18)
+    }
+    for i := range B {
+        B[i] += x[i]
+    }
+}
+
+func blockMix(B []uint32, Y []uint32, r int) {
+    /* scryptBlockMix: hashes the 2r 64-byte blocks of B in a chain with
+     * Salsa20/8, writing the even outputs then the odd outputs back to B.
+     * Y is scratch space of the same size */
+    var X [16]uint32
+    copy(X[:], B[(2*r-1)*16:])
+    for i := 0; i < 2*r; i++ {
+        for j := range X {
+            X[j] ^= B[i*16+j]
+        }
+        salsa20(&X, 8)
+        // Y_i goes to position i/2, or r + i/2 for odd i
+        copy(Y[(i/2 + (i&1)*r)*16:], X[:])
+    }
+    copy(B, Y)
+}
+
+func roMix(b []byte, N int, r int, V []uint32, X []uint32, Y []uint32) {
+    /* scryptROMix: fills V with N successive block mixes of the lane b,
+     * then mixes in N entries of V chosen by the state, and writes the
+     * result back to b */
+    words := 32 * r
+    for i := range X {
+        X[i] = binary.LittleEndian.Uint32(b[4*i:])
+    }
+    for i := 0; i < N; i++ {
+        copy(V[i*words:], X)
+        blockMix(X, Y, r)
+    }
+    for i := 0; i < N; i++ {
+        // Integerify: the first word of the last 64-byte block, mod N
+        j := int(uint64(X[(2*r-1)*16]) | uint64(X[(2*r-1)*16+1]) << 32) & (N-1)
+        for k, v := range V[j*words:(j+1)*words] {
+            X[k] ^= v
+        }
+        blockMix(X, Y, r)
+    }
+    for i, v := range X {
+        binary.LittleEndian.PutUint32(b[4*i:], v)
+    }
+}
@@ -0,0 +1,57 @@
+package sha
+
+import (
+    "testing"
+    "encoding/hex"
+)
+
+// Test vectors from RFC 7914 section 12
+var scryptTests = []struct {
+    password string
+    salt string
+    N int
+    r int
+    p int
+    expected string
+}{
+    {"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+    {"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+    {"pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+    {"pleaseletmein", "SodiumChloride", 1048576, 8, 1, "2101cb9b6a511aaeaddbbe09cf70f881ec568d574a2ffd4dabe5ee9820adaa478e56fd8f4ba5d09ffa1c6d927c40f4c337304049e8a952fbcbf45c6fa77a41a4"},
+}
+
+func TestScrypt(t *testing.T) {
+    for _, test := range scryptTests {
+        // The last vector needs 1 GiB of memory
+        if test.N > 1 << 16 && testing.Short() {
+            continue
+        }
+        output, err := Scrypt([]byte(test.password), []byte(test.salt), test.N, test.r, test.p, 64)
+        if err != nil || hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %q N=%d\nResult:   %x %v\nExpected: %s\n", test.password, test.N, output, err, test.expected)
+        }
+    }
+}
+
+func TestScryptParameters(t *testing.T) {
+    tests := []struct {
+        N int
+        r int
+        p int
+        keyLen int
+    }{
+        {0, 1, 1, 32},
+        {1, 1, 1, 32},
+        {1000, 1, 1, 32},
+        {16, 0, 1, 32},
+        {16, 1, 0, 32},
+        {16, 1, 1, 0},
+        {16, 1 << 15, 1 << 15, 32},
+        {1 << 62, 8, 1, 32},
+    }
+    for _, test := range tests {
+        if _, err := Scrypt([]byte("password"), []byte("salt"), test.N, test.r, test.p, test.keyLen); err == nil {
+            t.Errorf("\nTest: N=%d r=%d p=%d keyLen=%d\nExpected an error\n", test.N, test.r, test.p, test.keyLen)
+        }
+    }
+}
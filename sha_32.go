@@ -51,6 +51,54 @@ func ParseMessage32(M []byte) [][16]uint32 {
     return blocks
 }
 
+func SHA2Compress32(H *[8]uint32, M [16]uint32) {
+    /* Takes the intermediate hash value, H, and updates it with one
+     * 512-bit message block, M (used for SHA224 & SHA256) */
+    // All additions are automatically performed modulo 2^32
+    var W [64]uint32  // Message schedule
+    var a, b, c, d, e, f, g, h uint32  // Working variables
+    var T1, T2 uint32  // Temporary words
+    // Prepare message schedule
+    for t := 0; t < 64; t++ {
+        if t < 16 {
+            W[t] = M[t]
+        } else {
+            W[t] = SmallSigma1(W[t-2]) + W[t-7] + SmallSigma0(W[t-15]) + W[t-16]
+        }
+    }
+    // Initialize working variables
+    a = H[0]
+    b = H[1]
+    c = H[2]
+    d = H[3]
+    e = H[4]
+    f = H[5]
+    g = H[6]
+    h = H[7]
+    // Manipulate working variables
+    for t := 0; t < 64; t++ {
+        T1 = h + BigSigma1(e) + Ch(e, f, g) + K[t] + W[t]
+        T2 = BigSigma0(a) + Maj(a, b, c)
+        h = g
+        g = f
+        f = e
+        e = d + T1
+        d = c
+        c = b
+        b = a
+        a = T1 + T2
+    }
+    // Compute intermediate hash values
+    H[0] = a + H[0]
+    H[1] = b + H[1]
+    H[2] = c + H[2]
+    H[3] = d + H[3]
+    H[4] = e + H[4]
+    H[5] = f + H[5]
+    H[6] = g + H[6]
+    H[7] = h + H[7]
+}
+
 func SHA2_32(input []byte, H0 [8]uint32) [32]byte {
     /* Takes an input, and the initial hash value, then computes a
      * SHA2 hash using 32-bit words (used for SHA224 & SHA256)
@@ -68,52 +116,8 @@ func SHA2_32(input []byte, H0 [8]uint32) [32]byte {
     var H [8]uint32
     copy(H[:], H0[:])
     /* HASH COMPUTATION */
-    // All additions are automatically performed modulo 2^32
-    var W [64]uint32  // Message schedule
-    var a, b, c, d, e, f, g, h uint32  // Working variables
-    var T1, T2 uint32  // Temporary words
-    N := len(M)  // Number of blocks
-
-    for i := 0; i < N; i++ {
-        // Prepare message schedule
-        for t := 0; t < 64; t++ {
-            if t < 16 {
-                W[t] = M[i][t]
-            } else {
-                W[t] = SmallSigma1(W[t-2]) + W[t-7] + SmallSigma0(W[t-15]) + W[t-16]
-            }
-        }
-        // Initialize working variables
-        a = H[0]
-        b = H[1]
-        c = H[2]
-        d = H[3]
-        e = H[4]
-        f = H[5]
-        g = H[6]
-        h = H[7]
-        // Manipulate working variables
-        for t := 0; t < 64; t++ {
-            T1 = h + BigSigma1(e) + Ch(e, f, g) + K[t] + W[t]
-            T2 = BigSigma0(a) + Maj(a, b, c)
-            h = g
-            g = f
-            f = e
-            e = d + T1
-            d = c
-            c = b
-            b = a
-            a = T1 + T2
-        }
-        // Compute intermediate hash values
-        H[0] = a + H[0]
-        H[1] = b + H[1]
-        H[2] = c + H[2]
-        H[3] = d + H[3]
-        H[4] = e + H[4]
-        H[5] = f + H[5]
-        H[6] = g + H[6]
-        H[7] = h + H[7]
+    for i := 0; i < len(M); i++ {
+        SHA2Compress32(&H, M[i])
     }
     // Combine final H values into the output hash
     var output [32]byte
@@ -157,6 +161,44 @@ func SHA1_K(t int) uint32 {
     }
 }
 
+func SHA1Compress(H *[5]uint32, M [16]uint32) {
+    /* Takes the intermediate hash value, H, and updates it with one
+     * 512-bit message block, M */
+    // All additions are automatically performed modulo 2^32
+    var W [80]uint32  // Message schedule
+    var a, b, c, d, e uint32  // Working variables
+    var T uint32  // Temporary word
+    // Prepare message schedule
+    for t := 0; t < 80; t++ {
+        if t < 16 {
+            W[t] = M[t]
+        } else {
+            W[t] = ROTL(W[t-3] ^ W[t-8] ^ W[t-14] ^ W[t-16], 1)
+        }
+    }
+    // Initialize working variables
+    a = H[0]
+    b = H[1]
+    c = H[2]
+    d = H[3]
+    e = H[4]
+    // Manipulate working variables
+    for t := 0; t < 80; t++ {
+        T = ROTL(a, 5) + F(b, c, d, t) + e + SHA1_K(t) + W[t]
+        e = d
+        d = c
+        c = ROTL(b, 30)
+        b = a
+        a = T
+    }
+    // Compute intermediate hash values
+    H[0] = a + H[0]
+    H[1] = b + H[1]
+    H[2] = c + H[2]
+    H[3] = d + H[3]
+    H[4] = e + H[4]
+}
+
 func SHA1(input []byte) [20]byte {
     /* Takes an input and returns the SHA1 hash */
     /* PREPROCESSING */
@@ -171,42 +213,8 @@ func SHA1(input []byte) [20]byte {
     // Set the initial hash value
     var H = [5]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476, 0xc3d2e1f0}
     /* HASH COMPUTATION */
-    // All additions are automatically performed modulo 2^32
-    var W [80]uint32  // Message schedule
-    var a, b, c, d, e uint32  // Working variables
-    var T uint32  // Temporary word
-    N := len(M)  // Number of blocks
-
-    for i := 0; i < N; i++ {
-        // Prepare message schedule
-        for t := 0; t < 80; t++ {
-            if t < 16 {
-                W[t] = M[i][t]
-            } else {
-                W[t] = ROTL(W[t-3] ^ W[t-8] ^ W[t-14] ^ W[t-16], 1)
-            }
-        }
-        // Initialize working variables
-        a = H[0]
-        b = H[1]
-        c = H[2]
-        d = H[3]
-        e = H[4]
-        // Manipulate working variables
-        for t := 0; t < 80; t++ {
-            T = ROTL(a, 5) + F(b, c, d, t) + e + SHA1_K(t) + W[t]
-            e = d
-            d = c
-            c = ROTL(b, 30)
-            b = a
-            a = T
-        }
-        // Compute intermediate hash values
-        H[0] = a + H[0]
-        H[1] = b + H[1]
-        H[2] = c + H[2]
-        H[3] = d + H[3]
-        H[4] = e + H[4]
+    for i := 0; i < len(M); i++ {
+        SHA1Compress(&H, M[i])
     }
     // Combine final H values into the output hash
     var output [20]byte
@@ -2,9 +2,10 @@ package sha
 
 import (
     "encoding/binary"
+    "strconv"
 )
 
-/* Functions for SHA2 functions with 64-bit words (SHA-384 & SHA-512) */
+/* Functions for SHA2 functions with 64-bit words (SHA-384, SHA-512 & SHA-512/t) */
 
 // SHA-384 & SHA-512 constants
 var K_64 = [80]uint64{0x428a2f98d728ae22, 0x7137449123ef65cd, 0xb5c0fbcfec4d3b2f, 0xe9b5dba58189dbbc, 0x3956c25bf348b538, 0x59f111f1b605d019, 0x923f82a4af194f9b, 0xab1c5ed5da6d8118, 0xd807aa98a3030242, 0x12835b0145706fbe, 0x243185be4ee4b28c, 0x550c7dc3d5ffb4e2, 0x72be5d74f27b896f, 0x80deb1fe3b1696b1, 0x9bdc06a725c71235, 0xc19bf174cf692694, 0xe49b69c19ef14ad2, 0xefbe4786384f25e3, 0x0fc19dc68b8cd5b5, 0x240ca1cc77ac9c65, 0x2de92c6f592b0275, 0x4a7484aa6ea6e483, 0x5cb0a9dcbd41fbd4, 0x76f988da831153b5, 0x983e5152ee66dfab, 0xa831c66d2db43210, 0xb00327c898fb213f, 0xbf597fc7beef0ee4, 0xc6e00bf33da88fc2, 0xd5a79147930aa725, 0x06ca6351e003826f, 0x142929670a0e6e70, 0x27b70a8546d22ffc, 0x2e1b21385c26c926, 0x4d2c6dfc5ac42aed, 0x53380d139d95b3df, 0x650a73548baf63de, 0x766a0abb3c77b2a8, 0x81c2c92e47edaee6, 0x92722c851482353b, 0xa2bfe8a14cf10364, 0xa81a664bbc423001, 0xc24b8b70d0f89791, 0xc76c51a30654be30, 0xd192e819d6ef5218, 0xd69906245565a910, 0xf40e35855771202a, 0x106aa07032bbd1b8, 0x19a4c116b8d2d0c8, 0x1e376c085141ab53, 0x2748774cdf8eeb99, 0x34b0bcb5e19b48a8, 0x391c0cb3c5c95a63, 0x4ed8aa4ae3418acb, 0x5b9cca4f7763e373, 0x682e6ff3d6b2b8a3, 0x748f82ee5defb2fc, 0x78a5636f43172f60, 0x84c87814a1f0ab72, 0x8cc702081a6439ec, 0x90befffa23631e28, 0xa4506cebde82bde9, 0xbef9a3f7b2c67915, 0xc67178f2e372532b, 0xca273eceea26619c, 0xd186b8c721c0c207, 0xeada7dd6cde0eb1e, 0xf57d4f7fee6ed178, 0x06f067aa72176fba, 0x0a637dc5a2c898a6, 0x113f9804bef90dae, 0x1b710b35131c471b, 0x28db77f523047d84, 0x32caab7b40c72493, 0x3c9ebe0a15c9bebc, 0x431d67c49c100d4c, 0x4cc5d4becb3e42b6, 0x597f299cfc657e2a, 0x5fcb6fab3ad6faec, 0x6c44198c4a475817}
@@ -16,7 +17,7 @@ func PadMessage64(M []byte) []byte {
     // Calculate smallest non-negative k such that l + 1 + k = 896 (mod 1024)
     var k int = -1
     for p := 0; k < 0; p++ {
-        k = 896 - l + 1024*p
+        k = 895 - l + 1024*p
     }
     // Extend M to contain space for padding
     M = append(M, make([]uint8, (k + 1 + 128)/8)...)
@@ -26,8 +27,9 @@ func PadMessage64(M []byte) []byte {
         M[i] = 0
     }
     // Add the value of 'l' as a 128-bit big-endian integer
-    binary.BigEndian.PutUint64(M[(l+k+1)/8:], uint64(l >> 64))
-    binary.BigEndian.PutUint64(M[8+(l+k+1)/8:], uint64(l % 0x100000000))
+    // (the length of a slice always fits in the lower 64 bits)
+    binary.BigEndian.PutUint64(M[(l+k+1)/8:], 0)
+    binary.BigEndian.PutUint64(M[8+(l+k+1)/8:], uint64(l))
     return M
 }
 
@@ -52,6 +54,54 @@ func ParseMessage64(M []byte) [][16]uint64 {
     return blocks
 }
 
+func SHA2Compress64(H *[8]uint64, M [16]uint64) {
+    /* Takes the intermediate hash value, H, and updates it with one
+     * 1024-bit message block, M (used for SHA384 & SHA512) */
+    // All additions are automatically performed modulo 2^64
+    var W [80]uint64  // Message schedule
+    var a, b, c, d, e, f, g, h uint64  // Working variables
+    var T1, T2 uint64  // Temporary words
+    // Prepare message schedule
+    for t := 0; t < 80; t++ {
+        if t < 16 {
+            W[t] = M[t]
+        } else {
+            W[t] = SmallSigma1_64(W[t-2]) + W[t-7] + SmallSigma0_64(W[t-15]) + W[t-16]
+        }
+    }
+    // Initialize working variables
+    a = H[0]
+    b = H[1]
+    c = H[2]
+    d = H[3]
+    e = H[4]
+    f = H[5]
+    g = H[6]
+    h = H[7]
+    // Manipulate working variables
+    for t := 0; t < 80; t++ {
+        T1 = h + BigSigma1_64(e) + Ch_64(e, f, g) + K_64[t] + W[t]
+        T2 = BigSigma0_64(a) + Maj_64(a, b, c)
+        h = g
+        g = f
+        f = e
+        e = d + T1
+        d = c
+        c = b
+        b = a
+        a = T1 + T2
+    }
+    // Compute intermediate hash values
+    H[0] = a + H[0]
+    H[1] = b + H[1]
+    H[2] = c + H[2]
+    H[3] = d + H[3]
+    H[4] = e + H[4]
+    H[5] = f + H[5]
+    H[6] = g + H[6]
+    H[7] = h + H[7]
+}
+
 func SHA2_64(input []byte, H0 [8]uint64) [64]byte {
     /* Takes an input, and the initial hash value, then computes a
      * SHA2 hash using 64-bit words (used for SHA384 & SHA512)
@@ -69,52 +119,8 @@ func SHA2_64(input []byte, H0 [8]uint64) [64]byte {
     var H [8]uint64
     copy(H[:], H0[:])
     /* HASH COMPUTATION */
-    // All additions are automatically performed modulo 2^32
-    var W [80]uint64  // Message schedule
-    var a, b, c, d, e, f, g, h uint64  // Working variables
-    var T1, T2 uint64  // Temporary words
-    N := len(M)  // Number of blocks
-
-    for i := 0; i < N; i++ {
-        // Prepare message schedule
-        for t := 0; t < 80; t++ {
-            if t < 16 {
-                W[t] = M[i][t]
-            } else {
-                W[t] = SmallSigma1_64(W[t-2]) + W[t-7] + SmallSigma0_64(W[t-15]) + W[t-16]
-            }
-        }
-        // Initialize working variables
-        a = H[0]
-        b = H[1]
-        c = H[2]
-        d = H[3]
-        e = H[4]
-        f = H[5]
-        g = H[6]
-        h = H[7]
-        // Manipulate working variables
-        for t := 0; t < 80; t++ {
-            T1 = h + BigSigma1_64(e) + Ch_64(e, f, g) + K_64[t] + W[t]
-            T2 = BigSigma0_64(a) + Maj_64(a, b, c)
-            h = g
-            g = f
-            f = e
-            e = d + T1
-            d = c
-            c = b
-            b = a
-            a = T1 + T2
-        }
-        // Compute intermediate hash values
-        H[0] = a + H[0]
-        H[1] = b + H[1]
-        H[2] = c + H[2]
-        H[3] = d + H[3]
-        H[4] = e + H[4]
-        H[5] = f + H[5]
-        H[6] = g + H[6]
-        H[7] = h + H[7]
+    for i := 0; i < len(M); i++ {
+        SHA2Compress64(&H, M[i])
     }
     // Combine final H values into the output hash
     var output [64]byte
@@ -144,3 +150,56 @@ func SHA512(input []byte) [64]byte {
     // Calculate hash and return
     return SHA2_64(input, H)
 }
+
+func SHA512_t_IV(t int) [8]uint64 {
+    /* Returns the initial hash value for SHA512/t, which is the SHA512
+     * hash of the string "SHA-512/t" using a modified initial hash value */
+    var H = [8]uint64{0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1, 0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179}
+    for i := 0; i < 8; i++ {
+        H[i] ^= 0xa5a5a5a5a5a5a5a5
+    }
+    var hash [64]byte = SHA2_64([]byte("SHA-512/" + strconv.Itoa(t)), H)
+    var output [8]uint64
+    for i := 0; i < 8; i++ {
+        output[i] = binary.BigEndian.Uint64(hash[i*8:])
+    }
+    return output
+}
+
+func SHA512_t(input []byte, t int) []byte {
+    /* Takes an input and returns the SHA512/t hash, where t is a multiple
+     * of 8 less than 512, other than 384 */
+    if t <= 0 || t >= 512 || t == 384 || t % 8 != 0 {
+        panic("sha: invalid SHA512/t output length")
+    }
+    // Calculate full 512-bit hash with the initial hash value for t
+    var hash [64]byte = SHA2_64(input, SHA512_t_IV(t))
+    // Truncate to t bits
+    output := make([]byte, t/8)
+    copy(output, hash[:t/8])
+    return output
+}
+
+func SHA512_224(input []byte) [28]byte {
+    /* Takes an input and returns the SHA512/224 hash */
+    // Initial hash value
+    var H = [8]uint64{0x8c3d37c819544da2, 0x73e1996689dcd4d6, 0x1dfab7ae32ff9c82, 0x679dd514582f9fcf, 0x0f6d2b697bd44da8, 0x77e36f7304c48942, 0x3f9d85a86a1d36c8, 0x1112e6ad91d692a1}
+    // Calculate full 512-bit hash
+    var hash [64]byte = SHA2_64(input, H)
+    // Truncate to 224 bits
+    var output [28]byte
+    copy(output[:], hash[:28])
+    return output
+}
+
+func SHA512_256(input []byte) [32]byte {
+    /* Takes an input and returns the SHA512/256 hash */
+    // Initial hash value
+    var H = [8]uint64{0x22312194fc2bf72c, 0x9f555fa3c84c64c2, 0x2393b86b6f53b151, 0x963877195940eabd, 0x96283ee2a88effe3, 0xbe5e1e2553863992, 0x2b0199fc2c85b8aa, 0x0eb72ddc81c52ca2}
+    // Calculate full 512-bit hash
+    var hash [64]byte = SHA2_64(input, H)
+    // Truncate to 256 bits
+    var output [32]byte
+    copy(output[:], hash[:32])
+    return output
+}
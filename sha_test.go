@@ -4,6 +4,7 @@ import (
     "testing"
     "bytes"
     "reflect"
+    "encoding/hex"
 )
 
 func TestPadMessage32(t *testing.T) {
@@ -131,3 +132,78 @@ func TestSHA512(t *testing.T) {
         t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
     }
 }
+
+func TestSHA512OneBlock(t *testing.T) {
+    // Input: 111 bytes of 0x61, padded to exactly one block
+    input := bytes.Repeat([]byte{0x61}, 111)
+    // Expected: from Python's hashlib
+    expected := "fa9121c7b32b9e01733d034cfc78cbf67f926c7ed83e82200ef86818196921760b4beff48404df811b953828274461673c68d04e297b0eb7b2b4d60fc6b566a2"
+    result := SHA512(input)
+    if hex.EncodeToString(result[:]) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", result, expected)
+    }
+}
+
+func TestSHA512_224(t *testing.T) {
+    // Input: 61 62 63
+    input := []byte("abc")
+    // Expected: 4634270f707b6a54daae7530460842e20e37ed265ceee9a43e8924aa
+    expected := [28]byte{0x46, 0x34, 0x27, 0x0f, 0x70, 0x7b, 0x6a, 0x54, 0xda, 0xae, 0x75, 0x30, 0x46, 0x08, 0x42, 0xe2, 0x0e, 0x37, 0xed, 0x26, 0x5c, 0xee, 0xe9, 0xa4, 0x3e, 0x89, 0x24, 0xaa}
+    result := SHA512_224(input)
+    if result != expected {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestSHA512_256(t *testing.T) {
+    // Input: 61 62 63
+    input := []byte("abc")
+    // Expected: 53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23
+    expected := [32]byte{0x53, 0x04, 0x8e, 0x26, 0x81, 0x94, 0x1e, 0xf9, 0x9b, 0x2e, 0x29, 0xb7, 0x6b, 0x4c, 0x7d, 0xab, 0xe4, 0xc2, 0xd0, 0xc6, 0x34, 0xfc, 0x6d, 0x46, 0xe0, 0xe2, 0xf1, 0x31, 0x07, 0xe7, 0xaf, 0x23}
+    result := SHA512_256(input)
+    if result != expected {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestSHA512_t_IV(t *testing.T) {
+    // Expected: initial hash value for SHA-512/256 from FIPS 180-4 section 5.3.6.2
+    expected := [8]uint64{0x22312194fc2bf72c, 0x9f555fa3c84c64c2, 0x2393b86b6f53b151, 0x963877195940eabd, 0x96283ee2a88effe3, 0xbe5e1e2553863992, 0x2b0199fc2c85b8aa, 0x0eb72ddc81c52ca2}
+    result := SHA512_t_IV(256)
+    if result != expected {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+    // SHA512_t should agree with the fixed-size SHA512_224
+    hash := SHA512_224([]byte("abc"))
+    if !bytes.Equal(SHA512_t([]byte("abc"), 224), hash[:]) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", SHA512_t([]byte("abc"), 224), hash)
+    }
+}
+
+func TestPadMessage64Full(t *testing.T) {
+    // Input: 111 bytes, the longest message whose padding fits in one block
+    M := make([]byte, 111)
+    // Expected: 111 bytes, 0x80, 16 zero bytes, then the length 0x0378
+    expected := append(make([]byte, 111), 0x80)
+    expected = append(expected, make([]byte, 16)...)
+    expected[126] = 0x03
+    expected[127] = 0x78
+    result := PadMessage64(M)
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestPadMessage64Boundary(t *testing.T) {
+    // Input: 112 bytes, so the length no longer fits in the first block
+    M := make([]byte, 112)
+    // Expected: 112 bytes, 0x80, 127 zero bytes, then the length 0x0380
+    expected := append(make([]byte, 112), 0x80)
+    expected = append(expected, make([]byte, 143)...)
+    expected[254] = 0x03
+    expected[255] = 0x80
+    result := PadMessage64(M)
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
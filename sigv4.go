@@ -0,0 +1,597 @@
+package sha
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/subtle"
+    "encoding/hex"
+    "errors"
+    "hash"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+/* AWS Signature Version 4: request signing with HMAC-SHA256, as used by AWS
+ * and S3-compatible object stores. For S3 the path is URI-encoded once and
+ * left as it is. Other services normalise it and encode it twice, which
+ * is once more than the request line, unless EncodePathOnce is set. Of
+ * the streaming payloads, only signed chunks without trailers
+ * (STREAMING-AWS4-HMAC-SHA256-PAYLOAD) are supported */
+
+const (
+    SigV4Algorithm = "AWS4-HMAC-SHA256"
+    SigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+    SigV4StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+    sigV4ChunkAlgorithm = "AWS4-HMAC-SHA256-PAYLOAD"
+    sigV4TimeFormat = "20060102T150405Z"
+)
+
+// The clock skew a verifier allows by default, as AWS does
+const SigV4MaxSkew = 15 * time.Minute
+
+// The longest a presigned URL may be valid for
+const SigV4MaxExpires = 7 * 24 * time.Hour
+
+// The largest chunk a verifier reads into memory
+const sigV4MaxChunk = 1 << 24
+
+// The largest body a verifier reads into memory to hash, for services
+// which send no X-Amz-Content-Sha256
+const sigV4MaxBody = 1 << 24
+
+// Returned when a signature is wrong or out of date, or its key unknown
+var ErrSigV4Auth = errors.New("sha: SigV4 signature does not match")
+
+// Returned for a request whose signature cannot be parsed
+var ErrSigV4Format = errors.New("sha: malformed SigV4 request")
+
+// Headers which are changed in transit, and so are not signed
+var sigV4Unsigned = map[string]bool{
+    "authorization": true,
+    "user-agent": true,
+    "expect": true,
+    "x-amzn-trace-id": true,
+}
+
+// The hash of an empty payload
+var sigV4EmptyHash = hex.EncodeToString(HashSHA256.Sum(nil))
+
+// AWS credentials. The session token of temporary credentials is sent as
+// X-Amz-Security-Token
+type SigV4Credentials struct {
+    AccessKeyID string
+    SecretAccessKey string
+    SessionToken string
+}
+
+// Signs requests for a service in a region
+type SigV4Signer struct {
+    Credentials SigV4Credentials
+    Region string       // e.g. "us-east-1"
+    Service string      // e.g. "s3"
+    EncodePathOnce bool // Encode the path once for services other than S3
+}
+
+// Verifies requests signed for a service in a region
+type SigV4Verifier struct {
+    Region string
+    Service string
+    // Returns the credentials of an access key ID, or an error if there
+    // are none
+    Lookup func(accessKeyID string) (SigV4Credentials, error)
+    MaxSkew time.Duration  // SigV4MaxSkew if zero
+    EncodePathOnce bool    // Encode the path once for services other than S3
+}
+
+// Signs the chunks of a streaming upload, each signature chaining from the
+// one before, starting with the request's
+type SigV4ChunkSigner struct {
+    key []byte
+    date string
+    scope string
+    previous string
+}
+
+func SigV4SigningKey(secretAccessKey string, date string, region string, service string) []byte {
+    /* Derives the signing key for a date of the form YYYYMMDD, by chaining
+     * HMAC-SHA256 from "AWS4" and the secret through the date, region,
+     * service and "aws4_request" */
+    key := HMACSum(HashSHA256, []byte("AWS4" + secretAccessKey), []byte(date))
+    key = HMACSum(HashSHA256, key, []byte(region))
+    key = HMACSum(HashSHA256, key, []byte(service))
+    return HMACSum(HashSHA256, key, []byte("aws4_request"))
+}
+
+func sigV4Hash(b []byte) string {
+    /* Hex SHA-256, as used throughout SigV4 */
+    return hex.EncodeToString(HashSHA256.Sum(b))
+}
+
+func sigV4Scope(t time.Time, region string, service string) string {
+    /* The credential scope: date/region/service/aws4_request */
+    return t.UTC().Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+}
+
+/* Canonical requests */
+
+func sigV4Escape(s string, slash bool) string {
+    /* URI-encodes every byte but the unreserved characters of RFC 3986,
+     * and '/' if slash is false */
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' || c == '/' && !slash {
+            b.WriteByte(c)
+        } else {
+            b.WriteByte('%')
+            b.WriteByte("0123456789ABCDEF"[c >> 4])
+            b.WriteByte("0123456789ABCDEF"[c & 15])
+        }
+    }
+    return b.String()
+}
+
+func sigV4CanonicalURI(path string, service string, once bool) string {
+    /* Returns the encoded path. Except for S3, whose object keys may
+     * contain them, dot segments and empty segments are removed, and the
+     * path is encoded twice unless once is set */
+    if path == "" {
+        path = "/"
+    }
+    if service != "s3" {
+        var segments []string
+        for _, segment := range strings.Split(path, "/") {
+            switch segment {
+            case "", ".":
+            case "..":
+                if len(segments) > 0 {
+                    segments = segments[:len(segments)-1]
+                }
+            default:
+                segments = append(segments, segment)
+            }
+        }
+        normalised := "/" + strings.Join(segments, "/")
+        if len(segments) > 0 && (strings.HasSuffix(path, "/") || strings.HasSuffix(path, "/.") || strings.HasSuffix(path, "/..")) {
+            normalised += "/"
+        }
+        path = normalised
+    }
+    path = sigV4Escape(path, false)
+    if service != "s3" && !once {
+        path = sigV4Escape(path, false)
+    }
+    return path
+}
+
+func sigV4CanonicalQuery(rawQuery string, skip string) (string, error) {
+    /* Returns the query parameters encoded and sorted, leaving out the
+     * parameter named skip */
+    var params [][2]string
+    for _, param := range strings.Split(rawQuery, "&") {
+        if param == "" {
+            continue
+        }
+        name, value, _ := strings.Cut(param, "=")
+        name, err := url.QueryUnescape(name)
+        if err != nil {
+            return "", ErrSigV4Format
+        }
+        if value, err = url.QueryUnescape(value); err != nil {
+            return "", ErrSigV4Format
+        }
+        if name != skip {
+            params = append(params, [2]string{sigV4Escape(name, true), sigV4Escape(value, true)})
+        }
+    }
+    // Sorted by encoded name, then value. Sorting whole name=value
+    // strings would put "a1=" before "a=", as '=' sorts after '0'
+    sort.Slice(params, func(i, j int) bool {
+        if params[i][0] != params[j][0] {
+            return params[i][0] < params[j][0]
+        }
+        return params[i][1] < params[j][1]
+    })
+    encoded := make([]string, len(params))
+    for i, param := range params {
+        encoded[i] = param[0] + "=" + param[1]
+    }
+    return strings.Join(encoded, "&"), nil
+}
+
+func sigV4HeaderValue(r *http.Request, name string) (string, bool) {
+    /* Returns the canonical value of a header: each value trimmed, with
+     * runs of spaces collapsed, and joined by commas */
+    switch name {
+    case "host":
+        if r.Host != "" {
+            return r.Host, true
+        }
+        return r.URL.Host, r.URL.Host != ""
+    case "content-length":
+        // Servers hold the length in the request rather than its headers
+        if _, ok := r.Header["Content-Length"]; !ok && r.ContentLength >= 0 {
+            return strconv.FormatInt(r.ContentLength, 10), true
+        }
+    }
+    values, ok := r.Header[http.CanonicalHeaderKey(name)]
+    if !ok {
+        return "", false
+    }
+    canonical := make([]string, len(values))
+    for i, value := range values {
+        canonical[i] = strings.Join(strings.Fields(value), " ")
+    }
+    return strings.Join(canonical, ","), true
+}
+
+func sigV4SignedHeaders(r *http.Request) []string {
+    /* Returns the lower case names of the headers to sign: the host and
+     * every header of the request but those changed in transit */
+    names := []string{"host"}
+    for name := range r.Header {
+        name = strings.ToLower(name)
+        if name != "host" && !sigV4Unsigned[name] {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+func sigV4CanonicalRequest(r *http.Request, service string, once bool, query string, signedHeaders []string, payloadHash string) (string, error) {
+    /* Returns the canonical request of the method, path, canonical query,
+     * signed headers and payload hash */
+    var b strings.Builder
+    b.WriteString(r.Method + "\n")
+    b.WriteString(sigV4CanonicalURI(r.URL.Path, service, once) + "\n")
+    b.WriteString(query + "\n")
+    for _, name := range signedHeaders {
+        value, ok := sigV4HeaderValue(r, name)
+        if !ok {
+            return "", ErrSigV4Format
+        }
+        b.WriteString(name + ":" + value + "\n")
+    }
+    b.WriteString("\n" + strings.Join(signedHeaders, ";") + "\n")
+    b.WriteString(payloadHash)
+    return b.String(), nil
+}
+
+func sigV4Signature(key []byte, t time.Time, scope string, canonicalRequest string) string {
+    /* Signs the string to sign of a canonical request */
+    stringToSign := SigV4Algorithm + "\n" + t.UTC().Format(sigV4TimeFormat) + "\n" + scope + "\n" + sigV4Hash([]byte(canonicalRequest))
+    return hex.EncodeToString(HMACSum(HashSHA256, key, []byte(stringToSign)))
+}
+
+/* Signing */
+
+func (s *SigV4Signer) Sign(r *http.Request, payloadHash string, t time.Time) error {
+    /* Signs a request at time t, setting its X-Amz-Date and Authorization
+     * headers, and X-Amz-Content-Sha256 for S3. The payload hash is the hex
+     * SHA-256 of the body, the hash of an empty body if empty, or one of
+     * SigV4UnsignedPayload and SigV4StreamingPayload */
+    _, err := s.sign(r, payloadHash, t)
+    return err
+}
+
+func (s *SigV4Signer) sign(r *http.Request, payloadHash string, t time.Time) (string, error) {
+    /* Signs a request, returning the signature */
+    if payloadHash == "" {
+        payloadHash = sigV4EmptyHash
+    }
+    r.Header.Set("X-Amz-Date", t.UTC().Format(sigV4TimeFormat))
+    if s.Credentials.SessionToken != "" {
+        r.Header.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+    }
+    if s.Service == "s3" {
+        r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+    }
+    query, err := sigV4CanonicalQuery(r.URL.RawQuery, "")
+    if err != nil {
+        return "", err
+    }
+    signedHeaders := sigV4SignedHeaders(r)
+    canonicalRequest, err := sigV4CanonicalRequest(r, s.Service, s.EncodePathOnce, query, signedHeaders, payloadHash)
+    if err != nil {
+        return "", err
+    }
+    scope := sigV4Scope(t, s.Region, s.Service)
+    key := SigV4SigningKey(s.Credentials.SecretAccessKey, t.UTC().Format("20060102"), s.Region, s.Service)
+    signature := sigV4Signature(key, t, scope, canonicalRequest)
+    r.Header.Set("Authorization", SigV4Algorithm + " Credential=" + s.Credentials.AccessKeyID + "/" + scope + ", SignedHeaders=" + strings.Join(signedHeaders, ";") + ", Signature=" + signature)
+    return signature, nil
+}
+
+func (s *SigV4Signer) Presign(r *http.Request, expires time.Duration, t time.Time) (string, error) {
+    /* Returns a URL for the request which is valid from time t for the
+     * given duration, with the signature in its query. Any headers of the
+     * request are signed and must be sent with it. The payload is unsigned
+     * for S3, and must otherwise be empty */
+    if expires < time.Second || expires > SigV4MaxExpires {
+        return "", errors.New("sha: SigV4 presigned URLs must expire within 1 second to 7 days")
+    }
+    payloadHash := sigV4EmptyHash
+    if s.Service == "s3" {
+        payloadHash = SigV4UnsignedPayload
+    }
+    scope := sigV4Scope(t, s.Region, s.Service)
+    signedHeaders := sigV4SignedHeaders(r)
+    u := *r.URL
+    query := u.Query()
+    query.Set("X-Amz-Algorithm", SigV4Algorithm)
+    query.Set("X-Amz-Credential", s.Credentials.AccessKeyID + "/" + scope)
+    query.Set("X-Amz-Date", t.UTC().Format(sigV4TimeFormat))
+    query.Set("X-Amz-Expires", strconv.Itoa(int(expires / time.Second)))
+    query.Set("X-Amz-SignedHeaders", strings.Join(signedHeaders, ";"))
+    if s.Credentials.SessionToken != "" {
+        query.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+    }
+    // url.Values encodes spaces as '+', which SigV4 does not
+    u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+    presigned := r.Clone(r.Context())
+    presigned.URL = &u
+    canonicalQuery, err := sigV4CanonicalQuery(u.RawQuery, "")
+    if err != nil {
+        return "", err
+    }
+    canonicalRequest, err := sigV4CanonicalRequest(presigned, s.Service, s.EncodePathOnce, canonicalQuery, signedHeaders, payloadHash)
+    if err != nil {
+        return "", err
+    }
+    key := SigV4SigningKey(s.Credentials.SecretAccessKey, t.UTC().Format("20060102"), s.Region, s.Service)
+    u.RawQuery += "&X-Amz-Signature=" + sigV4Signature(key, t, scope, canonicalRequest)
+    return u.String(), nil
+}
+
+/* Chunked uploads */
+
+func SigV4ChunkedLength(decodedLength int64, chunkSize int64) int64 {
+    /* Returns the Content-Length of a streaming upload of decodedLength
+     * bytes in chunks of chunkSize, including the final empty chunk */
+    chunkLength := func(size int64) int64 {
+        return int64(len(strconv.FormatInt(size, 16)) + len(";chunk-signature=") + 64 + 2) + size + 2
+    }
+    length := (decodedLength / chunkSize) * chunkLength(chunkSize)
+    if decodedLength % chunkSize != 0 {
+        length += chunkLength(decodedLength % chunkSize)
+    }
+    return length + chunkLength(0)
+}
+
+func (s *SigV4Signer) SignStreaming(r *http.Request, t time.Time) (*SigV4ChunkSigner, error) {
+    /* Signs the headers of a streaming upload, returning the signer of its
+     * chunks. The request should have the headers Content-Encoding:
+     * aws-chunked, X-Amz-Decoded-Content-Length, and a Content-Length from
+     * SigV4ChunkedLength */
+    signature, err := s.sign(r, SigV4StreamingPayload, t)
+    if err != nil {
+        return nil, err
+    }
+    return &SigV4ChunkSigner{
+        key: SigV4SigningKey(s.Credentials.SecretAccessKey, t.UTC().Format("20060102"), s.Region, s.Service),
+        date: t.UTC().Format(sigV4TimeFormat),
+        scope: sigV4Scope(t, s.Region, s.Service),
+        previous: signature,
+    }, nil
+}
+
+func (c *SigV4ChunkSigner) Sign(chunk []byte) string {
+    /* Returns the signature of the next chunk. The last chunk is empty */
+    stringToSign := sigV4ChunkAlgorithm + "\n" + c.date + "\n" + c.scope + "\n" + c.previous + "\n" + sigV4EmptyHash + "\n" + sigV4Hash(chunk)
+    c.previous = hex.EncodeToString(HMACSum(HashSHA256, c.key, []byte(stringToSign)))
+    return c.previous
+}
+
+func (c *SigV4ChunkSigner) Encode(chunk []byte) []byte {
+    /* Returns the next chunk with its size and signature, as sent in the
+     * body. The last chunk is empty */
+    signature := c.Sign(chunk)
+    encoded := []byte(strconv.FormatInt(int64(len(chunk)), 16) + ";chunk-signature=" + signature + "\r\n")
+    encoded = append(encoded, chunk...)
+    return append(encoded, "\r\n"...)
+}
+
+// Decodes a streaming body, checking the signature of each chunk
+type sigV4ChunkReader struct {
+    r *bufio.Reader
+    body io.Closer
+    signer *SigV4ChunkSigner
+    chunk []byte
+    err error
+}
+
+func (c *sigV4ChunkReader) Read(p []byte) (int, error) {
+    for len(c.chunk) == 0 && c.err == nil {
+        c.err = c.next()
+    }
+    if len(c.chunk) > 0 {
+        n := copy(p, c.chunk)
+        c.chunk = c.chunk[n:]
+        return n, nil
+    }
+    return 0, c.err
+}
+
+func (c *sigV4ChunkReader) next() error {
+    /* Reads and verifies the next chunk, returning io.EOF after the last */
+    line, err := c.r.ReadSlice('\n')
+    if err != nil || !bytes.HasSuffix(line, []byte("\r\n")) {
+        return ErrSigV4Format
+    }
+    sizeHex, signature, found := strings.Cut(string(line[:len(line)-2]), ";chunk-signature=")
+    size, err := strconv.ParseInt(sizeHex, 16, 64)
+    if !found || err != nil || size < 0 || size > sigV4MaxChunk {
+        return ErrSigV4Format
+    }
+    chunk := make([]byte, size + 2)
+    if _, err := io.ReadFull(c.r, chunk); err != nil || !bytes.HasSuffix(chunk, []byte("\r\n")) {
+        return ErrSigV4Format
+    }
+    chunk = chunk[:size]
+    if subtle.ConstantTimeCompare([]byte(c.signer.Sign(chunk)), []byte(signature)) != 1 {
+        return ErrSigV4Auth
+    }
+    if size == 0 {
+        return io.EOF
+    }
+    c.chunk = chunk
+    return nil
+}
+
+func (c *sigV4ChunkReader) Close() error {
+    return c.body.Close()
+}
+
+// Checks a body against its signed hash when it has been read
+type sigV4HashReader struct {
+    body io.ReadCloser
+    d hash.Hash
+    expected string
+}
+
+func (h *sigV4HashReader) Read(p []byte) (int, error) {
+    n, err := h.body.Read(p)
+    h.d.Write(p[:n])
+    if err == io.EOF && hex.EncodeToString(h.d.Sum(nil)) != h.expected {
+        return n, ErrSigV4Auth
+    }
+    return n, err
+}
+
+func (h *sigV4HashReader) Close() error {
+    return h.body.Close()
+}
+
+/* Verifying */
+
+func (v *SigV4Verifier) Verify(r *http.Request, now time.Time) (string, error) {
+    /* Checks the signature of a request in its Authorization header or a
+     * presigned URL, returning the access key ID. The body is replaced by
+     * one which returns ErrSigV4Auth at its end if it does not match its
+     * signed hash, or, for a streaming upload, the decoded payload, which
+     * returns ErrSigV4Auth at a chunk whose signature is wrong. Services
+     * other than S3 send no hash, so their bodies are read to find it, up
+     * to 16 MiB */
+    presigned := r.Header.Get("Authorization") == ""
+    var credential, signedHeaders, signature, date string
+    expires := time.Duration(0)
+    if presigned {
+        query := r.URL.Query()
+        if query.Get("X-Amz-Algorithm") != SigV4Algorithm {
+            return "", ErrSigV4Format
+        }
+        credential, signedHeaders, signature, date = query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders"), query.Get("X-Amz-Signature"), query.Get("X-Amz-Date")
+        seconds, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+        if err != nil || seconds < 1 || time.Duration(seconds) * time.Second > SigV4MaxExpires {
+            return "", ErrSigV4Format
+        }
+        expires = time.Duration(seconds) * time.Second
+    } else {
+        params, found := strings.CutPrefix(r.Header.Get("Authorization"), SigV4Algorithm + " ")
+        if !found {
+            return "", ErrSigV4Format
+        }
+        for _, param := range strings.Split(params, ",") {
+            name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+            switch name {
+            case "Credential":
+                credential = value
+            case "SignedHeaders":
+                signedHeaders = value
+            case "Signature":
+                signature = value
+            }
+        }
+        date = r.Header.Get("X-Amz-Date")
+    }
+    t, err := time.Parse(sigV4TimeFormat, date)
+    if err != nil || signature == "" {
+        return "", ErrSigV4Format
+    }
+    accessKeyID, scope, found := strings.Cut(credential, "/")
+    if !found || scope != sigV4Scope(t, v.Region, v.Service) {
+        return "", ErrSigV4Auth
+    }
+    // The signed headers must be sorted, and include the host
+    headers := strings.Split(signedHeaders, ";")
+    host := sort.SearchStrings(headers, "host")
+    if !sort.StringsAreSorted(headers) || host == len(headers) || headers[host] != "host" {
+        return "", ErrSigV4Format
+    }
+    skew := v.MaxSkew
+    if skew == 0 {
+        skew = SigV4MaxSkew
+    }
+    if t.After(now.Add(skew)) || !presigned && t.Before(now.Add(-skew)) || presigned && now.After(t.Add(expires)) {
+        return "", ErrSigV4Auth
+    }
+    payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+    if presigned && payloadHash == "" {
+        payloadHash = SigV4UnsignedPayload
+        if v.Service != "s3" {
+            payloadHash = sigV4EmptyHash
+        }
+    }
+    credentials, err := v.Lookup(accessKeyID)
+    if err != nil {
+        return "", ErrSigV4Auth
+    }
+    // Other services sign the body without sending its hash
+    if payloadHash == "" && r.Body != nil {
+        body, err := io.ReadAll(io.LimitReader(r.Body, sigV4MaxBody + 1))
+        r.Body.Close()
+        if err != nil {
+            return "", err
+        }
+        if len(body) > sigV4MaxBody {
+            return "", errors.New("sha: SigV4 body without X-Amz-Content-Sha256 is too large")
+        }
+        r.Body = io.NopCloser(bytes.NewReader(body))
+        payloadHash = sigV4Hash(body)
+    } else if payloadHash == "" {
+        payloadHash = sigV4EmptyHash
+    }
+    skip := ""
+    if presigned {
+        skip = "X-Amz-Signature"
+    }
+    query, err := sigV4CanonicalQuery(r.URL.RawQuery, skip)
+    if err != nil {
+        return "", err
+    }
+    canonicalRequest, err := sigV4CanonicalRequest(r, v.Service, v.EncodePathOnce, query, headers, payloadHash)
+    if err != nil {
+        return "", err
+    }
+    key := SigV4SigningKey(credentials.SecretAccessKey, t.Format("20060102"), v.Region, v.Service)
+    if subtle.ConstantTimeCompare([]byte(sigV4Signature(key, t, scope, canonicalRequest)), []byte(signature)) != 1 {
+        return "", ErrSigV4Auth
+    }
+    if credentials.SessionToken != "" {
+        token := r.Header.Get("X-Amz-Security-Token")
+        if presigned {
+            token = r.URL.Query().Get("X-Amz-Security-Token")
+        }
+        if subtle.ConstantTimeCompare([]byte(token), []byte(credentials.SessionToken)) != 1 {
+            return "", ErrSigV4Auth
+        }
+    }
+    switch {
+    case r.Body == nil || payloadHash == SigV4UnsignedPayload:
+    case payloadHash == SigV4StreamingPayload:
+        r.Body = &sigV4ChunkReader{
+            r: bufio.NewReader(r.Body),
+            body: r.Body,
+            signer: &SigV4ChunkSigner{key: key, date: date, scope: scope, previous: signature},
+        }
+    default:
+        r.Body = &sigV4HashReader{body: r.Body, d: HashSHA256.New(), expected: payloadHash}
+    }
+    return accessKeyID, nil
+}
@@ -0,0 +1,227 @@
+package sha
+
+import (
+    "errors"
+)
+
+/* A configurable Keccak sponge, and the SHA-3 functions built from it */
+
+// Settings for a Keccak sponge. Zero values for LaneSize, Rate and Rounds
+// are replaced by 64, 25*LaneSize - Capacity and the full 12 + 2l rounds of
+// Keccak-f, so only the capacity and suffix are needed for standard Keccak
+type SpongeConfig struct {
+    Rate int          // Rate, r, in bits
+    Capacity int      // Capacity, c, in bits
+    Rounds int        // Number of rounds of Keccak-p
+    LaneSize uint     // Lane size, w, in bits (1, 2, 4, 8, 16, 32 or 64)
+    Suffix byte       // Suffix bits appended to the message, first bit lowest
+    SuffixLen int     // Number of suffix bits (0-7)
+}
+
+// A Keccak sponge, which can absorb input with Write and WriteBits and
+// then squeeze an output of any length with Read
+type Sponge struct {
+    SpongeConfig
+    A [25]uint64      // State
+    pos int           // Bit position within the current block
+    squeezing bool    // Whether the sponge has been padded
+}
+
+func NewSponge(config SpongeConfig) (*Sponge, error) {
+    /* Returns a sponge using the given settings, after filling in any
+     * defaults and checking that they describe a valid Keccak instance */
+    if config.LaneSize == 0 {
+        config.LaneSize = 64
+    }
+    w := config.LaneSize
+    if w > 64 || w & (w-1) != 0 {
+        return nil, errors.New("sha: lane size must be 1, 2, 4, 8, 16, 32 or 64")
+    }
+    b := 25 * int(w)
+    if config.Rate == 0 {
+        config.Rate = b - config.Capacity
+    }
+    if config.Rate + config.Capacity != b {
+        return nil, errors.New("sha: rate and capacity must add up to the width")
+    }
+    // pad10*1 needs at least two bits of each block
+    if config.Rate < 2 || config.Capacity < 0 {
+        return nil, errors.New("sha: rate must be at least 2 bits")
+    }
+    if config.Rounds == 0 {
+        var l int
+        for 1<<uint(l) < w {
+            l++
+        }
+        config.Rounds = 12 + 2*l
+    }
+    if config.Rounds < 0 {
+        return nil, errors.New("sha: number of rounds must not be negative")
+    }
+    if config.SuffixLen < 0 || config.SuffixLen > 7 {
+        return nil, errors.New("sha: suffix must be 0-7 bits")
+    }
+    return &Sponge{SpongeConfig: config}, nil
+}
+
+func (s *Sponge) xorBit(bit uint64) {
+    /* Xors a single bit into the current block position, permuting the
+     * state if the block is full */
+    w := uint64(s.LaneSize)
+    s.A[uint64(s.pos)/w] ^= bit << (uint64(s.pos) % w)
+    s.pos++
+    if s.pos == s.Rate {
+        KeccakP(&s.A, s.LaneSize, s.Rounds)
+        s.pos = 0
+    }
+}
+
+func (s *Sponge) aligned() bool {
+    /* Returns whether whole bytes can be moved in and out of the state at
+     * the current position */
+    return s.pos % 8 == 0 && s.Rate % 8 == 0 && s.LaneSize >= 8
+}
+
+func (s *Sponge) WriteBits(p []byte, n int) {
+    /* Absorbs the first n bits of p, taking the bits of each byte from
+     * least to most significant. Writing after reading is not allowed */
+    if s.squeezing {
+        panic("sha: sponge written to after being read")
+    }
+    w := uint64(s.LaneSize)
+    for i := 0; i < n; {
+        if n - i >= 8 && s.aligned() {
+            // Xor in a whole byte
+            s.A[uint64(s.pos)/w] ^= uint64(p[i/8]) << (uint64(s.pos) % w)
+            s.pos += 8
+            if s.pos == s.Rate {
+                KeccakP(&s.A, s.LaneSize, s.Rounds)
+                s.pos = 0
+            }
+            i += 8
+        } else {
+            s.xorBit(uint64(p[i/8] >> uint(i%8)) & 1)
+            i++
+        }
+    }
+}
+
+func (s *Sponge) Write(p []byte) (int, error) {
+    /* Absorbs p into the sponge. Writing after reading is not allowed */
+    s.WriteBits(p, len(p)*8)
+    return len(p), nil
+}
+
+func (s *Sponge) Read(out []byte) (int, error) {
+    /* Squeezes len(out) bytes from the sponge. The first call appends the
+     * suffix and pads the input, so the sponge can no longer be written to */
+    if !s.squeezing {
+        s.WriteBits([]byte{s.Suffix}, s.SuffixLen)
+        // pad10*1: a '1' bit, then zeros up to a final '1' bit
+        s.xorBit(1)
+        s.pos = s.Rate - 1
+        s.xorBit(1)
+        s.squeezing = true
+    }
+    w := uint64(s.LaneSize)
+    for i := range out {
+        if s.aligned() {
+            out[i] = byte(s.A[uint64(s.pos)/w] >> (uint64(s.pos) % w))
+            s.pos += 8
+            if s.pos == s.Rate {
+                KeccakP(&s.A, s.LaneSize, s.Rounds)
+                s.pos = 0
+            }
+            continue
+        }
+        // Gather the byte one bit at a time
+        out[i] = 0
+        for j := uint(0); j < 8; j++ {
+            bit := (s.A[uint64(s.pos)/w] >> (uint64(s.pos) % w)) & 1
+            out[i] |= byte(bit) << j
+            s.pos++
+            if s.pos == s.Rate {
+                KeccakP(&s.A, s.LaneSize, s.Rounds)
+                s.pos = 0
+            }
+        }
+    }
+    return len(out), nil
+}
+
+func (s *Sponge) Reset() {
+    /* Resets the sponge to its initial state, keeping its settings */
+    s.A = [25]uint64{}
+    s.pos = 0
+    s.squeezing = false
+}
+
+func Keccak(config SpongeConfig, M []byte, L int) ([]byte, error) {
+    /* Takes sponge settings, a message M and an output length L in
+     * bytes, and returns the output of the sponge */
+    s, err := NewSponge(config)
+    if err != nil {
+        return nil, err
+    }
+    s.Write(M)
+    output := make([]byte, L)
+    s.Read(output)
+    return output, nil
+}
+
+/* SHA-3 (FIPS 202) */
+
+func sha3(input []byte, c int, output []byte) {
+    /* Computes Keccak[c](input || 01) into output */
+    s, _ := NewSponge(SpongeConfig{Capacity: c, Suffix: 0x02, SuffixLen: 2})
+    s.Write(input)
+    s.Read(output)
+}
+
+func SHA3_224(input []byte) [28]byte {
+    /* Takes an input and returns the SHA3-224 hash */
+    var output [28]byte
+    sha3(input, 448, output[:])
+    return output
+}
+
+func SHA3_256(input []byte) [32]byte {
+    /* Takes an input and returns the SHA3-256 hash */
+    var output [32]byte
+    sha3(input, 512, output[:])
+    return output
+}
+
+func SHA3_384(input []byte) [48]byte {
+    /* Takes an input and returns the SHA3-384 hash */
+    var output [48]byte
+    sha3(input, 768, output[:])
+    return output
+}
+
+func SHA3_512(input []byte) [64]byte {
+    /* Takes an input and returns the SHA3-512 hash */
+    var output [64]byte
+    sha3(input, 1024, output[:])
+    return output
+}
+
+func SHAKE128(input []byte, L int) []byte {
+    /* Takes an input and an output length L in bytes, and returns the
+     * SHAKE128 output, Keccak[256](input || 1111) */
+    s, _ := NewSponge(SpongeConfig{Capacity: 256, Suffix: 0x0F, SuffixLen: 4})
+    s.Write(input)
+    output := make([]byte, L)
+    s.Read(output)
+    return output
+}
+
+func SHAKE256(input []byte, L int) []byte {
+    /* Takes an input and an output length L in bytes, and returns the
+     * SHAKE256 output, Keccak[512](input || 1111) */
+    s, _ := NewSponge(SpongeConfig{Capacity: 512, Suffix: 0x0F, SuffixLen: 4})
+    s.Write(input)
+    output := make([]byte, L)
+    s.Read(output)
+    return output
+}
@@ -0,0 +1,152 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "encoding/hex"
+)
+
+func TestKeccakRC(t *testing.T) {
+    // The LFSR has a period of 255, so it should reproduce the table of
+    // round constants 255 rounds later
+    for ir := 0; ir < 24; ir++ {
+        result := KeccakRC(ir+255, 64)
+        if result != RC[ir] {
+            t.Errorf("\nTest: KeccakRC(%d, 64)\nResult:   0x%016X\nExpected: 0x%016X\n", ir+255, result, RC[ir])
+        }
+    }
+}
+
+func TestROTL_W(t *testing.T) {
+    x := uint64(0x9)  // Input:    1001
+    x = ROTL_W(x, 1, 4)  // Expected: 0011
+    if x != 0x3 {
+        t.Errorf("\nResult:   0x%X\nExpected: 0x3\n", x)
+    }
+}
+
+func TestKeccak(t *testing.T) {
+    // Expected values from a bit-by-bit implementation of FIPS 202
+    tests := []struct {
+        config SpongeConfig
+        M []byte
+        L int
+        expected string
+    }{
+        {SpongeConfig{Rate: 40, Capacity: 160, LaneSize: 8}, []byte("abc"), 16, "37fbb0a32b4a316cd6fe54a181e5fa76"},
+        {SpongeConfig{Rate: 17, Capacity: 8, LaneSize: 1, Suffix: 0x02, SuffixLen: 2}, []byte("abc"), 4, "71dd2f07"},
+        {SpongeConfig{Rate: 50, Capacity: 50, LaneSize: 4, Rounds: 4}, ptn(20), 10, "2c3e1b06226cc0b50ab9"},
+        {SpongeConfig{Capacity: 256, LaneSize: 32}, ptn(100), 40, "222bb6f450a0407f099636c87ada1484b854818cb4647b3c2ed966f22f5e5de8a6206178c657b8a9"},
+        {SpongeConfig{Capacity: 512, Rounds: 30, Suffix: 0x02, SuffixLen: 2}, []byte("abc"), 32, "dfe06c752a23001cd802c7652f71784158e6435d766fb640efc3898050126181"},
+        {SpongeConfig{Rate: 144, Capacity: 256, LaneSize: 16, Rounds: 3, Suffix: 0x0F, SuffixLen: 4}, ptn(20), 24, "35b1de5727005e46f8f43329f5a08584601be4fe9c9021f7"},
+    }
+    for _, test := range tests {
+        output, err := Keccak(test.config, test.M, test.L)
+        if err != nil {
+            t.Errorf("\nTest: %+v\nError: %s\n", test.config, err)
+            continue
+        }
+        result := hex.EncodeToString(output)
+        if result != test.expected {
+            t.Errorf("\nTest: %+v\nResult:   %s\nExpected: %s\n", test.config, result, test.expected)
+        }
+    }
+}
+
+func TestSpongeWriteBits(t *testing.T) {
+    // Input: the 5-bit message 11001 from the NIST SHA3-256 examples
+    s, _ := NewSponge(SpongeConfig{Capacity: 512, Suffix: 0x02, SuffixLen: 2})
+    s.WriteBits([]byte{0x13}, 5)
+    result := make([]byte, 32)
+    s.Read(result)
+    expected, _ := hex.DecodeString("7b0047cf5a456882363cbf0fb05322cf65f4b7059a46365e830132e3b5d957af")
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestSpongeTurboSHAKE(t *testing.T) {
+    // TurboSHAKE128 is the 12-round sponge with D holding the suffix and
+    // the first padding bit
+    config := SpongeConfig{Capacity: 256, Rounds: 12, Suffix: 0x0F, SuffixLen: 4}
+    result, _ := Keccak(config, ptn(500), 200)
+    expected := TurboSHAKE128(ptn(500), 0x1F, 200)
+    if !bytes.Equal(result, expected) {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestNewSponge(t *testing.T) {
+    // Invalid settings should be rejected
+    tests := []SpongeConfig{
+        {Capacity: 256, LaneSize: 3},
+        {Capacity: 256, LaneSize: 128},
+        {Rate: 1000, Capacity: 1000},
+        {Capacity: 1599},
+        {Capacity: 512, Rounds: -1},
+        {Capacity: 512, SuffixLen: 8},
+    }
+    for _, test := range tests {
+        if _, err := NewSponge(test); err == nil {
+            t.Errorf("\nTest: %+v\nExpected an error\n", test)
+        }
+    }
+}
+
+func TestSHA3_224(t *testing.T) {
+    // Input: 61 62 63
+    input := []byte("abc")
+    // Expected: e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf
+    expected := [28]byte{0xe6, 0x42, 0x82, 0x4c, 0x3f, 0x8c, 0xf2, 0x4a, 0xd0, 0x92, 0x34, 0xee, 0x7d, 0x3c, 0x76, 0x6f, 0xc9, 0xa3, 0xa5, 0x16, 0x8d, 0x0c, 0x94, 0xad, 0x73, 0xb4, 0x6f, 0xdf}
+    result := SHA3_224(input)
+    if result != expected {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestSHA3_256(t *testing.T) {
+    // Input: 61 62 63
+    input := []byte("abc")
+    // Expected: 3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532
+    expected := [32]byte{0x3a, 0x98, 0x5d, 0xa7, 0x4f, 0xe2, 0x25, 0xb2, 0x04, 0x5c, 0x17, 0x2d, 0x6b, 0xd3, 0x90, 0xbd, 0x85, 0x5f, 0x08, 0x6e, 0x3e, 0x9d, 0x52, 0x5b, 0x46, 0xbf, 0xe2, 0x45, 0x11, 0x43, 0x15, 0x32}
+    result := SHA3_256(input)
+    if result != expected {
+        t.Errorf("\nResult:   %x\nExpected: %x\n", result, expected)
+    }
+}
+
+func TestSHA3_384(t *testing.T) {
+    // Input: 61 62 63
+    input := []byte("abc")
+    // Expected: ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25
+    expected := "ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25"
+    result := SHA3_384(input)
+    if hex.EncodeToString(result[:]) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", result, expected)
+    }
+}
+
+func TestSHA3_512(t *testing.T) {
+    // Input: 61 62 63
+    input := []byte("abc")
+    // Expected: b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0
+    expected := "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"
+    result := SHA3_512(input)
+    if hex.EncodeToString(result[:]) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", result, expected)
+    }
+}
+
+func TestSHAKE(t *testing.T) {
+    // Expected: SHAKE128 of the empty string, and SHAKE256 of "abc"
+    expected := "7f9c2ba4e88f827d616045507605853ed73b8093f6efbc88eb1a6eacfa66ef26"
+    result := hex.EncodeToString(SHAKE128([]byte{}, 32))
+    if result != expected {
+        t.Errorf("\nResult:   %s\nExpected: %s\n", result, expected)
+    }
+    expected = "483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739d5a15bef186a5386c75744c0527e1faa9f8726e462a12a4feb06bd8801e751e4"
+    result = hex.EncodeToString(SHAKE256([]byte("abc"), 64))
+    if result != expected {
+        t.Errorf("\nResult:   %s\nExpected: %s\n", result, expected)
+    }
+}
@@ -0,0 +1,111 @@
+package sha
+
+import (
+    "encoding/binary"
+)
+
+/* The SSH exchange hash and session key derivation (RFC 4253 sections 7.2
+ * & 8, RFC 5656 section 4 and RFC 8731) */
+
+func SSHString(b []byte) []byte {
+    /* Encodes b as an SSH string, prefixed by its 32-bit length */
+    output := binary.BigEndian.AppendUint32(nil, uint32(len(b)))
+    return append(output, b...)
+}
+
+func SSHMpint(b []byte) []byte {
+    /* Encodes the non-negative big-endian integer b as an SSH mpint: the
+     * shortest two's complement form (a leading zero byte is added if the
+     * top bit is set), as an SSH string */
+    for len(b) > 0 && b[0] == 0 {
+        b = b[1:]
+    }
+    if len(b) > 0 && b[0] & 0x80 != 0 {
+        b = append([]byte{0}, b...)
+    }
+    return SSHString(b)
+}
+
+// The fields of an SSH key exchange hashed into the exchange hash H. For
+// Diffie-Hellman (RFC 4253) the public values are the integers e and f, and
+// for ECDH and Curve25519 (RFC 5656, RFC 8731) the points Q_C and Q_S
+type SSHExchange struct {
+    ClientVersion []byte   // V_C, the client's version string without CR LF
+    ServerVersion []byte   // V_S, the server's version string without CR LF
+    ClientKexInit []byte   // I_C, the payload of the client's SSH_MSG_KEXINIT
+    ServerKexInit []byte   // I_S, the payload of the server's SSH_MSG_KEXINIT
+    HostKey []byte         // K_S, the server's public host key blob
+    ClientPublic []byte    // e or Q_C
+    ServerPublic []byte    // f or Q_S
+    FiniteField bool       // Whether the public values are mpints e and f
+    SharedSecret []byte    // K, as a big-endian unsigned integer
+}
+
+func (x *SSHExchange) Hash(h Hash) []byte {
+    /* Returns the exchange hash H using the hash function of the key
+     * exchange method. The first H of a connection is also its session
+     * identifier */
+    d := h.New()
+    d.Write(SSHString(x.ClientVersion))
+    d.Write(SSHString(x.ServerVersion))
+    d.Write(SSHString(x.ClientKexInit))
+    d.Write(SSHString(x.ServerKexInit))
+    d.Write(SSHString(x.HostKey))
+    if x.FiniteField {
+        d.Write(SSHMpint(x.ClientPublic))
+        d.Write(SSHMpint(x.ServerPublic))
+    } else {
+        d.Write(SSHString(x.ClientPublic))
+        d.Write(SSHString(x.ServerPublic))
+    }
+    d.Write(SSHMpint(x.SharedSecret))
+    return d.Sum(nil)
+}
+
+func SSHDeriveKey(h Hash, K []byte, H []byte, letter byte, sessionID []byte, L int) []byte {
+    /* Derives L bytes of key as HASH(K || H || letter || session_id),
+     * extended by K_n = HASH(K || H || K_1 || ... || K_n-1) when the hash
+     * output is too short. K is the shared secret as an unsigned integer,
+     * and letter is one of 'A' to 'F' */
+    mpint := SSHMpint(K)
+    d := h.New()
+    d.Write(mpint)
+    d.Write(H)
+    d.Write([]byte{letter})
+    d.Write(sessionID)
+    output := d.Sum(make([]byte, 0, L+h.Size()))
+    for len(output) < L {
+        d.Reset()
+        d.Write(mpint)
+        d.Write(H)
+        d.Write(output)
+        output = d.Sum(output)
+    }
+    return output[:L]
+}
+
+// The six keys derived after a key exchange. Client keys protect data sent
+// from the client to the server, and server keys data sent from the server
+// to the client
+type SSHKeys struct {
+    ClientIV []byte    // 'A', initial IV client to server
+    ServerIV []byte    // 'B', initial IV server to client
+    ClientKey []byte   // 'C', encryption key client to server
+    ServerKey []byte   // 'D', encryption key server to client
+    ClientMAC []byte   // 'E', integrity key client to server
+    ServerMAC []byte   // 'F', integrity key server to client
+}
+
+func SSHSessionKeys(h Hash, K []byte, H []byte, sessionID []byte, ivLen int, keyLen int, macLen int) *SSHKeys {
+    /* Derives the IVs, encryption keys and integrity keys with the lengths
+     * needed by the negotiated cipher and MAC. For the first key exchange
+     * sessionID is H */
+    return &SSHKeys{
+        ClientIV: SSHDeriveKey(h, K, H, 'A', sessionID, ivLen),
+        ServerIV: SSHDeriveKey(h, K, H, 'B', sessionID, ivLen),
+        ClientKey: SSHDeriveKey(h, K, H, 'C', sessionID, keyLen),
+        ServerKey: SSHDeriveKey(h, K, H, 'D', sessionID, keyLen),
+        ClientMAC: SSHDeriveKey(h, K, H, 'E', sessionID, macLen),
+        ServerMAC: SSHDeriveKey(h, K, H, 'F', sessionID, macLen),
+    }
+}
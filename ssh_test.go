@@ -0,0 +1,128 @@
+package sha
+
+import (
+    "testing"
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/ed25519"
+    "encoding/binary"
+    "encoding/hex"
+)
+
+func TestSSHMpint(t *testing.T) {
+    // Examples from RFC 4251 section 5
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"", "00000000"},
+        {"00", "00000000"},
+        {"09a378f9b2e332a7", "0000000809a378f9b2e332a7"},
+        {"80", "000000020080"},
+        {"0080", "000000020080"},
+    }
+    for _, test := range tests {
+        output := SSHMpint(mustHex(test.input))
+        if hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %s\nResult:   %x\nExpected: %s\n", test.input, output, test.expected)
+        }
+    }
+}
+
+func sshFields(b []byte) [][]byte {
+    /* Splits b into the contents of consecutive SSH strings */
+    var fields [][]byte
+    for len(b) >= 4 {
+        n := binary.BigEndian.Uint32(b)
+        fields = append(fields, b[4:4+n])
+        b = b[4+n:]
+    }
+    return fields
+}
+
+// Sizes used by the ciphers and MACs in SSHKEX.rsp
+var sshKeyLen = map[string]int{"aes128-ctr": 16, "aes256-ctr": 32}
+var sshMACLen = map[string]int{"hmac-sha1": 20, "hmac-sha2-256": 32, "hmac-sha2-512": 64}
+var sshMACHash = map[string]Hash{"hmac-sha1": HashSHA1, "hmac-sha2-256": HashSHA256, "hmac-sha2-512": HashSHA512}
+
+func TestSSHExchange(t *testing.T) {
+    /* Checks the exchange hash against the server's signature, then
+     * decrypts the client's first encrypted packet with the derived keys
+     * and checks its MAC */
+    var tested int
+    readRSP(t, "SSHKEX.rsp", func(section map[string]string, record map[string]string) {
+        tested++
+        h := cavpDRBGHash[section["Hash"]]
+        x := &SSHExchange{
+            ClientVersion: mustHex(record["V_C"]),
+            ServerVersion: mustHex(record["V_S"]),
+            ClientKexInit: mustHex(record["I_C"]),
+            ServerKexInit: mustHex(record["I_S"]),
+            HostKey: mustHex(record["K_S"]),
+            ClientPublic: mustHex(record["ClientPublic"]),
+            ServerPublic: mustHex(record["ServerPublic"]),
+            FiniteField: section[""] != "curve25519-sha256",
+            SharedSecret: mustHex(record["K"]),
+        }
+        H := x.Hash(h)
+        if hex.EncodeToString(H) != record["H"] {
+            t.Errorf("\nTest: %s\nResult:   %x\nExpected: %s\n", section[""], H, record["H"])
+        }
+        publicKey := sshFields(x.HostKey)[1]
+        signature := sshFields(mustHex(record["Signature"]))[1]
+        if !ed25519.Verify(publicKey, H, signature) {
+            t.Errorf("\nTest: %s\nServer signature does not verify\n", section[""])
+        }
+        macLen := sshMACLen[section["MAC"]]
+        keys := SSHSessionKeys(h, x.SharedSecret, H, H, aes.BlockSize, sshKeyLen[section["Cipher"]], macLen)
+        packet := mustHex(record["Packet"])
+        block, err := aes.NewCipher(keys.ClientKey)
+        if err != nil {
+            t.Fatal(err)
+        }
+        // mac = MAC(key, sequence_number || unencrypted_packet)
+        plaintext := make([]byte, len(packet) - macLen)
+        cipher.NewCTR(block, keys.ClientIV).XORKeyStream(plaintext, packet[:len(plaintext)])
+        mac := HMACSum(sshMACHash[section["MAC"]], keys.ClientMAC, append(make([]byte, 4), plaintext...))
+        if !bytes.Equal(mac, packet[len(plaintext):]) {
+            t.Errorf("\nTest: %s\nResult:   %x\nExpected: %x\n", section[""], mac, packet[len(plaintext):])
+        }
+        // SSH_MSG_SERVICE_REQUEST for "ssh-userauth"
+        if !bytes.Contains(plaintext, []byte("\x05\x00\x00\x00\x0cssh-userauth")) {
+            t.Errorf("\nTest: %s\nDecrypted packet: %x\n", section[""], plaintext)
+        }
+    })
+    if tested != 3 {
+        t.Errorf("\nTested %d exchanges, expected 3\n", tested)
+    }
+}
+
+func TestSSHDeriveKey(t *testing.T) {
+    // Keys longer than the hash output, from the curve25519-sha256 and
+    // diffie-hellman-group14-sha1 exchanges in SSHKEX.rsp. Expected values
+    // from the SSHKDF implementation in OpenSSL 3.0
+    K := mustHex("01065e1948dedf41cad50829af9be5786686a884dce9c69540999cc8d5989626")
+    H := mustHex("29e93b28c12c4b134bf229ce65f993959556b60a8720c6ce4c554ed1eb37e3ca")
+    output := SSHDeriveKey(HashSHA256, K, H, 'D', H, 80)
+    expected := "938bbde61604524feadd4cb095e7a9777b292e39535e4165442896dba60c17328a5655de820f83d68a6087c92afe6aa0cbb39f14107c8dfc78f93870e82994f0bf9be33fc6d6aab5331e0d2a0400a2c4"
+    if hex.EncodeToString(output) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+    }
+    var found bool
+    readRSP(t, "SSHKEX.rsp", func(section map[string]string, record map[string]string) {
+        if section[""] != "diffie-hellman-group14-sha1" {
+            return
+        }
+        found = true
+        H := mustHex(record["H"])
+        output := SSHDeriveKey(HashSHA1, mustHex(record["K"]), H, 'C', H, 64)
+        expected := "ada3b7bc742987c5ab78e70b62a46ef6e250e2ca0a78cd06c0529289b2aca10c315b06e68bed769a7879698873e3bafd393d2872e84935b6c0f18e6d5a7669d2"
+        if hex.EncodeToString(output) != expected {
+            t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+        }
+    })
+    if !found {
+        t.Errorf("\ndiffie-hellman-group14-sha1 not found in SSHKEX.rsp\n")
+    }
+}
@@ -0,0 +1,186 @@
+package sha
+
+import (
+    "encoding/binary"
+)
+
+/* Key derivation for TLS: the TLS 1.2 PRF (RFC 5246) and the TLS 1.3 key
+ * schedule (RFC 8446) */
+
+/* TLS 1.2 */
+
+func PHash(h Hash, secret []byte, seed []byte, L int) []byte {
+    /* The data expansion function P_hash, which returns L bytes of
+     * HMAC(secret, A(i) || seed) for A(0) = seed, A(i) = HMAC(secret, A(i-1)) */
+    m := NewHMAC(h, secret)
+    m.Write(seed)
+    A := m.Sum(nil)
+    output := make([]byte, 0, L+h.Size())
+    for len(output) < L {
+        m.Reset()
+        m.Write(A)
+        m.Write(seed)
+        output = m.Sum(output)
+        m.Reset()
+        m.Write(A)
+        A = m.Sum(A[:0])
+    }
+    return output[:L]
+}
+
+func TLS12PRF(h Hash, secret []byte, label string, seed []byte, L int) []byte {
+    /* The TLS 1.2 PRF, P_hash(secret, label || seed), where h is SHA-256
+     * unless the cipher suite says otherwise (e.g. SHA-384 for suites
+     * ending in _SHA384) */
+    return PHash(h, secret, append([]byte(label), seed...), L)
+}
+
+func TLS12MasterSecret(h Hash, preMasterSecret []byte, clientRandom []byte, serverRandom []byte) []byte {
+    /* Returns the 48-byte master secret, which is the value logged with
+     * CLIENT_RANDOM in an SSLKEYLOGFILE */
+    return TLS12PRF(h, preMasterSecret, "master secret", append(append([]byte(nil), clientRandom...), serverRandom...), 48)
+}
+
+func TLS12ExtendedMasterSecret(h Hash, preMasterSecret []byte, sessionHash []byte) []byte {
+    /* Returns the 48-byte master secret when the extended master secret
+     * extension (RFC 7627) is used, where sessionHash is the hash of the
+     * handshake messages up to and including the ClientKeyExchange */
+    return TLS12PRF(h, preMasterSecret, "extended master secret", sessionHash, 48)
+}
+
+func TLS12KeyBlock(h Hash, masterSecret []byte, clientRandom []byte, serverRandom []byte, L int) []byte {
+    /* Returns L bytes of key block, which is split into the client and
+     * server MAC keys, encryption keys and IVs in that order. Note that the
+     * server random comes first here */
+    return TLS12PRF(h, masterSecret, "key expansion", append(append([]byte(nil), serverRandom...), clientRandom...), L)
+}
+
+func TLS12Finished(h Hash, masterSecret []byte, label string, handshakeHash []byte) []byte {
+    /* Returns the 12-byte verify_data of a Finished message, where label is
+     * "client finished" or "server finished" and handshakeHash is the hash
+     * of the handshake messages so far */
+    return TLS12PRF(h, masterSecret, label, handshakeHash, 12)
+}
+
+/* TLS 1.3 */
+
+func HKDFExpandLabel(h Hash, secret []byte, label string, context []byte, L int) []byte {
+    /* HKDF-Expand with the HkdfLabel structure: a 16-bit length, then
+     * "tls13 " || label and the context, each prefixed by an 8-bit length */
+    var info []byte
+    info = binary.BigEndian.AppendUint16(info, uint16(L))
+    info = append(info, byte(len("tls13 ") + len(label)))
+    info = append(info, "tls13 "...)
+    info = append(info, label...)
+    info = append(info, byte(len(context)))
+    info = append(info, context...)
+    output, _ := HKDFExpand(h, secret, info, L)
+    return output
+}
+
+func DeriveSecret(h Hash, secret []byte, label string, transcriptHash []byte) []byte {
+    /* Derive-Secret, where transcriptHash is the hash of the handshake
+     * messages (the hash of the empty string for no messages) */
+    return HKDFExpandLabel(h, secret, label, transcriptHash, h.Size())
+}
+
+// The secrets of the TLS 1.3 key schedule. NewTLS13KeySchedule sets the
+// early secret, and AddECDHE the handshake and master secrets. Each method
+// taking a transcript hash derives the secret named in RFC 8446 section
+// 7.1 from the hash of the messages listed there
+type TLS13KeySchedule struct {
+    Hash Hash
+    EarlySecret []byte
+    HandshakeSecret []byte
+    MasterSecret []byte
+}
+
+func NewTLS13KeySchedule(h Hash, PSK []byte) *TLS13KeySchedule {
+    /* Starts the key schedule with the early secret HKDF-Extract(0, PSK),
+     * using zeros for the PSK if there is none */
+    if len(PSK) == 0 {
+        PSK = make([]byte, h.Size())
+    }
+    return &TLS13KeySchedule{Hash: h, EarlySecret: HKDFExtract(h, nil, PSK)}
+}
+
+func (k *TLS13KeySchedule) derived(secret []byte) []byte {
+    /* Returns Derive-Secret(secret, "derived", "") */
+    return DeriveSecret(k.Hash, secret, "derived", k.Hash.Sum(nil))
+}
+
+func (k *TLS13KeySchedule) AddECDHE(sharedSecret []byte) {
+    /* Sets the handshake secret from the (EC)DHE shared secret, and the
+     * master secret from the handshake secret. A nil shared secret is
+     * replaced by zeros, for PSK-only handshakes */
+    if len(sharedSecret) == 0 {
+        sharedSecret = make([]byte, k.Hash.Size())
+    }
+    k.HandshakeSecret = HKDFExtract(k.Hash, k.derived(k.EarlySecret), sharedSecret)
+    k.MasterSecret = HKDFExtract(k.Hash, k.derived(k.HandshakeSecret), make([]byte, k.Hash.Size()))
+}
+
+func (k *TLS13KeySchedule) BinderKey(external bool) []byte {
+    /* Returns the key for PSK binders, for an external PSK or a resumption
+     * PSK */
+    if external {
+        return DeriveSecret(k.Hash, k.EarlySecret, "ext binder", k.Hash.Sum(nil))
+    }
+    return DeriveSecret(k.Hash, k.EarlySecret, "res binder", k.Hash.Sum(nil))
+}
+
+func (k *TLS13KeySchedule) ClientEarlyTrafficSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.EarlySecret, "c e traffic", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) EarlyExporterMasterSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.EarlySecret, "e exp master", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) ClientHandshakeTrafficSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.HandshakeSecret, "c hs traffic", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) ServerHandshakeTrafficSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.HandshakeSecret, "s hs traffic", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) ClientApplicationTrafficSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.MasterSecret, "c ap traffic", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) ServerApplicationTrafficSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.MasterSecret, "s ap traffic", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) ExporterMasterSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.MasterSecret, "exp master", transcriptHash)
+}
+
+func (k *TLS13KeySchedule) ResumptionMasterSecret(transcriptHash []byte) []byte {
+    return DeriveSecret(k.Hash, k.MasterSecret, "res master", transcriptHash)
+}
+
+func TLS13TrafficKeys(h Hash, trafficSecret []byte, keyLen int) ([]byte, []byte) {
+    /* Returns the write key of keyLen bytes (16 for AES-128, 32 for AES-256
+     * and ChaCha20) and the 12-byte IV for a traffic secret */
+    return HKDFExpandLabel(h, trafficSecret, "key", nil, keyLen), HKDFExpandLabel(h, trafficSecret, "iv", nil, 12)
+}
+
+func TLS13NextTrafficSecret(h Hash, trafficSecret []byte) []byte {
+    /* Returns the application traffic secret after a KeyUpdate */
+    return HKDFExpandLabel(h, trafficSecret, "traffic upd", nil, h.Size())
+}
+
+func TLS13Finished(h Hash, baseKey []byte, transcriptHash []byte) []byte {
+    /* Returns the verify_data of a Finished message, HMAC(finished_key,
+     * transcriptHash), where baseKey is the handshake traffic secret of the
+     * sender (or the binder key, for PSK binders) */
+    finishedKey := HKDFExpandLabel(h, baseKey, "finished", nil, h.Size())
+    return HMACSum(h, finishedKey, transcriptHash)
+}
+
+func TLS13ResumptionPSK(h Hash, resumptionMasterSecret []byte, ticketNonce []byte) []byte {
+    /* Returns the PSK for resuming with a NewSessionTicket's nonce */
+    return HKDFExpandLabel(h, resumptionMasterSecret, "resumption", ticketNonce, h.Size())
+}
@@ -0,0 +1,202 @@
+package sha
+
+import (
+    "testing"
+    "encoding/hex"
+)
+
+/* TLS 1.2 */
+
+// Expected values from the TLS1-PRF implementation in OpenSSL 3.0
+var tls12Tests = []struct {
+    h Hash
+    preMasterSecret string
+    clientRandom string
+    serverRandom string
+    handshakeHash string
+    masterSecret string
+    extendedMasterSecret string
+    keyBlock string               // 72 bytes
+    clientFinished string
+    serverFinished string
+}{
+    {HashSHA256, "026dd8f8eac17a006b582cdfe73c72e2d5e7fb78502d1b883029e82dfe9b1680a983ffa005269fca8297e3875ea82d75",
+        "e5d2bd959eed16703192d60a9bc5809178becece32aecefa7f87f3c2ccbd1bf6", "4e79df7fb4abc41dc929b2e965736cb31091029803c8dd5c0c3398bec91e8b6d",
+        "0f72663dca336a2a8f3e19247376f5ed7a7374b2f0cea8262ada74e05d50b27b",
+        "78f562b58dd14e042c16ad6466c3671399d0652e3e479c5ae662dbe73affc974bf422201d7a49a8f6763143bb9886e1e",
+        "9cd47b8064218597b9289c75d46d2b0c0e12f4ee60d1567d972fe791b3c49ba34db9b3e37deb1e54d7a85a68bbabd5f6",
+        "071901213875e97a4892f60b15b963a48cb423ebb525e4f92d51a69d4882db220d29ce50e2c807ea929e2a060c3f519d28ecf04415659a6a9109ff726aa588fba9309f1cfb66cfaf",
+        "cbdac4e1eb3900c494c81393", "634256ebdc661fc84591e937"},
+    {HashSHA384, "ad8ac645128190cdfad9ebd515e2f750ec7ffbff2c72edc1f54078f9c00cc7b8b429a2143365e980a48e258249eb6f44",
+        "b6373de5d545dfd7b08fed7e2ffe40be5a58e64ce9661ec4f02b433358efeb60", "90c2130d8807506778c428a9561f9b791303fa2045e3fae5824c474debd664d2",
+        "b27a0e852e2f02a59f099ff9269735bcf381bd8358c4b9328cabd78ed465cb6a7aab651c5a52bdaf40f473221aa92475",
+        "334cf87e66e223e52a914ae0b89d0d5f1f60b73616ea03a1d8b7a5d569d0bbdc1badf45b4819169cc72ce5e799a4039c",
+        "816c7742e0c3b8a9f52622d3afdb7a7c439c3eb398e063b51decc0eb343394ba445442d382121666f81596ef8e1bbd10",
+        "0b1b2fe6eb28fc941938519f8dba1faa659db9ae09a3594def414308896d5d37b755c0be2d98c53d01c3e9013bb06ed6aab3b2d52c758e579912efc006c75b93297cf393ebf57602",
+        "0c853970e9b7ce962ce55d5a", "e934adac8fce91a2179f6b78"},
+}
+
+func TestTLS12PRF(t *testing.T) {
+    // The P_SHA256 test vector posted to the IETF TLS list, which OpenSSL
+    // also reproduces
+    secret := mustHex("9bbe436ba940f017b17652849a71db35")
+    seed := mustHex("a0ba9f936cda311827a6f796ffd5198c")
+    expected := "e3f229ba727be17b8d122620557cd453c2aab21d07c3d495329b52d4e61edb5a6b301791e90d35c9c9a46b4e14baf9af0fa022f7077def17abfd3797c0564bab4fbc91666e9def9b97fce34f796789baa48082d122ee42c5a72e5a5110fff70187347b66"
+    output := TLS12PRF(HashSHA256, secret, "test label", seed, 100)
+    if hex.EncodeToString(output) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+    }
+}
+
+func TestTLS12KeyDerivation(t *testing.T) {
+    for _, test := range tls12Tests {
+        pms, cr, sr, hh := mustHex(test.preMasterSecret), mustHex(test.clientRandom), mustHex(test.serverRandom), mustHex(test.handshakeHash)
+        ms := TLS12MasterSecret(test.h, pms, cr, sr)
+        results := []struct {
+            name string
+            output []byte
+            expected string
+        }{
+            {"master secret", ms, test.masterSecret},
+            {"extended master secret", TLS12ExtendedMasterSecret(test.h, pms, hh), test.extendedMasterSecret},
+            {"key block", TLS12KeyBlock(test.h, ms, cr, sr, 72), test.keyBlock},
+            {"client finished", TLS12Finished(test.h, ms, "client finished", hh), test.clientFinished},
+            {"server finished", TLS12Finished(test.h, ms, "server finished", hh), test.serverFinished},
+        }
+        for _, r := range results {
+            if hex.EncodeToString(r.output) != r.expected {
+                t.Errorf("\nTest: %s %s\nResult:   %x\nExpected: %s\n", test.h, r.name, r.output, r.expected)
+            }
+        }
+    }
+}
+
+/* TLS 1.3 */
+
+func TestHKDFExpandLabel(t *testing.T) {
+    // Expected value from the TLS13-KDF implementation in OpenSSL 3.0
+    output := HKDFExpandLabel(HashSHA256, byteRange(0, 32), "key", mustHex("aabb"), 40)
+    expected := "851a380bed9b5c97e12faa921f78cb5a4e41b43770e2290ebeaf9ea2dff3463e3081c7f8c3755d4a"
+    if hex.EncodeToString(output) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+    }
+}
+
+func TestTLS13KeySchedule(t *testing.T) {
+    // The "Simple 1-RTT Handshake" trace in RFC 8448 section 3, starting
+    // from the x25519 shared secret and the transcript hashes
+    k := NewTLS13KeySchedule(HashSHA256, nil)
+    k.AddECDHE(mustHex("8bd4054fb55b9d63fdfbacf9f04b9f0d35e6d63f537563efd46272900f89492d"))
+    hello := mustHex("860c06edc07858ee8e78f0e7428c58edd6b43f2ca3e6e95f02ed063cf0e1cad8")
+    serverFinished := mustHex("9608102a0f1ccc6db6250b7b7e417b1a000eaada3daae4777a7686c9ff83df13")
+    clientHS := k.ClientHandshakeTrafficSecret(hello)
+    serverHS := k.ServerHandshakeTrafficSecret(hello)
+    clientAP := k.ClientApplicationTrafficSecret(serverFinished)
+    serverAP := k.ServerApplicationTrafficSecret(serverFinished)
+    results := []struct {
+        name string
+        output []byte
+        expected string
+    }{
+        {"early secret", k.EarlySecret, "33ad0a1c607ec03b09e6cd9893680ce210adf300aa1f2660e1b22e10f170f92a"},
+        {"handshake secret", k.HandshakeSecret, "1dc826e93606aa6fdc0aadc12f741b01046aa6b99f691ed221a9f0ca043fbeac"},
+        {"master secret", k.MasterSecret, "18df06843d13a08bf2a449844c5f8a478001bc4d4c627984d5a41da8d0402919"},
+        {"client handshake traffic", clientHS, "b3eddb126e067f35a780b3abf45e2d8f3b1a950738f52e9600746a0e27a55a21"},
+        {"server handshake traffic", serverHS, "b67b7d690cc16c4e75e54213cb2d37b4e9c912bcded9105d42befd59d391ad38"},
+        {"client application traffic", clientAP, "9e40646ce79a7f9dc05af8889bce6552875afa0b06df0087f792ebb7c17504a5"},
+        {"server application traffic", serverAP, "a11af9f05531f856ad47116b45a950328204b4f44bfb6b3a4b4f1f3fcb631643"},
+        {"exporter master", k.ExporterMasterSecret(serverFinished), "fe22f881176eda18eb8f44529e6792c50c9a3f89452f68d8ae311b4309d3cf50"},
+    }
+    for _, r := range results {
+        if hex.EncodeToString(r.output) != r.expected {
+            t.Errorf("\nTest: %s\nResult:   %x\nExpected: %s\n", r.name, r.output, r.expected)
+        }
+    }
+    // Traffic keys and IVs for TLS_AES_128_GCM_SHA256
+    keyTests := []struct {
+        secret []byte
+        key string
+        iv string
+    }{
+        {clientHS, "dbfaa693d1762c5b666af5d950258d01", "5bd3c71b836e0b76bb73265f"},
+        {serverHS, "3fce516009c21727d0f2e4e86ee403bc", "5d313eb2671276ee13000b30"},
+        {clientAP, "17422dda596ed5d9acd890e3c63f5051", "5b78923dee08579033e523d9"},
+        {serverAP, "9f02283b6c9c07efc26bb9f2ac92e356", "cf782b88dd83549aadf1e984"},
+    }
+    for _, test := range keyTests {
+        key, iv := TLS13TrafficKeys(HashSHA256, test.secret, 16)
+        if hex.EncodeToString(key) != test.key || hex.EncodeToString(iv) != test.iv {
+            t.Errorf("\nTest: %x\nResult:   %x %x\nExpected: %s %s\n", test.secret, key, iv, test.key, test.iv)
+        }
+    }
+}
+
+func TestTLS13KeyScheduleACVP(t *testing.T) {
+    // A PSK-DHE vector from the NIST ACVP TLS-v1.3-KDF-RFC8446 files, where
+    // the "random" values are hashed in sequence to make the transcript
+    psk := mustHex("56288B726C73829F7A3E47B103837C8139ACF552E7530C7A710B35ED41191698")
+    dhe := mustHex("EFFE9EC26AA29FD750DFA6A10B944D74071595B27EE88887D5E11C84590B5CC3")
+    transcript := HashSHA256.New()
+    transcriptHash := func(random string) []byte {
+        transcript.Write(mustHex(random))
+        return transcript.Sum(nil)
+    }
+    k := NewTLS13KeySchedule(HashSHA256, psk)
+    clientHello := transcriptHash("E9137679E582BA7C1DB41CF725F86C6D09C8C05F297BAD9A65B552EAF524FDE4")
+    clientET := k.ClientEarlyTrafficSecret(clientHello)
+    k.AddECDHE(dhe)
+    serverHello := transcriptHash("23ECCFD030790748C8F8D8A656FD98D717F1B62AF3712F97211D2070B499F98A")
+    clientHS := k.ClientHandshakeTrafficSecret(serverHello)
+    serverHS := k.ServerHandshakeTrafficSecret(serverHello)
+    serverFinished := transcriptHash("C750EDA6696CD101B142BD79E00E6AC8C5F2C0ABC78DD64F4D991326659E9299")
+    clientAP := k.ClientApplicationTrafficSecret(serverFinished)
+    serverAP := k.ServerApplicationTrafficSecret(serverFinished)
+    clientFinished := transcriptHash("62A62FA75563ED4FDCAA0BC16567B314871C304ACF06B0FFC3F08C1797594D43")
+    resumption := k.ResumptionMasterSecret(clientFinished)
+    results := []struct {
+        name string
+        output []byte
+        expected string
+    }{
+        {"client early traffic", clientET, "3272189698c3594d18f58efa3f12b638a249515099be7a2fa9836babe74f0111"},
+        {"client handshake traffic", clientHS, "b32306c3ce9932c460a1fe6c0f060593974842036b96fa45049b7352e71c2ad2"},
+        {"server handshake traffic", serverHS, "22787f8ca269d34bc549ac8ba19f2040938a3aa370d7cc9d60f720882b88d01b"},
+        {"client application traffic", clientAP, "47d7ea08397b5871154b0fe85584bcc30a87c69e84d69b56007c5b21f76493ba"},
+        {"server application traffic", serverAP, "efbdb0c873c0480da57307083839a8984be25b9a8545e4fca029940fe2800565"},
+        {"resumption master", resumption, "5f4c961329c91044011acbecb0b289282e0e3fed045cb3ea924dffe5fe654b3d"},
+    }
+    for _, r := range results {
+        if hex.EncodeToString(r.output) != r.expected {
+            t.Errorf("\nTest: %s\nResult:   %x\nExpected: %s\n", r.name, r.output, r.expected)
+        }
+    }
+    // Expected value from the TLS13-KDF implementation in OpenSSL 3.0 and
+    // Python's hmac module
+    finished := TLS13Finished(HashSHA256, serverHS, serverHello)
+    expected := "c9a5e3c0931e921ae9f54ee89235af5e0681de0f792bffbf1293ff4d236ffd9d"
+    if hex.EncodeToString(finished) != expected {
+        t.Errorf("\nTest: finished\nResult:   %x\nExpected: %s\n", finished, expected)
+    }
+}
+
+func TestTLS13NextTrafficSecret(t *testing.T) {
+    // Input: the RFC 8448 client application traffic secret. Expected value
+    // from the TLS13-KDF implementation in OpenSSL 3.0
+    secret := mustHex("9e40646ce79a7f9dc05af8889bce6552875afa0b06df0087f792ebb7c17504a5")
+    output := TLS13NextTrafficSecret(HashSHA256, secret)
+    expected := "fcdfcc72725aaee48bf64e4fd8b749cdbdbab39d90da0b26e2245ca6ea167207"
+    if hex.EncodeToString(output) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+    }
+}
+
+func TestTLS13ResumptionPSK(t *testing.T) {
+    // The resumption master secret and PSK from RFC 8448 section 3, for the
+    // ticket nonce 00 00
+    secret := mustHex("7df235f2031d2a051287d02b0241b0bfdaf86cc856231f2d5aba46c434ec196c")
+    output := TLS13ResumptionPSK(HashSHA256, secret, []byte{0, 0})
+    expected := "4ecd0eb6ec3b4d87f5d6028f922ca4c5851a277fd41311c9e62d2c9492e1c4f3"
+    if hex.EncodeToString(output) != expected {
+        t.Errorf("\nResult:   %x\nExpected: %s\n", output, expected)
+    }
+}
@@ -0,0 +1,555 @@
+package sha
+
+import (
+    "crypto/rand"
+    "encoding/binary"
+    "errors"
+    "math"
+    "math/bits"
+    "strings"
+)
+
+/* yescrypt password hashing (yescrypt 1.1 by Alexander Peslyak), the default
+ * in the $y$ format of modern Linux shadow files. The read-write mode adds
+ * pwxform S-box lookups and multiplications to scrypt's memory-hard mix, and
+ * is built on the same PBKDF2 with HMAC-SHA-256 and Salsa20 core */
+
+// Flags selecting the yescrypt mode. 0 is classic scrypt, YescryptWORM is
+// scrypt with yescrypt's pre- and post-hashing and time cost, and
+// YescryptDefaults is the read-write mode with the standard pwxform settings,
+// the only read-write flavor supported
+const (
+    YescryptWORM = 0x001
+    YescryptRW = 0x002
+    YescryptDefaults = 0x0b6
+)
+
+// The defaults of libxcrypt's yescrypt, "$y$j9T$": 16 MiB of memory
+var YescryptParamsDefault = YescryptParams{Flags: YescryptDefaults, N: 4096, R: 32, P: 1}
+
+// Set internally for the pre-hashing pass at a lower cost
+const yescryptPrehash = 0x10000000
+
+// pwxform settings of the default flavor: 2 lanes of 64-bit words, gathered
+// 4 at a time over 6 rounds, with three S-boxes of 2^8 entries (12 KiB)
+const (
+    pwxSimple = 2
+    pwxGather = 4
+    pwxRounds = 6
+    pwxSwidth = 8
+    // Sizes in 32-bit words
+    pwxWords = pwxGather * pwxSimple * 2
+    pwxSboxWords = (1 << pwxSwidth) * pwxSimple * 2
+    // Mask giving the byte offset of an S-box entry
+    pwxSmask = ((1 << pwxSwidth) - 1) * pwxSimple * 8
+)
+
+// Parameters of a yescrypt hash: the flags, the memory cost N (a power of
+// 2), the block size r, the parallelism p and the time cost t
+type YescryptParams struct {
+    Flags int
+    N int
+    R int
+    P int
+    T int
+}
+
+// The S-boxes of one yescrypt lane, as word offsets into S, and the
+// position of the next write to S2
+type pwxformCtx struct {
+    S []uint32
+    s0, s1, s2 int
+    w int
+}
+
+func Yescrypt(password []byte, salt []byte, params YescryptParams, keyLen int) ([]byte, error) {
+    /* Takes a password, a salt, the parameters and a key length in bytes,
+     * and returns a key derived using yescrypt. Costs of at least 16 MiB in
+     * read-write mode first hash the password at a 64th of the cost */
+    if err := params.check(); err != nil {
+        return nil, err
+    }
+    if keyLen <= 0 {
+        return nil, errors.New("sha: yescrypt key length must be positive")
+    }
+    N, r, p := params.N, params.R, params.P
+    if params.Flags & YescryptRW != 0 && N / p >= 0x100 && N / p * r >= 0x20000 {
+        password = yescryptKDF(password, salt, params.Flags | yescryptPrehash, N >> 6, r, p, 0, 32)
+    }
+    return yescryptKDF(password, salt, params.Flags, N, r, p, params.T, keyLen), nil
+}
+
+func (params YescryptParams) check() error {
+    /* Returns an error for parameters yescrypt cannot use */
+    switch params.Flags {
+    case 0:
+        if params.T != 0 {
+            return errors.New("sha: yescrypt time cost needs the WORM or RW flags")
+        }
+    case YescryptWORM, YescryptDefaults:
+    default:
+        return errors.New("sha: unsupported yescrypt flavor")
+    }
+    N, r, p := params.N, params.R, params.P
+    // libxcrypt refuses N = 2 in every mode
+    if N < 4 || N & (N-1) != 0 {
+        return errors.New("sha: yescrypt N must be a power of 2 of at least 4")
+    }
+    if r <= 0 || p <= 0 || params.T < 0 {
+        return errors.New("sha: yescrypt r and p must be positive")
+    }
+    if params.Flags & YescryptRW != 0 && N / p < 4 {
+        return errors.New("sha: yescrypt N must be at least 4p in read-write mode")
+    }
+    if uint64(r) * uint64(p) >= 1 << 30 || r > math.MaxInt / 128 / p || r > math.MaxInt / 256 || N > math.MaxInt / 128 / r {
+        return errors.New("sha: yescrypt parameters are too large")
+    }
+    return nil
+}
+
+func yescryptKDF(password []byte, salt []byte, flags int, N int, r int, p int, t int, keyLen int) []byte {
+    /* One pass of yescrypt: PBKDF2 into p lanes, the mix, and PBKDF2 keyed
+     * by a password updated during the mix. Except for classic scrypt the
+     * result is passed through SCRAM's ClientKey and StoredKey steps */
+    if flags != 0 {
+        key := "yescrypt"
+        if flags & yescryptPrehash != 0 {
+            key = "yescrypt-prehash"
+        }
+        password = HMACSum(HashSHA256, []byte(key), password)
+    }
+    // The parameters have been checked, so PBKDF2 cannot fail
+    B, _ := PBKDF2(password, salt, 1, p*128*r, HashSHA256)
+    if flags != 0 {
+        password = append([]byte(nil), B[:32]...)
+    }
+    // The words of each 64-byte block are kept in the order of yescrypt's
+    // SIMD implementations, which the S-boxes and pwxform depend on
+    X := make([]uint32, len(B) / 4)
+    for k := 0; k < len(X); k += 16 {
+        for i := 0; i < 16; i++ {
+            X[k+i] = binary.LittleEndian.Uint32(B[4*(k + i*5%16):])
+        }
+    }
+    if flags & YescryptRW != 0 {
+        password = yescryptSMix(X, r, N, p, t, flags, password)
+    } else {
+        // Without read-write mode the lanes are independent, as in scrypt
+        for i := 0; i < p; i++ {
+            yescryptSMix(X[i*32*r:(i+1)*32*r], r, N, 1, t, flags, nil)
+        }
+    }
+    for k := 0; k < len(X); k += 16 {
+        for i := 0; i < 16; i++ {
+            binary.LittleEndian.PutUint32(B[4*(k + i*5%16):], X[k+i])
+        }
+    }
+    if flags == 0 || flags & yescryptPrehash != 0 {
+        output, _ := PBKDF2(password, B, 1, keyLen, HashSHA256)
+        return output
+    }
+    output, _ := PBKDF2(password, B, 1, max(keyLen, 32), HashSHA256)
+    storedKey := HashSHA256.Sum(HMACSum(HashSHA256, output[:32], []byte("Client Key")))
+    copy(output, storedKey)
+    return output[:keyLen]
+}
+
+func yescryptSMix(B []uint32, r int, N int, p int, t int, flags int, password []byte) []byte {
+    /* Mixes the p lanes of B in read-write mode, sharing one array V of N
+     * blocks, or a single lane otherwise. Each lane first fills and mixes
+     * its own part of V, then all of V is read. Returns the password for
+     * the final PBKDF2 */
+    s := 32 * r
+    V := make([]uint32, s*N)
+    Y := make([]uint32, s)
+    // Loop counts from the time cost
+    nChunk := N / p
+    nLoopAll := nChunk
+    if flags & YescryptRW != 0 {
+        if t <= 1 {
+            if t != 0 {
+                nLoopAll *= 2
+            }
+            nLoopAll = (nLoopAll + 2) / 3
+        } else {
+            nLoopAll *= t - 1
+        }
+    } else if t != 0 {
+        if t == 1 {
+            nLoopAll += (nLoopAll + 1) / 2
+        }
+        nLoopAll *= t
+    }
+    nLoopRW := 0
+    if flags & YescryptRW != 0 {
+        nLoopRW = nLoopAll / p
+    }
+    nChunk &^= 1
+    nLoopAll = (nLoopAll + 1) &^ 1
+    nLoopRW = (nLoopRW + 1) &^ 1
+    var ctx []*pwxformCtx
+    for i := 0; i < p; i++ {
+        Bp := B[i*s:(i+1)*s]
+        var c *pwxformCtx
+        if flags & YescryptRW != 0 {
+            // The S-boxes are filled by a Salsa20/8 mix of the lane's
+            // first block
+            S := make([]uint32, 3*pwxSboxWords)
+            yescryptSMix1(Bp[:32], 1, len(S) / 32, 0, S, nil, Y)
+            c = &pwxformCtx{S: S, s2: 0, s1: pwxSboxWords, s0: 2*pwxSboxWords}
+            if i == 0 {
+                var last [64]byte
+                for j := 0; j < 16; j++ {
+                    binary.LittleEndian.PutUint32(last[4*(j*5%16):], Bp[s-16+j])
+                }
+                password = HMACSum(HashSHA256, last[:], password)
+            }
+        }
+        ctx = append(ctx, c)
+        Np := nChunk
+        if i == p - 1 {
+            Np = N - i*nChunk
+        }
+        Vp := V[i*nChunk*s:(i*nChunk + Np)*s]
+        yescryptSMix1(Bp, r, Np, flags, Vp, c, Y)
+        // The largest power of 2 up to Np, which is 0 when the lanes
+        // outnumber N/2
+        yescryptSMix2(Bp, r, Np & (1 << bits.Len(uint(Np)) >> 1), nLoopRW, flags, Vp, c, Y)
+    }
+    for i := 0; i < p; i++ {
+        yescryptSMix2(B[i*s:(i+1)*s], r, N, nLoopAll - nLoopRW, flags &^ YescryptRW, V, ctx[i], Y)
+    }
+    return password
+}
+
+func yescryptSMix1(X []uint32, r int, N int, flags int, V []uint32, ctx *pwxformCtx, Y []uint32) {
+    /* Fills V with N successive block mixes of X. In read-write mode each
+     * block after the second is first combined with an earlier one */
+    s := 32 * r
+    for i := 0; i < N; i++ {
+        copy(V[i*s:], X)
+        if flags & YescryptRW != 0 && i > 1 {
+            // Wrap: an index below the largest power of 2 up to i is
+            // moved into the last blocks written
+            n := 1 << (bits.Len(uint(i)) - 1)
+            j := int(yescryptIntegerify(X, r) & uint64(n-1)) + i - n
+            for k, v := range V[j*s:(j+1)*s] {
+                X[k] ^= v
+            }
+        }
+        yescryptBlockMix(X, r, ctx, Y)
+    }
+}
+
+func yescryptSMix2(X []uint32, r int, N int, nLoop int, flags int, V []uint32, ctx *pwxformCtx, Y []uint32) {
+    /* Mixes nLoop entries of V chosen by the state into X, writing each
+     * result back to V in read-write mode */
+    s := 32 * r
+    for i := 0; i < nLoop; i++ {
+        j := int(yescryptIntegerify(X, r) & uint64(N-1))
+        Vj := V[j*s:(j+1)*s]
+        for k, v := range Vj {
+            X[k] ^= v
+        }
+        if flags & YescryptRW != 0 {
+            copy(Vj, X)
+        }
+        yescryptBlockMix(X, r, ctx, Y)
+    }
+}
+
+func yescryptIntegerify(X []uint32, r int) uint64 {
+    /* The first 64 bits of the last 64-byte block, whose second word is
+     * word 13 in SIMD order */
+    last := X[(2*r-1)*16:]
+    return uint64(last[13]) << 32 | uint64(last[0])
+}
+
+func yescryptSalsa20(B []uint32, rounds int) {
+    /* The Salsa20 core on a block of 16 words in SIMD order */
+    var x [16]uint32
+    for i := range x {
+        x[i*5%16] = B[i]
+    }
+    salsa20(&x, rounds)
+    for i := range x {
+        B[i] = x[i*5%16]
+    }
+}
+
+func yescryptBlockMix(B []uint32, r int, ctx *pwxformCtx, Y []uint32) {
+    /* BlockMix with pwxform when there are S-boxes, or else scrypt's
+     * BlockMix with Salsa20/8 */
+    if ctx == nil {
+        var X [16]uint32
+        copy(X[:], B[(2*r-1)*16:])
+        for i := 0; i < 2*r; i++ {
+            for j := range X {
+                X[j] ^= B[i*16+j]
+            }
+            yescryptSalsa20(X[:], 8)
+            copy(Y[(i/2 + (i&1)*r)*16:], X[:])
+        }
+        copy(B, Y[:32*r])
+        return
+    }
+    // pwxform over 64-byte blocks in a chain, then Salsa20/2 on the last
+    var X [pwxWords]uint32
+    blocks := 2 * r
+    copy(X[:], B[(blocks-1)*pwxWords:])
+    for i := 0; i < blocks; i++ {
+        if blocks > 1 {
+            for j := range X {
+                X[j] ^= B[i*pwxWords+j]
+            }
+        }
+        ctx.pwxform(&X)
+        copy(B[i*pwxWords:], X[:])
+    }
+    yescryptSalsa20(B[(blocks-1)*16:blocks*16], 2)
+}
+
+func (ctx *pwxformCtx) pwxform(X *[pwxWords]uint32) {
+    /* Each round replaces the 64-bit lanes of X by the product of their
+     * halves, added to and xored with S-box entries chosen by the first
+     * lane of each group. The middle rounds also write S2, and the S-boxes
+     * are rotated afterwards */
+    S := ctx.S
+    w := ctx.w
+    for i := 0; i < pwxRounds; i++ {
+        for j := 0; j < pwxGather; j++ {
+            lane := X[j*pwxSimple*2:(j+1)*pwxSimple*2]
+            p0 := ctx.s0 + int(lane[0] & pwxSmask) / 4
+            p1 := ctx.s1 + int(lane[1] & pwxSmask) / 4
+            for k := 0; k < pwxSimple; k++ {
+                s0 := uint64(S[p0+2*k+1]) << 32 | uint64(S[p0+2*k])
+                s1 := uint64(S[p1+2*k+1]) << 32 | uint64(S[p1+2*k])
+                x := (uint64(lane[2*k+1]) * uint64(lane[2*k]) + s0) ^ s1
+                lane[2*k] = uint32(x)
+                lane[2*k+1] = uint32(x >> 32)
+                if i != 0 && i != pwxRounds - 1 {
+                    S[ctx.s2+2*w] = uint32(x)
+                    S[ctx.s2+2*w+1] = uint32(x >> 32)
+                    w++
+                }
+            }
+        }
+    }
+    ctx.s0, ctx.s1, ctx.s2 = ctx.s2, ctx.s0, ctx.s1
+    ctx.w = w & (pwxSboxWords/2 - 1)
+}
+
+/* The $y$ format */
+
+func yescryptBase64(b []byte) string {
+    /* Encodes b in the crypt(3) alphabet in groups of three bytes, least
+     * significant first. A final group of n < 3 bytes gives n+1 characters */
+    var output strings.Builder
+    for i := 0; i < len(b); i += 3 {
+        var w uint
+        group := b[i:min(i+3, len(b))]
+        for j, c := range group {
+            w |= uint(c) << (8*j)
+        }
+        for n := 0; n <= len(group); n++ {
+            output.WriteByte(cryptAlphabet[w & 0x3f])
+            w >>= 6
+        }
+    }
+    return output.String()
+}
+
+func yescryptDecodeBase64(s string) ([]byte, bool) {
+    /* Decodes yescryptBase64, rejecting groups of one character and unused
+     * bits which are not zero */
+    var output []byte
+    for i := 0; i < len(s); i += 4 {
+        group := s[i:min(i+4, len(s))]
+        if len(group) == 1 {
+            return nil, false
+        }
+        var w uint
+        for j := range group {
+            c := strings.IndexByte(cryptAlphabet, group[j])
+            if c < 0 {
+                return nil, false
+            }
+            w |= uint(c) << (6*j)
+        }
+        for n := 1; n < len(group); n++ {
+            output = append(output, byte(w))
+            w >>= 8
+        }
+        if w != 0 {
+            return nil, false
+        }
+    }
+    return output, true
+}
+
+func yescryptEncodeInt(x int, min int) string {
+    /* Encodes a parameter of at least min in a variable number of
+     * characters. The first character gives both the length and the high
+     * bits: 48 values in one character, then 8 of its values for each 6
+     * bits more, then 4 for 6 more and so on */
+    x -= min
+    start, end, chars, shift := 0, 47, 1, 0
+    for x >= (end + 1 - start) << shift {
+        x -= (end + 1 - start) << shift
+        start = end + 1
+        end = start + (62 - end) / 2
+        chars++
+        shift += 6
+    }
+    output := []byte{cryptAlphabet[start + x >> shift]}
+    for chars--; chars > 0; chars-- {
+        shift -= 6
+        output = append(output, cryptAlphabet[x >> shift & 0x3f])
+    }
+    return string(output)
+}
+
+func yescryptDecodeInt(s string, min int) (int, string, bool) {
+    /* Decodes a parameter from the start of s, returning it and the rest of
+     * s */
+    if s == "" {
+        return 0, s, false
+    }
+    c := strings.IndexByte(cryptAlphabet, s[0])
+    if c < 0 {
+        return 0, s, false
+    }
+    x := min
+    start, end, chars, shift := 0, 47, 1, 0
+    for c > end {
+        x += (end + 1 - start) << shift
+        start = end + 1
+        end = start + (62 - end) / 2
+        chars++
+        shift += 6
+    }
+    if len(s) < chars {
+        return 0, s, false
+    }
+    x += (c - start) << shift
+    for i := 1; i < chars; i++ {
+        c = strings.IndexByte(cryptAlphabet, s[i])
+        if c < 0 {
+            return 0, s, false
+        }
+        shift -= 6
+        x += c << shift
+    }
+    return x, s[chars:], true
+}
+
+func ParseYescryptSetting(setting string) (YescryptParams, []byte, error) {
+    /* Parses a $y$ setting such as "$y$j9T$salt", or a complete hash, into
+     * the parameters and the decoded salt */
+    var params YescryptParams
+    if !strings.HasPrefix(setting, "$y$") {
+        return params, nil, ErrCryptFormat
+    }
+    s := setting[3:]
+    flavor, s, ok := yescryptDecodeInt(s, 0)
+    if !ok || flavor > YescryptRW + 0xff {
+        return params, nil, ErrCryptFormat
+    }
+    params.Flags = flavor
+    if flavor >= YescryptRW {
+        params.Flags = YescryptRW + (flavor - YescryptRW) << 2
+    }
+    logN, s, ok := yescryptDecodeInt(s, 1)
+    if !ok || logN > 62 {
+        return params, nil, ErrCryptFormat
+    }
+    params.N = 1 << logN
+    params.P = 1
+    if params.R, s, ok = yescryptDecodeInt(s, 1); !ok {
+        return params, nil, ErrCryptFormat
+    }
+    // Optional fields: bit 0 for p, bit 1 for t, bit 2 for hash upgrades and
+    // bit 3 for a ROM, of which the last two are unsupported
+    if s != "" && s[0] != '$' {
+        var have int
+        have, s, ok = yescryptDecodeInt(s, 1)
+        if !ok || have & ^3 != 0 {
+            return params, nil, ErrCryptFormat
+        }
+        if have & 1 != 0 {
+            if params.P, s, ok = yescryptDecodeInt(s, 2); !ok {
+                return params, nil, ErrCryptFormat
+            }
+        }
+        if have & 2 != 0 {
+            if params.T, s, ok = yescryptDecodeInt(s, 1); !ok {
+                return params, nil, ErrCryptFormat
+            }
+        }
+    }
+    if s == "" || s[0] != '$' {
+        return params, nil, ErrCryptFormat
+    }
+    s = s[1:]
+    if end := strings.IndexByte(s, '$'); end >= 0 {
+        s = s[:end]
+    }
+    salt, ok := yescryptDecodeBase64(s)
+    if !ok || len(salt) > 64 {
+        return params, nil, ErrCryptFormat
+    }
+    return params, salt, nil
+}
+
+func YescryptSetting(params YescryptParams) (string, error) {
+    /* Returns a setting for Crypt with the given parameters and a random
+     * 16-byte salt */
+    if err := params.check(); err != nil {
+        return "", err
+    }
+    flavor := params.Flags
+    if flavor >= YescryptRW {
+        flavor = YescryptRW + flavor >> 2
+    }
+    setting := "$y$" + yescryptEncodeInt(flavor, 0) + yescryptEncodeInt(bits.Len(uint(params.N)) - 1, 1) + yescryptEncodeInt(params.R, 1)
+    have := 0
+    if params.P != 1 {
+        have |= 1
+    }
+    if params.T != 0 {
+        have |= 2
+    }
+    if have != 0 {
+        setting += yescryptEncodeInt(have, 1)
+    }
+    if params.P != 1 {
+        setting += yescryptEncodeInt(params.P, 2)
+    }
+    if params.T != 0 {
+        setting += yescryptEncodeInt(params.T, 1)
+    }
+    salt := make([]byte, 16)
+    if _, err := rand.Read(salt); err != nil {
+        return "", err
+    }
+    return setting + "$" + yescryptBase64(salt), nil
+}
+
+func yescryptCrypt(password []byte, setting string) (string, error) {
+    /* Returns the complete $y$ hash for a setting, keeping the setting's
+     * text up to the end of the salt */
+    params, salt, err := ParseYescryptSetting(setting)
+    if err != nil {
+        return "", err
+    }
+    hash, err := Yescrypt(password, salt, params, 32)
+    if err != nil {
+        return "", ErrCryptFormat
+    }
+    // The salt is the fourth field
+    prefix := setting
+    if fields := strings.SplitN(setting, "$", 5); len(fields) == 5 {
+        prefix = setting[:len(setting) - len(fields[4]) - 1]
+    }
+    return prefix + "$" + yescryptBase64(hash), nil
+}
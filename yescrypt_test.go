@@ -0,0 +1,136 @@
+package sha
+
+import (
+    "testing"
+    "encoding/hex"
+    "strings"
+)
+
+// Hashes made with libxcrypt 4.4, which includes the yescrypt reference
+// code. Flavor '.' is classic scrypt, '/' is WORM and 'j' the default
+// read-write mode, and they cover p, t, empty and 64-byte salts, and the
+// pre-hashing of costs from 16 MiB. The first is the hashcat example hash
+const yescryptLongPassword = "pässwörd with a longer text, more than sixty-four bytes long.........."
+const yescryptLongSalt = "k2XAnEHBqQ1Ct2aMXFKNa/HAmA1BpMnBsYHMWB4NZN4Al6nAoIXBrUHCV7qMYJaNk2XAnEHBqQ1Ct2aMXFKNa/"
+
+var yescryptTests = []struct {
+    password string
+    hash string
+}{
+    {"hashcat", "$y$j9T$saltsaltsaltsalt$oalHbQ72PkWGukIPkCP7T7BqiVDl/sipGgJHhfgReu1"},
+    {"", "$y$j9T$$EBiO75E.nlsNSY6EaCJLzant.b1uUsOgoDdj78FKfO7"},
+    {"hashcat", "$y$j9T/.$/.$UjAQCdYadL0Pjs5f1VHDrydaV7bM.06isHqIAhV9Td0"},
+    {"correct horse", "$y$jAD$LdJMENpBABJJ3hIHjB1Bi.$0OlrTAKATYYHjuRq7bdb.vLnkiI9y9rxrN7GoYF0U70"},
+    {"correct horse", "$y$jAT/.$LdJMENpBABJJ3hIHjB1Bi.$OMKFrmetDx7xSN2lEahUwHIgHcF.oD62Mb99w4JfRD4"},
+    {yescryptLongPassword, "$y$.15./$$fWEar5pnFMpAjDVEf.x6Vqco6CTt5e4nMNC/wtKQNgB"},
+    {"", "$y$./T$" + yescryptLongSalt + "$ITBEeu5eyvKxS0hLJVE1DgBKGQrukTnNptwcwfMwZOC"},
+    {"hashcat", "$y$.7...$$umK7YcFl/1HDTll1W0P.4w50OjeGaCHXwbaC3IA27r6"},
+    {"hashcat", "$y$/9/0//$$AEFTKhtfXnn0CbmgHYdz8ESFB5QiBXMfsUYcfwclf5/"},
+    {"", "$y$///$/.$Bl6GMUO2wX0nMhbIPV7n9IH8UKWIBfHiNekBwXLZrcB"},
+    {"", "$y$///0./$V74$6KWnwK9HTd4gNFv9vjntVDj3MfsFytcSngHqPFznvV."},
+    {"", "$y$/A5//$" + yescryptLongSalt + "$I3A29JETnrWFItKhukuLRydie8FBHnLHHWoO9RN.aXB"},
+    {"hashcat", "$y$j9//.$V74$udDy1oJRRido248KL02lV9ZBMPYYK4wrtpPRL1BX9TC"},
+    {"", "$y$j/./0$n34PoBLMgFrQVl4Rn34Po/$3akU905ZmqEivn3StnCsHnh09PmIFq4bL7TLE3bAJD1"},
+    {"hashcat", "$y$j9.0.0$/.$wigpqXEy48KS0yqK9zjHG.HCrc7jnpAl/govYcNW/d7"},
+    {yescryptLongPassword, "$y$j9///$n34PoBLMgFrQVl4Rn34Po/$3ZvwgxbbMkPsNevQvMH6eczEalcdxc5.otqBlaFkyw3"},
+    {"hashcat", "$y$j/./.$/.$lSAKi0OGjpRl59Z7x8OfdIRNGLKcfQzaw02uA5pwZ89"},
+    {"", "$y$j/T/0$" + yescryptLongSalt + "$JTbd1ajzb./5nydY8chhQFlGYcicE9H4YsNYeP3E8f/"},
+    {yescryptLongPassword, "$y$j1///$V74$khqWdIeJrvzmadsej8i1NnkOSimEr/B0iGbi8GXe600"},
+    {"", "$y$j9/0./$V74$SKneZZw5v3/nN56Yz31zod/WBHQhEID6AUAnJNSpLj."},
+    {yescryptLongPassword, "$y$j9.0/0$$QDngd2O3h9d5LvKFD6.JLOIA7ajMW5pw6Qd1SL9w6D9"},
+}
+
+func TestYescryptCrypt(t *testing.T) {
+    for _, test := range yescryptTests {
+        // The setting alone, and the complete hash
+        setting := test.hash[:strings.LastIndexByte(test.hash, '$')]
+        for _, input := range []string{setting, test.hash} {
+            output, err := Crypt([]byte(test.password), input)
+            if err != nil || output != test.hash {
+                t.Errorf("\nTest: %s\nResult:   %s %v\nExpected: %s\n", input, output, err, test.hash)
+            }
+        }
+        if CryptVerify([]byte(test.password + "x"), test.hash) {
+            t.Errorf("\nTest: %s\nWrong password verified\n", test.hash)
+        }
+    }
+    // Input: settings which libxcrypt also rejects, for a missing field, a
+    // salt of one character or with unused bits set, an unsupported flavor,
+    // optional fields for hash upgrades and a ROM, N of 2 in each flavor
+    // and a salt over 64 bytes
+    for _, setting := range []string{"$y$", "$y$j9T", "$y$j9T$a", "$y$j9T$.z", "$y$j9T$ab!d", "$y$k9T$..", "$y$j9T2.$..", "$y$j9T6.$..", "$y$j.T$..", "$y$..3$..", "$y$/.//0$qf3", "$y$j9T$" + yescryptLongSalt + "ab"} {
+        if _, err := Crypt([]byte("password"), setting); err != ErrCryptFormat {
+            t.Errorf("\nTest: %q\nResult:   %v\nExpected: %v\n", setting, err, ErrCryptFormat)
+        }
+    }
+}
+
+func TestYescrypt(t *testing.T) {
+    // Classic scrypt mode matches scrypt, with the RFC 7914 vectors
+    for _, test := range scryptTests[:3] {
+        output, err := Yescrypt([]byte(test.password), []byte(test.salt), YescryptParams{N: test.N, R: test.r, P: test.p}, 64)
+        if err != nil || hex.EncodeToString(output) != test.expected {
+            t.Errorf("\nTest: %q N=%d\nResult:   %x %v\nExpected: %s\n", test.password, test.N, output, err, test.expected)
+        }
+    }
+    // Input: the default flavor with keys shorter and longer than the 32
+    // bytes of $y$ hashes, which replace only the first 32 bytes
+    params, salt, err := ParseYescryptSetting(yescryptTests[0].hash)
+    if err != nil || params != YescryptParamsDefault || hex.EncodeToString(salt) != "b819e7b819e7b819e7b819e7" {
+        t.Fatalf("\nResult:   %+v %x %v\nExpected: %+v\n", params, salt, err, YescryptParamsDefault)
+    }
+    key, _ := Yescrypt([]byte("hashcat"), salt, params, 32)
+    short, _ := Yescrypt([]byte("hashcat"), salt, params, 20)
+    long, _ := Yescrypt([]byte("hashcat"), salt, params, 64)
+    if yescryptBase64(key) != yescryptTests[0].hash[len(yescryptTests[0].hash)-43:] || hex.EncodeToString(short) != hex.EncodeToString(key[:20]) || hex.EncodeToString(long[:32]) != hex.EncodeToString(key) {
+        t.Errorf("\nResult:   %x\n          %x\n          %x\n", key, short, long)
+    }
+}
+
+func TestYescryptParameters(t *testing.T) {
+    tests := []YescryptParams{
+        {Flags: YescryptDefaults, N: 0, R: 1, P: 1},
+        {Flags: YescryptDefaults, N: 1000, R: 1, P: 1},
+        {Flags: 0, N: 2, R: 1, P: 1},
+        {Flags: YescryptWORM, N: 2, R: 1, P: 1},
+        {Flags: YescryptDefaults, N: 4, R: 0, P: 1},
+        {Flags: YescryptDefaults, N: 4, R: 1, P: 0},
+        {Flags: YescryptDefaults, N: 8, R: 1, P: 3},
+        {Flags: YescryptDefaults, N: 4, R: 1, P: 1, T: -1},
+        {Flags: 0, N: 16, R: 1, P: 1, T: 1},
+        {Flags: YescryptRW, N: 16, R: 1, P: 1},
+        {Flags: YescryptDefaults, N: 16, R: 1 << 15, P: 1 << 15},
+    }
+    for _, params := range tests {
+        if _, err := Yescrypt([]byte("password"), []byte("salt"), params, 32); err == nil {
+            t.Errorf("\nTest: %+v\nExpected an error\n", params)
+        }
+        if _, err := YescryptSetting(params); err == nil {
+            t.Errorf("\nTest: %+v\nExpected an error for the setting\n", params)
+        }
+    }
+}
+
+func TestYescryptSetting(t *testing.T) {
+    tests := []YescryptParams{
+        YescryptParamsDefault,
+        {Flags: 0, N: 1024, R: 8, P: 1},
+        {Flags: YescryptWORM, N: 256, R: 1, P: 300, T: 2},
+        {Flags: YescryptDefaults, N: 1024, R: 100, P: 4, T: 70},
+    }
+    for _, params := range tests {
+        setting, err := YescryptSetting(params)
+        if err != nil {
+            t.Fatal(err)
+        }
+        parsed, salt, err := ParseYescryptSetting(setting)
+        if err != nil || parsed != params || len(salt) != 16 {
+            t.Errorf("\nTest: %s\nResult:   %+v %x %v\nExpected: %+v\n", setting, parsed, salt, err, params)
+        }
+    }
+    setting, _ := YescryptSetting(YescryptParamsDefault)
+    hash, err := Crypt([]byte("password"), setting)
+    if err != nil || !strings.HasPrefix(setting, "$y$j9T$") || len(setting) != len("$y$j9T$") + 22 || !CryptVerify([]byte("password"), hash) {
+        t.Errorf("\nResult:   %s %s %v\n", setting, hash, err)
+    }
+}